@@ -0,0 +1,25 @@
+#!/bin/sh
+#
+# Copyright 2026 The Kubernetes Authors.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Use for e2e test of --credential-exec.
+# Prints the magic credentials which git_askpass.sh expects.
+
+if [ -z "${GITSYNC_REPO}" ]; then
+    echo "GITSYNC_REPO is not set" >&2
+    exit 1
+fi
+echo "${GITSYNC_CREDENTIAL_REASON}" >> /var/log/runs
+echo '{"username": "my-username", "password": "my-password", "expires_at": "2099-01-01T00:00:00Z"}'
@@ -0,0 +1,99 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+)
+
+// askpassResponse is the data returned by --askpass-url.
+type askpassResponse struct {
+	// Credentials for --repo.
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// When the credentials expire, or zero if they must be re-fetched before
+	// every sync.
+	ExpiresAt time.Time `json:"expires_at"`
+	// Credentials for other URLs, e.g. submodules.
+	Credentials []askpassCredential `json:"credentials,omitempty"`
+}
+
+// askpassCredential is a credential for a specific URL.
+type askpassCredential struct {
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// parseAskpassResponse decodes a response from --askpass-url.  The response
+// is either a JSON object, if the content type says so or the body looks like
+// one, or a series of key=value lines.
+func parseAskpassResponse(contentType string, body []byte) (askpassResponse, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "application/json" || bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) {
+		return parseAskpassJSON(body)
+	}
+	return parseAskpassLines(body)
+}
+
+func parseAskpassJSON(body []byte) (askpassResponse, error) {
+	resp := askpassResponse{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return askpassResponse{}, fmt.Errorf("can't decode auth response: %w", err)
+	}
+	for i, cred := range resp.Credentials {
+		if cred.URL == "" {
+			return askpassResponse{}, fmt.Errorf("auth response credential %d: url must be specified", i)
+		}
+	}
+	return resp, nil
+}
+
+// parseAskpassLines handles the original format, which looks like:
+//
+//	username=xxx@example.com
+//	password=xxxyyyzzz
+//	expires_at=2006-01-02T15:04:05Z
+//
+// Unknown keys are ignored.
+func parseAskpassLines(body []byte) (askpassResponse, error) {
+	resp := askpassResponse{}
+	for _, line := range strings.Split(string(body), "\n") {
+		keyValues := strings.SplitN(line, "=", 2)
+		if len(keyValues) != 2 {
+			continue
+		}
+		switch keyValues[0] {
+		case "username":
+			resp.Username = keyValues[1]
+		case "password":
+			resp.Password = keyValues[1]
+		case "expires_at":
+			t, err := time.Parse(time.RFC3339, strings.TrimSpace(keyValues[1]))
+			if err != nil {
+				return askpassResponse{}, fmt.Errorf("can't parse expires_at in auth response: %w", err)
+			}
+			resp.ExpiresAt = t
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/git-sync/pkg/cmd"
+	"k8s.io/git-sync/pkg/logging"
+)
+
+func TestParseAskpassResponse(t *testing.T) {
+	expiry := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	cases := []struct {
+		name        string
+		contentType string
+		body        string
+		expResp     askpassResponse
+		expErr      bool
+	}{{
+		name:    "lines",
+		body:    "username=my-username\npassword=my=password\n",
+		expResp: askpassResponse{Username: "my-username", Password: "my=password"},
+	}, {
+		name:    "lines-with-expiry",
+		body:    "username=u\npassword=p\nexpires_at=2026-01-02T03:04:05Z\nother=ignored\n",
+		expResp: askpassResponse{Username: "u", Password: "p", ExpiresAt: expiry},
+	}, {
+		name:   "lines-bad-expiry",
+		body:   "username=u\npassword=p\nexpires_at=tomorrow\n",
+		expErr: true,
+	}, {
+		name:        "json",
+		contentType: "application/json; charset=utf-8",
+		body:        `{"username": "u", "password": "p", "expires_at": "2026-01-02T03:04:05Z"}`,
+		expResp:     askpassResponse{Username: "u", Password: "p", ExpiresAt: expiry},
+	}, {
+		name:    "json-sniffed",
+		body:    `  {"username": "u", "password": "p"}`,
+		expResp: askpassResponse{Username: "u", Password: "p"},
+	}, {
+		name: "json-per-url",
+		body: `{"credentials": [{"url": "https://example.com/sub", "username": "su", "password": "sp"}]}`,
+		expResp: askpassResponse{Credentials: []askpassCredential{
+			{URL: "https://example.com/sub", Username: "su", Password: "sp"},
+		}},
+	}, {
+		name:   "json-per-url-no-url",
+		body:   `{"credentials": [{"username": "su", "password": "sp"}]}`,
+		expErr: true,
+	}, {
+		name:        "json-bad",
+		contentType: "application/json",
+		body:        `username=u`,
+		expErr:      true,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := parseAskpassResponse(tc.contentType, []byte(tc.body))
+			if err != nil && !tc.expErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err == nil && tc.expErr {
+				t.Fatalf("unexpected success: %+v", resp)
+			}
+			if !reflect.DeepEqual(tc.expResp, resp) {
+				t.Errorf("expected %+v, got %+v", tc.expResp, resp)
+			}
+		})
+	}
+}
+
+func TestCallAskPassURLExpiry(t *testing.T) {
+	var expiresAt string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "username=u\npassword=p\n%s", expiresAt)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(dir, "gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "true")
+	log := logging.New("", "", 0)
+	git := &repoSync{
+		cmd:         "git",
+		repo:        "https://git.example.com/repo.git",
+		log:         log,
+		run:         cmd.NewRunner(log),
+		authURL:     srv.URL,
+		authTimeout: time.Second,
+		httpClient:  srv.Client(),
+	}
+	ctx := context.Background()
+	if _, _, err := git.Run(ctx, "", "config", "--global", "credential.helper", "store --file "+filepath.Join(dir, "git-credentials")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without an expiry, the URL is queried before every sync.
+	if err := git.CallAskPassURL(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !git.authExpiry.IsZero() {
+		t.Errorf("expected no expiry, got %v", git.authExpiry)
+	}
+
+	// A short expiry is used as-is.
+	expiry := time.Now().Add(10 * time.Minute).UTC().Truncate(time.Second)
+	expiresAt = "expires_at=" + expiry.Format(time.RFC3339) + "\n"
+	if err := git.CallAskPassURL(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !git.authExpiry.Equal(expiry) {
+		t.Errorf("expected expiry %v, got %v", expiry, git.authExpiry)
+	}
+
+	// An expiry after git's credential cache forgets the credentials is
+	// capped, so they are stored again in time.
+	expiresAt = "expires_at=" + time.Now().Add(3*time.Hour).UTC().Format(time.RFC3339) + "\n"
+	if err := git.CallAskPassURL(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if left := time.Until(git.authExpiry); left <= 0 || left >= credentialCacheTimeout {
+		t.Errorf("expected expiry within the credential cache timeout, got %v left", left)
+	}
+}
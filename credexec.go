@@ -0,0 +1,162 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Why --credential-exec is being run.  This is passed to the command as
+// $GITSYNC_CREDENTIAL_REASON.
+const (
+	credExecReasonInitial  = "initial"  // no credentials have been fetched yet
+	credExecReasonExpiring = "expiring" // the cached credentials are about to expire
+	credExecReasonRejected = "rejected" // the last sync failed to authenticate
+	credExecReasonSync     = "sync"     // the credentials have no expiry
+)
+
+// execCredential is a credential printed by --credential-exec.
+type execCredential struct {
+	// The URL this is for.  If not specified, this is --repo.
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	// Only one of Password or Token may be specified.
+	Password string `json:"password"`
+	Token    string `json:"token"`
+}
+
+// execCredentialResponse is the output of --credential-exec.
+type execCredentialResponse struct {
+	execCredential
+	// When the credentials expire, or zero if they must be re-fetched before
+	// every sync.
+	ExpiresAt time.Time `json:"expires_at"`
+	// Credentials for other URLs, e.g. submodules.
+	Credentials []execCredential `json:"credentials,omitempty"`
+
+	// The output of a kubectl exec credential plugin
+	// (client.authentication.k8s.io), so those can be used as-is.
+	Status *struct {
+		Token               string    `json:"token"`
+		ExpirationTimestamp time.Time `json:"expirationTimestamp"`
+	} `json:"status,omitempty"`
+}
+
+// parseExecCredentials decodes the output of --credential-exec into a list
+// of credentials to store, and when they expire.  Tokens are stored as
+// passwords, and a missing username or URL is filled in.
+func parseExecCredentials(repo string, out []byte) ([]execCredential, time.Time, error) {
+	resp := execCredentialResponse{}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return nil, time.Time{}, fmt.Errorf("can't decode credential-exec output: %w", err)
+	}
+	if resp.Status != nil {
+		resp.Token = resp.Status.Token
+		resp.ExpiresAt = resp.Status.ExpirationTimestamp
+	}
+
+	creds := []execCredential{}
+	// A response with only per-URL credentials need not include the main
+	// one.
+	if resp.Username != "" || resp.Password != "" || resp.Token != "" || len(resp.Credentials) == 0 {
+		if resp.URL == "" {
+			resp.URL = repo
+		}
+		creds = append(creds, resp.execCredential)
+	}
+	for i, cred := range resp.Credentials {
+		if cred.URL == "" {
+			return nil, time.Time{}, fmt.Errorf("credential-exec credential %d: url must be specified", i)
+		}
+		creds = append(creds, cred)
+	}
+	for i := range creds {
+		cred := &creds[i]
+		if cred.Password != "" && cred.Token != "" {
+			return nil, time.Time{}, fmt.Errorf("credential-exec credential for %s: only one of password and token may be specified", redactURL(cred.URL))
+		}
+		if cred.Password == "" && cred.Token == "" {
+			return nil, time.Time{}, fmt.Errorf("credential-exec credential for %s: password or token must be specified", redactURL(cred.URL))
+		}
+		if cred.Token != "" {
+			cred.Password = cred.Token
+			cred.Token = ""
+		}
+		if cred.Username == "" {
+			// git needs a username, but token-based auth ignores it.
+			cred.Username = "-"
+		}
+	}
+	return creds, resp.ExpiresAt, nil
+}
+
+// credExecReason returns why --credential-exec needs to be run, or "" if the
+// cached credentials can still be used.
+func (git *repoSync) credExecReason(now time.Time) string {
+	switch {
+	case git.credExecRuns == 0:
+		return credExecReasonInitial
+	case git.credExecRejected:
+		return credExecReasonRejected
+	case git.credExecExpiry.IsZero():
+		return credExecReasonSync
+	case git.credExecExpiry.Before(now.Add(30 * time.Second)):
+		return credExecReasonExpiring
+	}
+	return ""
+}
+
+// CallCredentialExec runs the --credential-exec command, and stores the
+// credentials it prints.  The command is told the repo and why it is being
+// run through its environment.
+func (git *repoSync) CallCredentialExec(ctx context.Context, reason string) error {
+	git.log.V(3).Info("running credential-exec to get credentials", "reason", reason)
+
+	ctx, cancel := context.WithTimeout(ctx, git.credExecTimeout)
+	defer cancel()
+
+	env := os.Environ()
+	env = append(env,
+		"GITSYNC_REPO="+git.repo,
+		"GITSYNC_CREDENTIAL_REASON="+reason,
+	)
+	stdout, _, err := git.run.RunWithSecretOutput(ctx, "", env, git.credExec)
+	if err != nil {
+		return fmt.Errorf("credential-exec failed: %w", err)
+	}
+	creds, expiry, err := parseExecCredentials(git.repo, []byte(stdout))
+	if err != nil {
+		return err
+	}
+	for _, cred := range creds {
+		if err := git.StoreCredentials(ctx, cred.URL, cred.Username, cred.Password); err != nil {
+			return err
+		}
+	}
+	git.credExecRuns++
+	git.credExecRejected = false
+	git.credExecExpiry = cacheExpiry(time.Now(), expiry)
+	if !expiry.IsZero() {
+		git.log.V(3).Info("credential-exec credentials will be cached", "expiresAt", expiry, "refreshAt", git.credExecExpiry)
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/git-sync/pkg/cmd"
+	"k8s.io/git-sync/pkg/logging"
+)
+
+func TestParseExecCredentials(t *testing.T) {
+	const repo = "https://example.com/repo"
+	expiry := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	cases := []struct {
+		name      string
+		out       string
+		expCreds  []execCredential
+		expExpiry time.Time
+		expErr    bool
+	}{{
+		name:     "password",
+		out:      `{"username": "u", "password": "p"}`,
+		expCreds: []execCredential{{URL: repo, Username: "u", Password: "p"}},
+	}, {
+		name:      "token-with-expiry",
+		out:       `{"token": "t", "expires_at": "2026-01-02T03:04:05Z"}`,
+		expCreds:  []execCredential{{URL: repo, Username: "-", Password: "t"}},
+		expExpiry: expiry,
+	}, {
+		name:     "explicit-url",
+		out:      `{"url": "https://example.com", "username": "u", "token": "t"}`,
+		expCreds: []execCredential{{URL: "https://example.com", Username: "u", Password: "t"}},
+	}, {
+		name: "per-url",
+		out:  `{"credentials": [{"url": "https://example.com/sub", "token": "t"}]}`,
+		expCreds: []execCredential{
+			{URL: "https://example.com/sub", Username: "-", Password: "t"},
+		},
+	}, {
+		name: "main-and-per-url",
+		out:  `{"password": "p", "credentials": [{"url": "https://example.com/sub", "username": "su", "password": "sp"}]}`,
+		expCreds: []execCredential{
+			{URL: repo, Username: "-", Password: "p"},
+			{URL: "https://example.com/sub", Username: "su", Password: "sp"},
+		},
+	}, {
+		name:      "kubectl",
+		out:       `{"apiVersion": "client.authentication.k8s.io/v1", "kind": "ExecCredential", "status": {"token": "t", "expirationTimestamp": "2026-01-02T03:04:05Z"}}`,
+		expCreds:  []execCredential{{URL: repo, Username: "-", Password: "t"}},
+		expExpiry: expiry,
+	}, {
+		name:   "password-and-token",
+		out:    `{"password": "p", "token": "t"}`,
+		expErr: true,
+	}, {
+		name:   "empty",
+		out:    `{}`,
+		expErr: true,
+	}, {
+		name:   "per-url-no-url",
+		out:    `{"credentials": [{"token": "t"}]}`,
+		expErr: true,
+	}, {
+		name:   "per-url-no-secret",
+		out:    `{"credentials": [{"url": "https://example.com/sub"}]}`,
+		expErr: true,
+	}, {
+		name:   "not-json",
+		out:    "username=u\npassword=p\n",
+		expErr: true,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			creds, expiry, err := parseExecCredentials(repo, []byte(tc.out))
+			if err != nil && !tc.expErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err == nil && tc.expErr {
+				t.Fatalf("unexpected success: %+v", creds)
+			}
+			if tc.expErr {
+				return
+			}
+			if !reflect.DeepEqual(tc.expCreds, creds) {
+				t.Errorf("expected %+v, got %+v", tc.expCreds, creds)
+			}
+			if !tc.expExpiry.Equal(expiry) {
+				t.Errorf("expected expiry %v, got %v", tc.expExpiry, expiry)
+			}
+		})
+	}
+}
+
+func TestCredExecReason(t *testing.T) {
+	now := time.Now()
+
+	cases := []struct {
+		name      string
+		git       repoSync
+		expReason string
+	}{{
+		name:      "never-run",
+		git:       repoSync{},
+		expReason: credExecReasonInitial,
+	}, {
+		name:      "no-expiry",
+		git:       repoSync{credExecRuns: 1},
+		expReason: credExecReasonSync,
+	}, {
+		name:      "cached",
+		git:       repoSync{credExecRuns: 1, credExecExpiry: now.Add(time.Hour)},
+		expReason: "",
+	}, {
+		name:      "expiring",
+		git:       repoSync{credExecRuns: 1, credExecExpiry: now.Add(10 * time.Second)},
+		expReason: credExecReasonExpiring,
+	}, {
+		name:      "rejected",
+		git:       repoSync{credExecRuns: 1, credExecExpiry: now.Add(time.Hour), credExecRejected: true},
+		expReason: credExecReasonRejected,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.git.credExecReason(now); got != tc.expReason {
+				t.Errorf("expected %q, got %q", tc.expReason, got)
+			}
+		})
+	}
+}
+
+func TestCallCredentialExecExpiry(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(dir, "gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "true")
+	script := filepath.Join(dir, "creds.sh")
+	expiresAt := time.Now().Add(3 * time.Hour).UTC().Format(time.RFC3339)
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho '{\"token\": \"t\", \"expires_at\": \""+expiresAt+"\"}'\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	log := logging.New("", "", 0)
+	git := &repoSync{
+		cmd:             "git",
+		repo:            "https://git.example.com/repo.git",
+		log:             log,
+		run:             cmd.NewRunner(log),
+		credExec:        script,
+		credExecTimeout: 10 * time.Second,
+	}
+	ctx := context.Background()
+	if _, _, err := git.Run(ctx, "", "config", "--global", "credential.helper", "store --file "+filepath.Join(dir, "git-credentials")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Credentials which outlast git's credential cache are fetched again
+	// before the cache forgets them.
+	if err := git.CallCredentialExec(ctx, credExecReasonInitial); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if left := time.Until(git.credExecExpiry); left <= 0 || left >= credentialCacheTimeout {
+		t.Errorf("expected expiry within the credential cache timeout, got %v left", left)
+	}
+	if got := git.credExecReason(time.Now().Add(credentialCacheTimeout)); got != credExecReasonExpiring {
+		t.Errorf("expected %q after the credential cache timeout, got %q", credExecReasonExpiring, got)
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// credentialFiles tracks files which hold credentials, so they can be re-read
+// when they change (e.g. when Kubernetes updates a mounted Secret) without
+// restarting git-sync.
+type credentialFiles struct {
+	versions map[string]fileVersion
+}
+
+// fileVersion identifies the contents of a file.
+type fileVersion struct {
+	exists  bool
+	modTime time.Time
+	size    int64
+	sum     [sha256.Size]byte
+}
+
+func newCredentialFiles() *credentialFiles {
+	return &credentialFiles{versions: map[string]fileVersion{}}
+}
+
+// changed returns true if the file at path has changed since the last call,
+// or if this is the first call for path.  A file which does not exist is not
+// an error, but a file appearing or disappearing is a change.
+//
+// The file's size and modification time are checked first, and the contents
+// are only read if those differ.  The contents are compared too, because
+// Kubernetes updates Secrets by swapping symlinks, which can change the
+// modification time without changing the contents.
+func (cf *credentialFiles) changed(path string) (bool, error) {
+	last, seen := cf.versions[path]
+
+	cur := fileVersion{}
+	fi, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		cf.versions[path] = cur
+		return !seen || last.exists, nil
+	} else if err != nil {
+		return false, err
+	}
+	cur.exists = true
+	cur.modTime = fi.ModTime()
+	cur.size = fi.Size()
+	if seen && last.exists && cur.modTime.Equal(last.modTime) && cur.size == last.size {
+		return false, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	cur.sum = sha256.Sum256(b)
+	cf.versions[path] = cur
+	return !seen || !last.exists || cur.sum != last.sum, nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCredentialFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "password")
+	cf := newCredentialFiles()
+
+	check := func(step string, want bool) {
+		t.Helper()
+		got, err := cf.changed(path)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", step, err)
+		}
+		if got != want {
+			t.Errorf("%s: expected changed=%v, got %v", step, want, got)
+		}
+	}
+	write := func(content string, mtime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t0 := time.Now().Add(-time.Hour)
+
+	check("missing, first", true)
+	check("missing, again", false)
+
+	write("one", t0)
+	check("created", true)
+	check("unchanged", false)
+
+	// Same contents, but touched (e.g. a Secret's symlink was swapped).
+	write("one", t0.Add(time.Minute))
+	check("touched", false)
+
+	write("two", t0.Add(2*time.Minute))
+	check("rewritten", true)
+
+	// Same size and mtime is assumed to be unchanged.
+	write("six", t0.Add(2*time.Minute))
+	check("same stat", false)
+
+	// Kubernetes-style update: the file is a symlink into a directory which
+	// is replaced.
+	data1 := filepath.Join(dir, "data1")
+	data2 := filepath.Join(dir, "data2")
+	for _, d := range []string{data1, data2} {
+		if err := os.Mkdir(d, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(data1, "key"), []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(data2, "key"), []byte("new"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(data2, "key"), t0, t0); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(data1, "key"), path); err != nil {
+		t.Fatal(err)
+	}
+	check("symlink", true)
+	tmp := filepath.Join(dir, "tmp")
+	if err := os.Symlink(filepath.Join(data2, "key"), tmp); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	check("symlink swapped", true)
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	check("removed", true)
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// githubAppInstallation is a GitHub app installation, whose tokens are used
+// for URLs which start with URLPrefix.
+type githubAppInstallation struct {
+	ID int `json:"installation-id"`
+	// If empty, the token is used for --repo (and, since git matches
+	// credentials by host, anything else on the same host).
+	URLPrefix string `json:"url-prefix"`
+	// If not empty, the token can only access these repositories, which
+	// must be owned by the installation's account.
+	Repositories []string `json:"repositories,omitempty"`
+}
+
+func (gi githubAppInstallation) String() string {
+	jb, err := json.Marshal(gi)
+	if err != nil {
+		return fmt.Sprintf("<encoding error: %v>", err)
+	}
+	return string(jb)
+}
+
+// validateGitHubAppInstallations checks the --github-app-installation flags.
+// The primaryID is --github-app-installation-id, or 0.
+func validateGitHubAppInstallations(installations []githubAppInstallation, primaryID int) error {
+	ids := map[int]bool{}
+	if primaryID != 0 {
+		ids[primaryID] = true
+	}
+	for i, gi := range installations {
+		if gi.ID <= 0 {
+			return fmt.Errorf("installation %d: installation-id must be specified", i)
+		}
+		if ids[gi.ID] {
+			return fmt.Errorf("installation %d: installation-id %d may only be specified once", i, gi.ID)
+		}
+		ids[gi.ID] = true
+		if gi.URLPrefix == "" {
+			return fmt.Errorf("installation %d: url-prefix must be specified", i)
+		}
+		u, err := url.Parse(gi.URLPrefix)
+		if err != nil {
+			return fmt.Errorf("installation %d: invalid url-prefix: %w", i, err)
+		}
+		if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("installation %d: url-prefix must be an http or https URL", i)
+		}
+		if u.User != nil {
+			return fmt.Errorf("installation %d: url-prefix may not include credentials", i)
+		}
+		for _, repo := range gi.Repositories {
+			if repo == "" || strings.Contains(repo, "/") {
+				return fmt.Errorf("installation %d: invalid repository %q (expected a name without the owner)", i, repo)
+			}
+		}
+	}
+	return nil
+}
+
+// pflagGitHubAppInstallationSlice is like pflag.StringSlice().
+func pflagGitHubAppInstallationSlice(name, def, usage string) *[]githubAppInstallation {
+	return pflagJSONSlice[githubAppInstallation](name, "githubAppInstallationSlice", def, usage)
+}
@@ -0,0 +1,535 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"k8s.io/git-sync/pkg/cmd"
+	"k8s.io/git-sync/pkg/hook"
+)
+
+const (
+	hookTypeExec   = "exec"
+	hookTypeWeb    = "webhook"
+	hookTypeSignal = "signal"
+	hookTypeSocket = hook.IPCSocket
+	hookTypeFIFO   = hook.IPCFIFO
+	hookTypeSpool  = hook.IPCSpool
+)
+
+// Events which can trigger hooks.
+const (
+	// The default: a new hash was synced.
+	hookOnSync = "sync"
+	// Another hook gave up on a hash.
+	hookOnHookFailure = "hook-failure"
+	// A new hash is about to be published, and may be rejected.
+	hookOnValidate = "validate"
+	// Syncing has failed a number of times in a row.
+	hookOnSyncFailure = hook.EventSyncFailure
+	// Syncing has succeeded after failing a number of times in a row.
+	hookOnSyncRecovery = hook.EventSyncRecovery
+	// git-sync is about to exit because of too many failures.
+	hookOnExit = hook.EventExit
+)
+
+// How hooks handle new events while they are busy.
+const (
+	// Only the latest event is kept.
+	hookDeliveryLatest = "latest"
+	// Every event is queued.
+	hookDeliveryEvery = "every"
+)
+
+const defaultHookQueueSize = 100
+
+// How many runs of each exechook to remember by default.
+const defaultHookOutputHistory = 10
+
+// Defaults for hook configs, which match the defaults for the older
+// --exechook-* and --webhook-* flags.
+const (
+	defaultExechookTimeout   = 30 * time.Second
+	defaultWebhookTimeout    = 1 * time.Second
+	defaultHookBackoff       = 3 * time.Second
+	defaultWebhookMethod     = "POST"
+	defaultWebhookSuccess    = 200
+	defaultSignalhookTimeout = 1 * time.Second
+	defaultSignalhookSignal  = "SIGHUP"
+	defaultIPChookTimeout    = 1 * time.Second
+)
+
+// hookConfig describes one hook, as specified by the --hook flag.
+type hookConfig struct {
+	Name          string       `json:"name"`
+	Type          string       `json:"type"`
+	Command       string       `json:"command,omitempty"`
+	Args          []string     `json:"args,omitempty"`
+	URL           string       `json:"url,omitempty"`
+	Method        string       `json:"method,omitempty"`
+	SuccessStatus *int         `json:"success-status,omitempty"`
+	Timeout       jsonDuration `json:"timeout,omitempty"`
+	Backoff       jsonDuration `json:"backoff,omitempty"`
+	Async         *bool        `json:"async,omitempty"`
+	After         []string     `json:"after,omitempty"`
+	On            string       `json:"on,omitempty"`
+	Failures      int          `json:"failures,omitempty"`
+
+	// Retry policy.
+	MaxBackoff       jsonDuration `json:"max-backoff,omitempty"`
+	MaxAttempts      int          `json:"max-attempts,omitempty"`
+	FailureThreshold int          `json:"failure-threshold,omitempty"`
+
+	// Delivery policy.
+	Delivery      string `json:"delivery,omitempty"`
+	QueueSize     int    `json:"queue-size,omitempty"`
+	QueueOverflow string `json:"queue-overflow,omitempty"`
+
+	// Exechook output capture.
+	OutputHistory int  `json:"output-history,omitempty"`
+	OutputFile    bool `json:"output-file,omitempty"`
+
+	// Exechook resource limits, in the same form as --exechook-limits.
+	Limits string `json:"limits,omitempty"`
+
+	// Webhook request details.
+	Headers          map[string]string `json:"headers,omitempty"`
+	HeaderFiles      map[string]string `json:"header-files,omitempty"`
+	BearerTokenFile  string            `json:"bearer-token-file,omitempty"`
+	Body             string            `json:"body,omitempty"`
+	BodyTemplate     string            `json:"body-template,omitempty"`
+	BodyTemplateFile string            `json:"body-template-file,omitempty"`
+	HMACSecretFile   string            `json:"hmac-secret-file,omitempty"`
+	TLSCAFile        string            `json:"tls-ca-file,omitempty"`
+	TLSCertFile      string            `json:"tls-cert-file,omitempty"`
+	TLSKeyFile       string            `json:"tls-key-file,omitempty"`
+
+	// Signal hook details.
+	Signal       string `json:"signal,omitempty"`
+	PIDFile      string `json:"pid-file,omitempty"`
+	ProcessName  string `json:"process-name,omitempty"`
+	ProcessRegex string `json:"process-regex,omitempty"`
+
+	// The socket, FIFO, or spool directory for IPC hooks.
+	Path string `json:"path,omitempty"`
+}
+
+// isWebhookOnly returns true if any webhook-specific fields are set.
+func (hc hookConfig) isWebhookOnly() bool {
+	return hc.URL != "" || hc.Method != "" || hc.SuccessStatus != nil ||
+		len(hc.Headers) > 0 || len(hc.HeaderFiles) > 0 || hc.BearerTokenFile != "" ||
+		hc.Body != "" || hc.BodyTemplate != "" || hc.BodyTemplateFile != "" ||
+		hc.HMACSecretFile != "" || hc.TLSCAFile != "" || hc.TLSCertFile != "" || hc.TLSKeyFile != ""
+}
+
+// isSignalOnly returns true if any signal-hook-specific fields are set.
+func (hc hookConfig) isSignalOnly() bool {
+	return hc.Signal != "" || hc.PIDFile != "" || hc.ProcessName != "" || hc.ProcessRegex != ""
+}
+
+// isExecOnly returns true if any exechook-specific fields are set.
+func (hc hookConfig) isExecOnly() bool {
+	return hc.Command != "" || len(hc.Args) > 0 || hc.OutputHistory != 0 || hc.OutputFile || hc.Limits != ""
+}
+
+// processSelector returns the hook.ProcessSelector for a signal hook.  The
+// config must have been validated.
+func (hc hookConfig) processSelector() hook.ProcessSelector {
+	sel := hook.ProcessSelector{
+		PIDFile: hc.PIDFile,
+		Name:    hc.ProcessName,
+	}
+	if hc.ProcessRegex != "" {
+		sel.Cmdline = regexp.MustCompile(hc.ProcessRegex)
+	}
+	return sel
+}
+
+// redacted returns a copy of the hook config, with any sensitive values
+// redacted, suitable for logging.
+func (hc hookConfig) redacted() hookConfig {
+	if len(hc.Headers) > 0 {
+		headers := map[string]string{}
+		for k := range hc.Headers {
+			headers[k] = redactedString
+		}
+		hc.Headers = headers
+	}
+	return hc
+}
+
+func (hc hookConfig) String() string {
+	jb, err := json.Marshal(hc)
+	if err != nil {
+		return fmt.Sprintf("<encoding error: %v>", err)
+	}
+	return string(jb)
+}
+
+// jsonDuration is a time.Duration which is encoded in JSON as a string, e.g.
+// "3s".
+type jsonDuration time.Duration
+
+func (d jsonDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *jsonDuration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("durations must be strings (e.g. \"3s\"): %w", err)
+	}
+	dur, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = jsonDuration(dur)
+	return nil
+}
+
+// validateHookConfigs checks a list of hooks for errors and fills in any
+// defaults.  The list is modified in place.
+func validateHookConfigs(hooks []hookConfig, defaultAsync bool) error {
+	names := map[string]bool{}
+	for i := range hooks {
+		hc := &hooks[i]
+		if hc.Name == "" {
+			return fmt.Errorf("hook %d: name must be specified", i)
+		}
+		if names[hc.Name] {
+			return fmt.Errorf("hook %q: name must be unique", hc.Name)
+		}
+		names[hc.Name] = true
+
+		switch hc.On {
+		case "":
+			hc.On = hookOnSync
+		case hookOnSync, hookOnHookFailure:
+		case hookOnSyncFailure, hookOnSyncRecovery:
+			if hc.Failures == 0 {
+				hc.Failures = 1
+			}
+			if hc.Failures < 0 {
+				return fmt.Errorf("hook %q: failures must be at least 1", hc.Name)
+			}
+		case hookOnValidate, hookOnExit:
+			// These hooks are run synchronously, in order, and are not
+			// retried.
+			if len(hc.After) > 0 || (hc.Async != nil && *hc.Async) || hc.MaxBackoff != 0 || hc.MaxAttempts != 0 || hc.FailureThreshold != 0 {
+				return fmt.Errorf("hook %q: after, async, max-backoff, max-attempts, and failure-threshold are not valid for %q hooks", hc.Name, hc.On)
+			}
+			if hc.On == hookOnValidate && hc.SuccessStatus != nil && *hc.SuccessStatus == 0 {
+				return fmt.Errorf("hook %q: success-status must not be 0 for %q hooks", hc.Name, hookOnValidate)
+			}
+			if hc.Delivery != "" {
+				return fmt.Errorf("hook %q: delivery is not valid for %q hooks", hc.Name, hc.On)
+			}
+		default:
+			return fmt.Errorf("hook %q: on must be one of %q, %q, %q, %q, %q, or %q", hc.Name,
+				hookOnSync, hookOnHookFailure, hookOnValidate, hookOnSyncFailure, hookOnSyncRecovery, hookOnExit)
+		}
+		if hc.Failures != 0 && hc.On != hookOnSyncFailure && hc.On != hookOnSyncRecovery {
+			return fmt.Errorf("hook %q: failures is only valid for %q and %q hooks", hc.Name, hookOnSyncFailure, hookOnSyncRecovery)
+		}
+
+		if hc.Path != "" && hc.Type != hookTypeSocket && hc.Type != hookTypeFIFO && hc.Type != hookTypeSpool {
+			return fmt.Errorf("hook %q: path is only valid for %q, %q, and %q hooks", hc.Name, hookTypeSocket, hookTypeFIFO, hookTypeSpool)
+		}
+
+		switch hc.Type {
+		case hookTypeExec:
+			if hc.Command == "" {
+				return fmt.Errorf("hook %q: command must be specified for %q hooks", hc.Name, hc.Type)
+			}
+			if hc.isWebhookOnly() {
+				return fmt.Errorf("hook %q: url, method, success-status, headers, body, hmac, and tls fields are only valid for %q hooks", hc.Name, hookTypeWeb)
+			}
+			if hc.isSignalOnly() {
+				return fmt.Errorf("hook %q: signal, pid-file, process-name, and process-regex are only valid for %q hooks", hc.Name, hookTypeSignal)
+			}
+			if hc.Timeout == 0 {
+				hc.Timeout = jsonDuration(defaultExechookTimeout)
+			}
+			if hc.OutputHistory == 0 {
+				hc.OutputHistory = defaultHookOutputHistory
+			}
+			if hc.OutputHistory < 0 {
+				return fmt.Errorf("hook %q: output-history must not be negative", hc.Name)
+			}
+			if _, err := cmd.ParseLimits(hc.Limits); err != nil {
+				return fmt.Errorf("hook %q: %w", hc.Name, err)
+			}
+		case hookTypeWeb:
+			if hc.URL == "" {
+				return fmt.Errorf("hook %q: url must be specified for %q hooks", hc.Name, hc.Type)
+			}
+			if hc.isExecOnly() {
+				return fmt.Errorf("hook %q: command, args, output-history, output-file, and limits are only valid for %q hooks", hc.Name, hookTypeExec)
+			}
+			if hc.isSignalOnly() {
+				return fmt.Errorf("hook %q: signal, pid-file, process-name, and process-regex are only valid for %q hooks", hc.Name, hookTypeSignal)
+			}
+			if hc.Method == "" {
+				hc.Method = defaultWebhookMethod
+			}
+			if hc.SuccessStatus == nil {
+				n := defaultWebhookSuccess
+				if hc.On == hookOnValidate {
+					n = hook.WebhookSuccess2xx
+				}
+				hc.SuccessStatus = &n
+			} else if *hc.SuccessStatus < 0 {
+				return fmt.Errorf("hook %q: success-status must be a valid HTTP code or 0", hc.Name)
+			}
+			if hc.Timeout == 0 {
+				hc.Timeout = jsonDuration(defaultWebhookTimeout)
+			}
+			if (hc.TLSCertFile == "") != (hc.TLSKeyFile == "") {
+				return fmt.Errorf("hook %q: tls-cert-file and tls-key-file must be specified together", hc.Name)
+			}
+			if hc.BodyTemplate != "" && hc.BodyTemplateFile != "" {
+				return fmt.Errorf("hook %q: only one of body-template and body-template-file may be specified", hc.Name)
+			}
+			if hc.BodyTemplateFile != "" {
+				b, err := os.ReadFile(hc.BodyTemplateFile)
+				if err != nil {
+					return fmt.Errorf("hook %q: can't read body-template-file: %w", hc.Name, err)
+				}
+				hc.BodyTemplate = string(b)
+				hc.BodyTemplateFile = ""
+			}
+			switch hc.Body {
+			case "":
+				if hc.BodyTemplate != "" {
+					hc.Body = hook.WebhookBodyTemplate
+				} else {
+					hc.Body = hook.WebhookBodyJSON
+				}
+			case hook.WebhookBodyJSON, hook.WebhookBodyEmpty:
+				if hc.BodyTemplate != "" {
+					return fmt.Errorf("hook %q: body-template may only be specified when body is %q", hc.Name, hook.WebhookBodyTemplate)
+				}
+			case hook.WebhookBodyTemplate:
+				if hc.BodyTemplate == "" {
+					return fmt.Errorf("hook %q: body-template or body-template-file must be specified when body is %q", hc.Name, hook.WebhookBodyTemplate)
+				}
+			default:
+				return fmt.Errorf("hook %q: body must be one of %q, %q, or %q", hc.Name, hook.WebhookBodyJSON, hook.WebhookBodyEmpty, hook.WebhookBodyTemplate)
+			}
+		case hookTypeSignal:
+			if hc.isExecOnly() {
+				return fmt.Errorf("hook %q: command, args, output-history, output-file, and limits are only valid for %q hooks", hc.Name, hookTypeExec)
+			}
+			if hc.isWebhookOnly() {
+				return fmt.Errorf("hook %q: url, method, success-status, headers, body, hmac, and tls fields are only valid for %q hooks", hc.Name, hookTypeWeb)
+			}
+			n := 0
+			for _, s := range []string{hc.PIDFile, hc.ProcessName, hc.ProcessRegex} {
+				if s != "" {
+					n++
+				}
+			}
+			if n != 1 {
+				return fmt.Errorf("hook %q: exactly one of pid-file, process-name, or process-regex must be specified for %q hooks", hc.Name, hc.Type)
+			}
+			if hc.ProcessRegex != "" {
+				if _, err := regexp.Compile(hc.ProcessRegex); err != nil {
+					return fmt.Errorf("hook %q: invalid process-regex: %w", hc.Name, err)
+				}
+			}
+			if hc.Signal == "" {
+				hc.Signal = defaultSignalhookSignal
+			}
+			if _, err := hook.ParseSignal(hc.Signal); err != nil {
+				return fmt.Errorf("hook %q: %w", hc.Name, err)
+			}
+			if hc.Timeout == 0 {
+				hc.Timeout = jsonDuration(defaultSignalhookTimeout)
+			}
+		case hookTypeSocket, hookTypeFIFO, hookTypeSpool:
+			if hc.Path == "" {
+				return fmt.Errorf("hook %q: path must be specified for %q hooks", hc.Name, hc.Type)
+			}
+			if hc.isExecOnly() {
+				return fmt.Errorf("hook %q: command, args, output-history, output-file, and limits are only valid for %q hooks", hc.Name, hookTypeExec)
+			}
+			if hc.isWebhookOnly() {
+				return fmt.Errorf("hook %q: url, method, success-status, headers, body, hmac, and tls fields are only valid for %q hooks", hc.Name, hookTypeWeb)
+			}
+			if hc.isSignalOnly() {
+				return fmt.Errorf("hook %q: signal, pid-file, process-name, and process-regex are only valid for %q hooks", hc.Name, hookTypeSignal)
+			}
+			if hc.Timeout == 0 {
+				hc.Timeout = jsonDuration(defaultIPChookTimeout)
+			}
+		default:
+			return fmt.Errorf("hook %q: type must be one of %q, %q, %q, %q, %q, or %q", hc.Name,
+				hookTypeExec, hookTypeWeb, hookTypeSignal, hookTypeSocket, hookTypeFIFO, hookTypeSpool)
+		}
+
+		if time.Duration(hc.Timeout) < time.Second {
+			return fmt.Errorf("hook %q: timeout must be at least 1s", hc.Name)
+		}
+		if hc.Backoff == 0 {
+			hc.Backoff = jsonDuration(defaultHookBackoff)
+		}
+		if time.Duration(hc.Backoff) < time.Second {
+			return fmt.Errorf("hook %q: backoff must be at least 1s", hc.Name)
+		}
+		if hc.MaxBackoff == 0 {
+			hc.MaxBackoff = hc.Backoff
+		}
+		if hc.MaxBackoff < hc.Backoff {
+			return fmt.Errorf("hook %q: max-backoff must be at least backoff", hc.Name)
+		}
+		if hc.MaxAttempts < 0 {
+			return fmt.Errorf("hook %q: max-attempts must be at least 0", hc.Name)
+		}
+		if hc.FailureThreshold < 0 {
+			return fmt.Errorf("hook %q: failure-threshold must be at least 0", hc.Name)
+		}
+		switch hc.Delivery {
+		case "", hookDeliveryLatest:
+			if hc.QueueSize != 0 || hc.QueueOverflow != "" {
+				return fmt.Errorf("hook %q: queue-size and queue-overflow are only valid when delivery is %q", hc.Name, hookDeliveryEvery)
+			}
+			if hc.On != hookOnValidate && hc.On != hookOnExit {
+				hc.Delivery = hookDeliveryLatest
+			}
+		case hookDeliveryEvery:
+			if hc.QueueSize == 0 {
+				hc.QueueSize = defaultHookQueueSize
+			}
+			if hc.QueueSize < 0 {
+				return fmt.Errorf("hook %q: queue-size must be at least 1", hc.Name)
+			}
+			switch hc.QueueOverflow {
+			case "":
+				hc.QueueOverflow = hook.QueueDropOldest
+			case hook.QueueDropOldest, hook.QueueDropNewest, hook.QueueBlock:
+			default:
+				return fmt.Errorf("hook %q: queue-overflow must be one of %q, %q, or %q", hc.Name, hook.QueueDropOldest, hook.QueueDropNewest, hook.QueueBlock)
+			}
+			// A dependency might skip or drop events, which would leave a
+			// queued hook waiting forever.
+			if len(hc.After) > 0 {
+				return fmt.Errorf("hook %q: after is not valid when delivery is %q", hc.Name, hookDeliveryEvery)
+			}
+		default:
+			return fmt.Errorf("hook %q: delivery must be one of %q or %q", hc.Name, hookDeliveryLatest, hookDeliveryEvery)
+		}
+		if hc.Async == nil {
+			async := defaultAsync
+			hc.Async = &async
+		}
+	}
+
+	// Check dependencies only after all names are known.
+	on := map[string]string{}
+	delivery := map[string]string{}
+	failures := map[string]int{}
+	for _, hc := range hooks {
+		on[hc.Name] = hc.On
+		delivery[hc.Name] = hc.Delivery
+		failures[hc.Name] = hc.Failures
+	}
+	for _, hc := range hooks {
+		for _, dep := range hc.After {
+			if !names[dep] {
+				return fmt.Errorf("hook %q: unknown hook in after: %q", hc.Name, dep)
+			}
+			if dep == hc.Name {
+				return fmt.Errorf("hook %q: can not depend on itself", hc.Name)
+			}
+			if on[dep] != hc.On {
+				return fmt.Errorf("hook %q: can not depend on hook %q, which is triggered on a different event", hc.Name, dep)
+			}
+			if failures[dep] != hc.Failures {
+				return fmt.Errorf("hook %q: can not depend on hook %q, which has a different failures value", hc.Name, dep)
+			}
+			if delivery[dep] == hookDeliveryEvery {
+				return fmt.Errorf("hook %q: can not depend on hook %q, which has delivery %q", hc.Name, dep, hookDeliveryEvery)
+			}
+		}
+	}
+	if cycle := findHookCycle(hooks); len(cycle) > 0 {
+		return fmt.Errorf("hooks have a dependency cycle: %s", strings.Join(cycle, " -> "))
+	}
+
+	return nil
+}
+
+// findHookCycle returns the names of the hooks in a dependency cycle, or nil
+// if there are no cycles.
+func findHookCycle(hooks []hookConfig) []string {
+	deps := map[string][]string{}
+	for _, hc := range hooks {
+		deps[hc.Name] = hc.After
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	var path []string
+
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		switch state[name] {
+		case visiting:
+			// Trim the path to just the cycle.
+			for i := range path {
+				if path[i] == name {
+					return append(append([]string{}, path[i:]...), name)
+				}
+			}
+			return []string{name, name}
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range deps[name] {
+			if cycle := visit(dep); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+
+	for _, hc := range hooks {
+		if cycle := visit(hc.Name); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// pflagHookConfigSlice is like pflag.StringSlice().
+func pflagHookConfigSlice(name, def, usage string) *[]hookConfig {
+	return pflagJSONSlice[hookConfig](name, "hookSlice", def, usage)
+}
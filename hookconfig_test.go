@@ -0,0 +1,442 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/git-sync/pkg/hook"
+)
+
+func TestHookConfigSliceValue(t *testing.T) {
+	hs := &jsonSliceValue[hookConfig]{}
+	if err := hs.Set(`{"name":"a", "type":"exec", "command":"/bin/true", "timeout":"5s"}`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := hs.Set(`[{"name":"b", "type":"webhook", "url":"http://example.com", "after":["a"]}]`); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hs.value) != 2 {
+		t.Fatalf("expected 2 hooks, got %d", len(hs.value))
+	}
+	if want, got := jsonDuration(5*time.Second), hs.value[0].Timeout; want != got {
+		t.Errorf("expected timeout %v, got %v", time.Duration(want), time.Duration(got))
+	}
+	if want, got := []string{"a"}, hs.value[1].After; !reflect.DeepEqual(want, got) {
+		t.Errorf("expected after %v, got %v", want, got)
+	}
+
+	for _, bad := range []string{
+		`{"name":"a", "type":"exec", "unknown":"field"}`,
+		`{"name":"a", "type":"exec", "timeout":5}`,
+		`{"name":"a", "type":"exec", "timeout":"five"}`,
+		`name=a`,
+	} {
+		if err := hs.Set(bad); err == nil {
+			t.Errorf("%s: unexpected success", bad)
+		}
+	}
+}
+
+func TestValidateHookConfigs(t *testing.T) {
+	intp := func(i int) *int { return &i }
+	boolp := func(b bool) *bool { return &b }
+
+	cases := []struct {
+		name  string
+		hooks []hookConfig
+		fail  bool
+	}{{
+		name:  "empty",
+		hooks: []hookConfig{},
+	}, {
+		name: "valid",
+		hooks: []hookConfig{
+			{Name: "a", Type: hookTypeExec, Command: "/bin/true"},
+			{Name: "b", Type: hookTypeWeb, URL: "http://example.com", After: []string{"a"}},
+			{Name: "c", Type: hookTypeWeb, URL: "http://example.com", After: []string{"a", "b"}},
+		},
+	}, {
+		name:  "no-name",
+		hooks: []hookConfig{{Type: hookTypeExec, Command: "/bin/true"}},
+		fail:  true,
+	}, {
+		name: "dup-name",
+		hooks: []hookConfig{
+			{Name: "a", Type: hookTypeExec, Command: "/bin/true"},
+			{Name: "a", Type: hookTypeExec, Command: "/bin/false"},
+		},
+		fail: true,
+	}, {
+		name:  "bad-type",
+		hooks: []hookConfig{{Name: "a", Type: "carrier-pigeon"}},
+		fail:  true,
+	}, {
+		name:  "exec-no-command",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec}},
+		fail:  true,
+	}, {
+		name:  "exec-with-url",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", URL: "http://example.com"}},
+		fail:  true,
+	}, {
+		name:  "webhook-no-url",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeWeb}},
+		fail:  true,
+	}, {
+		name:  "webhook-bad-status",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeWeb, URL: "http://example.com", SuccessStatus: intp(-1)}},
+		fail:  true,
+	}, {
+		name:  "short-timeout",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", Timeout: jsonDuration(time.Millisecond)}},
+		fail:  true,
+	}, {
+		name:  "short-backoff",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", Backoff: jsonDuration(time.Millisecond)}},
+		fail:  true,
+	}, {
+		name:  "webhook-template",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeWeb, URL: "http://example.com", BodyTemplate: "{{ .Hash }}"}},
+	}, {
+		name:  "webhook-template-wrong-body",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeWeb, URL: "http://example.com", Body: "json", BodyTemplate: "{{ .Hash }}"}},
+		fail:  true,
+	}, {
+		name:  "webhook-template-missing",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeWeb, URL: "http://example.com", Body: "template"}},
+		fail:  true,
+	}, {
+		name:  "webhook-template-file-missing",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeWeb, URL: "http://example.com", BodyTemplateFile: "/does/not/exist"}},
+		fail:  true,
+	}, {
+		name:  "webhook-bad-body",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeWeb, URL: "http://example.com", Body: "xml"}},
+		fail:  true,
+	}, {
+		name:  "exec-with-headers",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", Headers: map[string]string{"k": "v"}}},
+		fail:  true,
+	}, {
+		name:  "exec-with-hmac",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", HMACSecretFile: "/secret"}},
+		fail:  true,
+	}, {
+		name:  "webhook-tls-cert-no-key",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeWeb, URL: "https://example.com", TLSCertFile: "/tls.crt"}},
+		fail:  true,
+	}, {
+		name:  "webhook-tls",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeWeb, URL: "https://example.com", HMACSecretFile: "/secret", TLSCAFile: "/ca.crt", TLSCertFile: "/tls.crt", TLSKeyFile: "/tls.key"}},
+	}, {
+		name:  "retry-policy",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", Backoff: jsonDuration(time.Second), MaxBackoff: jsonDuration(time.Minute), MaxAttempts: 5, FailureThreshold: 10}},
+	}, {
+		name:  "max-backoff-too-short",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", Backoff: jsonDuration(time.Minute), MaxBackoff: jsonDuration(time.Second)}},
+		fail:  true,
+	}, {
+		name:  "negative-max-attempts",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", MaxAttempts: -1}},
+		fail:  true,
+	}, {
+		name:  "negative-failure-threshold",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", FailureThreshold: -1}},
+		fail:  true,
+	}, {
+		name:  "bad-on",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", On: "sometimes"}},
+		fail:  true,
+	}, {
+		name: "dep-on-different-event",
+		hooks: []hookConfig{
+			{Name: "a", Type: hookTypeExec, Command: "/bin/true"},
+			{Name: "b", Type: hookTypeExec, Command: "/bin/true", On: hookOnHookFailure, After: []string{"a"}},
+		},
+		fail: true,
+	}, {
+		name: "validate",
+		hooks: []hookConfig{
+			{Name: "a", Type: hookTypeExec, Command: "/bin/true", On: hookOnValidate},
+			{Name: "b", Type: hookTypeWeb, URL: "http://example.com", On: hookOnValidate},
+		},
+	}, {
+		name:  "validate-with-after",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true"}, {Name: "b", Type: hookTypeExec, Command: "/bin/true", On: hookOnValidate, After: []string{"a"}}},
+		fail:  true,
+	}, {
+		name:  "validate-with-retries",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", On: hookOnValidate, MaxAttempts: 3}},
+		fail:  true,
+	}, {
+		name:  "validate-async",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", On: hookOnValidate, Async: boolp(true)}},
+		fail:  true,
+	}, {
+		name:  "validate-fire-and-forget",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeWeb, URL: "http://example.com", On: hookOnValidate, SuccessStatus: intp(0)}},
+		fail:  true,
+	}, {
+		name:  "delivery-every",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", Delivery: hookDeliveryEvery, QueueSize: 10, QueueOverflow: hook.QueueBlock}},
+	}, {
+		name:  "bad-delivery",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", Delivery: "sometimes"}},
+		fail:  true,
+	}, {
+		name:  "queue-size-without-every",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", QueueSize: 10}},
+		fail:  true,
+	}, {
+		name:  "negative-queue-size",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", Delivery: hookDeliveryEvery, QueueSize: -1}},
+		fail:  true,
+	}, {
+		name:  "bad-queue-overflow",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", Delivery: hookDeliveryEvery, QueueOverflow: "explode"}},
+		fail:  true,
+	}, {
+		name:  "delivery-every-with-after",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true"}, {Name: "b", Type: hookTypeExec, Command: "/bin/true", Delivery: hookDeliveryEvery, After: []string{"a"}}},
+		fail:  true,
+	}, {
+		name:  "dep-on-delivery-every",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", Delivery: hookDeliveryEvery}, {Name: "b", Type: hookTypeExec, Command: "/bin/true", After: []string{"a"}}},
+		fail:  true,
+	}, {
+		name:  "validate-delivery",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", On: hookOnValidate, Delivery: hookDeliveryEvery}},
+		fail:  true,
+	}, {
+		name:  "exec-output",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", OutputHistory: 3, OutputFile: true}},
+	}, {
+		name:  "negative-output-history",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", OutputHistory: -1}},
+		fail:  true,
+	}, {
+		name:  "webhook-output-file",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeWeb, URL: "http://example.com", OutputFile: true}},
+		fail:  true,
+	}, {
+		name:  "exec-limits",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", Limits: "cpu=10s,nice=5"}},
+	}, {
+		name:  "exec-bad-limits",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", Limits: "cpu=forever"}},
+		fail:  true,
+	}, {
+		name:  "webhook-limits",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeWeb, URL: "http://example.com", Limits: "nice=5"}},
+		fail:  true,
+	}, {
+		name: "sync-failure-and-recovery",
+		hooks: []hookConfig{
+			{Name: "a", Type: hookTypeExec, Command: "/bin/true", On: hookOnSyncFailure, Failures: 3},
+			{Name: "b", Type: hookTypeWeb, URL: "http://example.com", On: hookOnSyncRecovery, After: []string{"c"}},
+			{Name: "c", Type: hookTypeExec, Command: "/bin/true", On: hookOnSyncRecovery},
+		},
+	}, {
+		name: "dep-on-different-failures",
+		hooks: []hookConfig{
+			{Name: "a", Type: hookTypeExec, Command: "/bin/true", On: hookOnSyncFailure, Failures: 3},
+			{Name: "b", Type: hookTypeExec, Command: "/bin/true", On: hookOnSyncFailure, After: []string{"a"}},
+		},
+		fail: true,
+	}, {
+		name:  "negative-failures",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", On: hookOnSyncFailure, Failures: -1}},
+		fail:  true,
+	}, {
+		name:  "sync-with-failures",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", Failures: 2}},
+		fail:  true,
+	}, {
+		name:  "exit",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeWeb, URL: "http://example.com", On: hookOnExit, SuccessStatus: intp(0)}},
+	}, {
+		name:  "exit-with-after",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true"}, {Name: "b", Type: hookTypeExec, Command: "/bin/true", On: hookOnExit, After: []string{"a"}}},
+		fail:  true,
+	}, {
+		name:  "exit-with-retries",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", On: hookOnExit, MaxAttempts: 3}},
+		fail:  true,
+	}, {
+		name: "signal",
+		hooks: []hookConfig{
+			{Name: "a", Type: hookTypeSignal, PIDFile: "/run/nginx.pid"},
+			{Name: "b", Type: hookTypeSignal, ProcessName: "prometheus", Signal: "HUP"},
+			{Name: "c", Type: hookTypeSignal, ProcessRegex: "^nginx: master", Signal: "SIGUSR1"},
+		},
+	}, {
+		name:  "signal-no-selector",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeSignal}},
+		fail:  true,
+	}, {
+		name:  "signal-two-selectors",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeSignal, PIDFile: "/run/nginx.pid", ProcessName: "nginx"}},
+		fail:  true,
+	}, {
+		name:  "signal-bad-regex",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeSignal, ProcessRegex: "nginx("}},
+		fail:  true,
+	}, {
+		name:  "signal-bad-signal",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeSignal, ProcessName: "nginx", Signal: "SIGNOPE"}},
+		fail:  true,
+	}, {
+		name:  "signal-with-command",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeSignal, ProcessName: "nginx", Command: "/bin/true"}},
+		fail:  true,
+	}, {
+		name:  "exec-with-signal",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", ProcessName: "nginx"}},
+		fail:  true,
+	}, {
+		name:  "webhook-with-signal",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeWeb, URL: "http://example.com", Signal: "HUP"}},
+		fail:  true,
+	}, {
+		name: "ipc",
+		hooks: []hookConfig{
+			{Name: "a", Type: hookTypeSocket, Path: "/run/app/git-sync.sock"},
+			{Name: "b", Type: hookTypeFIFO, Path: "/run/app/git-sync.fifo"},
+			{Name: "c", Type: hookTypeSpool, Path: "/run/app/spool"},
+		},
+	}, {
+		name:  "ipc-no-path",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeSocket}},
+		fail:  true,
+	}, {
+		name:  "ipc-with-url",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeSpool, Path: "/spool", URL: "http://example.com"}},
+		fail:  true,
+	}, {
+		name:  "exec-with-path",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", Path: "/spool"}},
+		fail:  true,
+	}, {
+		name:  "unknown-dep",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", After: []string{"b"}}},
+		fail:  true,
+	}, {
+		name:  "self-dep",
+		hooks: []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", After: []string{"a"}}},
+		fail:  true,
+	}, {
+		name: "cycle",
+		hooks: []hookConfig{
+			{Name: "a", Type: hookTypeExec, Command: "/bin/true", After: []string{"c"}},
+			{Name: "b", Type: hookTypeExec, Command: "/bin/true", After: []string{"a"}},
+			{Name: "c", Type: hookTypeExec, Command: "/bin/true", After: []string{"b"}},
+		},
+		fail: true,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateHookConfigs(tc.hooks, true)
+			if err != nil && !tc.fail {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if err == nil && tc.fail {
+				t.Errorf("unexpected success")
+			}
+		})
+	}
+}
+
+func TestValidateHookConfigsDefaults(t *testing.T) {
+	hooks := []hookConfig{
+		{Name: "a", Type: hookTypeExec, Command: "/bin/true"},
+		{Name: "b", Type: hookTypeWeb, URL: "http://example.com"},
+		{Name: "c", Type: hookTypeSignal, ProcessName: "nginx"},
+		{Name: "d", Type: hookTypeSpool, Path: "/spool"},
+	}
+	if err := validateHookConfigs(hooks, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want, got := defaultExechookTimeout, time.Duration(hooks[0].Timeout); want != got {
+		t.Errorf("exec: expected timeout %v, got %v", want, got)
+	}
+	if want, got := defaultWebhookTimeout, time.Duration(hooks[1].Timeout); want != got {
+		t.Errorf("webhook: expected timeout %v, got %v", want, got)
+	}
+	if want, got := defaultHookOutputHistory, hooks[0].OutputHistory; want != got {
+		t.Errorf("exec: expected output-history %d, got %d", want, got)
+	}
+	if want, got := defaultWebhookMethod, hooks[1].Method; want != got {
+		t.Errorf("webhook: expected method %q, got %q", want, got)
+	}
+	if want, got := defaultWebhookSuccess, *hooks[1].SuccessStatus; want != got {
+		t.Errorf("webhook: expected success-status %d, got %d", want, got)
+	}
+	if want, got := defaultSignalhookTimeout, time.Duration(hooks[2].Timeout); want != got {
+		t.Errorf("signal: expected timeout %v, got %v", want, got)
+	}
+	if want, got := defaultSignalhookSignal, hooks[2].Signal; want != got {
+		t.Errorf("signal: expected signal %q, got %q", want, got)
+	}
+	if want, got := defaultIPChookTimeout, time.Duration(hooks[3].Timeout); want != got {
+		t.Errorf("spool: expected timeout %v, got %v", want, got)
+	}
+	for _, hc := range hooks {
+		if want, got := defaultHookBackoff, time.Duration(hc.Backoff); want != got {
+			t.Errorf("%s: expected backoff %v, got %v", hc.Name, want, got)
+		}
+		if want, got := defaultHookBackoff, time.Duration(hc.MaxBackoff); want != got {
+			t.Errorf("%s: expected max-backoff %v, got %v", hc.Name, want, got)
+		}
+		if *hc.Async {
+			t.Errorf("%s: expected async to default to false", hc.Name)
+		}
+		if hc.On != hookOnSync {
+			t.Errorf("%s: expected on to default to %q, got %q", hc.Name, hookOnSync, hc.On)
+		}
+		if hc.Delivery != hookDeliveryLatest {
+			t.Errorf("%s: expected delivery to default to %q, got %q", hc.Name, hookDeliveryLatest, hc.Delivery)
+		}
+	}
+}
+
+func TestValidateHookConfigsQueueDefaults(t *testing.T) {
+	hooks := []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", Delivery: hookDeliveryEvery}}
+	if err := validateHookConfigs(hooks, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want, got := defaultHookQueueSize, hooks[0].QueueSize; want != got {
+		t.Errorf("expected queue-size %d, got %d", want, got)
+	}
+	if want, got := hook.QueueDropOldest, hooks[0].QueueOverflow; want != got {
+		t.Errorf("expected queue-overflow %q, got %q", want, got)
+	}
+}
+
+func TestValidateHookConfigsFailuresDefault(t *testing.T) {
+	hooks := []hookConfig{{Name: "a", Type: hookTypeExec, Command: "/bin/true", On: hookOnSyncRecovery}}
+	if err := validateHookConfigs(hooks, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want, got := 1, hooks[0].Failures; want != got {
+		t.Errorf("expected failures %d, got %d", want, got)
+	}
+}
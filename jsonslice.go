@@ -0,0 +1,152 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// jsonSliceValue is for flags which take a JSON object or a list of them.
+type jsonSliceValue[T fmt.Stringer] struct {
+	value    []T
+	changed  bool
+	typeName string
+}
+
+var _ pflag.Value = &jsonSliceValue[credential]{}
+var _ pflag.SliceValue = &jsonSliceValue[credential]{}
+
+// pflagJSONSlice is like pflag.StringSlice().
+func pflagJSONSlice[T fmt.Stringer](name, typeName, def, usage string) *[]T {
+	p := &jsonSliceValue[T]{typeName: typeName}
+	_ = p.Set(def)
+	pflag.Var(p, name, usage)
+	return &p.value
+}
+
+// unmarshal is like json.Unmarshal, but fails on unknown fields.
+func (js jsonSliceValue[T]) unmarshal(val string, out any) error {
+	dec := json.NewDecoder(strings.NewReader(val))
+	dec.DisallowUnknownFields()
+	return dec.Decode(out)
+}
+
+// decodeObject handles a string-encoded JSON object.
+func (js jsonSliceValue[T]) decodeObject(val string) (T, error) {
+	var obj T
+	if err := js.unmarshal(val, &obj); err != nil {
+		var zero T
+		return zero, err
+	}
+	return obj, nil
+}
+
+// decodeList handles a string-encoded JSON list.
+func (js jsonSliceValue[T]) decodeList(val string) ([]T, error) {
+	var objs []T
+	if err := js.unmarshal(val, &objs); err != nil {
+		return nil, err
+	}
+	return objs, nil
+}
+
+// decode handles a string-encoded JSON object or list.
+func (js jsonSliceValue[T]) decode(val string) ([]T, error) {
+	s := strings.TrimSpace(val)
+	if s == "" {
+		return nil, nil
+	}
+	// If it tastes like an object...
+	if s[0] == '{' {
+		obj, err := js.decodeObject(s)
+		return []T{obj}, err
+	}
+	// If it tastes like a list...
+	if s[0] == '[' {
+		return js.decodeList(s)
+	}
+	// Otherwise, bad
+	return nil, fmt.Errorf("not a JSON object or list")
+}
+
+func (js *jsonSliceValue[T]) Set(val string) error {
+	v, err := js.decode(val)
+	if err != nil {
+		return err
+	}
+
+	if !js.changed {
+		js.value = v
+	} else {
+		js.value = append(js.value, v...)
+	}
+	js.changed = true
+
+	return nil
+}
+
+func (js jsonSliceValue[T]) Type() string {
+	return js.typeName
+}
+
+func (js jsonSliceValue[T]) String() string {
+	if len(js.value) == 0 {
+		return "[]"
+	}
+	jb, err := json.Marshal(js.value)
+	if err != nil {
+		return fmt.Sprintf("<encoding error: %v>", err)
+	}
+	return string(jb)
+}
+
+func (js *jsonSliceValue[T]) Append(val string) error {
+	v, err := js.decodeObject(val)
+	if err != nil {
+		return err
+	}
+	js.value = append(js.value, v)
+	return nil
+}
+
+func (js *jsonSliceValue[T]) Replace(val []string) error {
+	objs := []T{}
+	for _, s := range val {
+		v, err := js.decodeObject(s)
+		if err != nil {
+			return err
+		}
+		objs = append(objs, v)
+	}
+	js.value = objs
+	return nil
+}
+
+func (js jsonSliceValue[T]) GetSlice() []string {
+	if len(js.value) == 0 {
+		return nil
+	}
+	ret := []string{}
+	for _, obj := range js.value {
+		ret = append(ret, obj.String())
+	}
+	return ret
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/git-sync/pkg/kube"
+	"k8s.io/git-sync/pkg/logging"
+)
+
+// How long to wait for each request to the Kubernetes API.  Reporting is
+// best-effort, so this is kept short to avoid delaying syncs.
+const kubeRequestTimeout = 5 * time.Second
+
+// Reasons for Kubernetes Events.
+const (
+	kubeReasonPublished     = "Published"
+	kubeReasonSyncFailed    = "SyncFailed"
+	kubeReasonSyncRecovered = "SyncRecovered"
+)
+
+// kubeReporter describes git-sync's progress through the Kubernetes API, as
+// Events and an annotation on its own pod.  Errors are logged, but are
+// otherwise ignored.  A nil *kubeReporter does nothing.
+type kubeReporter struct {
+	client *kube.Client
+	log    *logging.Logger
+	// Whether to record Events.
+	events bool
+	// The annotation to set to the published hash, or "".
+	annotation string
+	// How many consecutive failures before a failure is reported.
+	failures int
+}
+
+// published reports that a new hash was published.
+func (k *kubeReporter) published(repo, ref, hash string) {
+	if k == nil {
+		return
+	}
+	if k.annotation != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), kubeRequestTimeout)
+		defer cancel()
+		if err := k.client.SetAnnotations(ctx, map[string]string{k.annotation: hash}); err != nil {
+			k.log.Error(err, "can't update pod annotation", "annotation", k.annotation)
+		}
+	}
+	k.record(kube.EventTypeNormal, kubeReasonPublished, fmt.Sprintf("Published %s (ref %s) at %s", repo, ref, hash))
+}
+
+// syncFailed reports a failed sync, if it is the one which makes the number
+// of consecutive failures reach the threshold.
+func (k *kubeReporter) syncFailed(failCount int, serr *syncError) {
+	if k == nil || failCount != k.failures {
+		return
+	}
+	k.record(kube.EventTypeWarning, kubeReasonSyncFailed,
+		fmt.Sprintf("Sync failed %d times in a row (%s, %s): %v", failCount, serr.class, serr.reason, serr))
+}
+
+// syncRecovered reports a successful sync after failures which were
+// reported.
+func (k *kubeReporter) syncRecovered(failCount int) {
+	if k == nil || failCount < k.failures {
+		return
+	}
+	k.record(kube.EventTypeNormal, kubeReasonSyncRecovered, fmt.Sprintf("Sync succeeded after %d failures", failCount))
+}
+
+func (k *kubeReporter) record(eventType, reason, message string) {
+	if !k.events {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), kubeRequestTimeout)
+	defer cancel()
+	if err := k.client.RecordEvent(ctx, eventType, reason, k.log.Redact(message)); err != nil {
+		k.log.Error(err, "can't record Kubernetes event", "reason", reason)
+	}
+}
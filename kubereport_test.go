@@ -0,0 +1,87 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"k8s.io/git-sync/pkg/kube"
+	"k8s.io/git-sync/pkg/logging"
+)
+
+func TestKubeReporter(t *testing.T) {
+	var mutex sync.Mutex
+	reasons := []string{}
+	patches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		switch r.Method {
+		case http.MethodGet, http.MethodPatch:
+			if r.Method == http.MethodPatch {
+				patches++
+			}
+			_, _ = w.Write([]byte(`{"metadata":{"name":"pod","uid":"1234"}}`))
+		case http.MethodPost:
+			body, _ := io.ReadAll(r.Body)
+			ev := struct {
+				Reason string `json:"reason"`
+			}{}
+			_ = json.Unmarshal(body, &ev)
+			reasons = append(reasons, ev.Reason)
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer srv.Close()
+
+	k := &kubeReporter{
+		client:     kube.NewClient(srv.URL, "", srv.Client(), "ns", "pod"),
+		log:        logging.New("", "", 0),
+		events:     true,
+		annotation: "git-sync/hash",
+		failures:   2,
+	}
+	serr := &syncError{class: errorClassTransient, reason: "network", err: errors.New("boom")}
+
+	k.published("repo", "main", "abc")
+	for i := 1; i <= 3; i++ {
+		k.syncFailed(i, serr)
+	}
+	k.syncRecovered(3)
+	k.syncRecovered(1) // below the threshold, so not reported
+
+	want := []string{kubeReasonPublished, kubeReasonSyncFailed, kubeReasonSyncRecovered}
+	if !reflect.DeepEqual(want, reasons) {
+		t.Errorf("expected events %q, got %q", want, reasons)
+	}
+	if patches != 1 {
+		t.Errorf("expected 1 annotation update, got %d", patches)
+	}
+
+	// A nil reporter does nothing.
+	var nilRep *kubeReporter
+	nilRep.published("repo", "main", "abc")
+	nilRep.syncFailed(2, serr)
+	nilRep.syncRecovered(2)
+}
@@ -19,6 +19,7 @@ limitations under the License.
 package main // import "k8s.io/git-sync/cmd/git-sync"
 
 import (
+	"bytes"
 	"context"
 	"crypto/md5"
 	"encoding/json"
@@ -48,8 +49,10 @@ import (
 	"golang.org/x/sys/unix"
 	"k8s.io/git-sync/pkg/cmd"
 	"k8s.io/git-sync/pkg/hook"
+	"k8s.io/git-sync/pkg/kube"
 	"k8s.io/git-sync/pkg/logging"
 	"k8s.io/git-sync/pkg/pid1"
+	"k8s.io/git-sync/pkg/vault"
 	"k8s.io/git-sync/pkg/version"
 )
 
@@ -69,6 +72,21 @@ var (
 		Help: "How many git fetches were run",
 	})
 
+	metricFetchProgressPercent = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "git_sync_fetch_progress_percent",
+		Help: "How far the current (or last) git fetch is through each phase (e.g. 'Receiving objects')",
+	}, []string{"phase"})
+
+	metricFetchObjects = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "git_sync_fetch_objects_received",
+		Help: "How many objects the current (or last) git fetch has received",
+	})
+
+	metricFetchBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "git_sync_fetch_bytes_received",
+		Help: "How many bytes the current (or last) git fetch has received",
+	})
+
 	metricAskpassCount = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: "git_sync_askpass_calls",
 		Help: "How many git askpass calls completed, partitioned by state (success, error)",
@@ -78,14 +96,47 @@ var (
 		Name: "git_sync_refresh_github_app_token_count",
 		Help: "How many times the GitHub app token was refreshed, partitioned by state (success, error)",
 	}, []string{"status"})
+
+	metricSyncFailureCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "git_sync_failure_count_total",
+		Help: "How many git syncs failed, partitioned by error class (permanent, transient, unknown) and reason",
+	}, []string{"class", "reason"})
+
+	metricCredentialReloadCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "git_sync_credential_reload_count_total",
+		Help: "How many times changed credential files were reloaded, partitioned by kind (password-file, ssh, github-app-key, tls)",
+	}, []string{"kind"})
+
+	metricTokenProviderCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "git_sync_token_provider_refresh_count_total",
+		Help: "How many times a token was requested from a token provider, partitioned by provider (client-credentials, token-exchange) and state (success, error)",
+	}, []string{"provider", "status"})
+
+	metricCredentialExecCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "git_sync_credential_exec_count_total",
+		Help: "How many times --credential-exec was run, partitioned by state (success, error)",
+	}, []string{"status"})
+
+	metricVaultReadCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "git_sync_vault_read_count_total",
+		Help: "How many times credentials were read from Vault, partitioned by state (success, error)",
+	}, []string{"status"})
 )
 
 func init() {
 	prometheus.MustRegister(metricSyncDuration)
 	prometheus.MustRegister(metricSyncCount)
 	prometheus.MustRegister(metricFetchCount)
+	prometheus.MustRegister(metricFetchProgressPercent)
+	prometheus.MustRegister(metricFetchObjects)
+	prometheus.MustRegister(metricFetchBytes)
 	prometheus.MustRegister(metricAskpassCount)
 	prometheus.MustRegister(metricRefreshGitHubAppTokenCount)
+	prometheus.MustRegister(metricSyncFailureCount)
+	prometheus.MustRegister(metricCredentialReloadCount)
+	prometheus.MustRegister(metricCredentialExecCount)
+	prometheus.MustRegister(metricTokenProviderCount)
+	prometheus.MustRegister(metricVaultReadCount)
 }
 
 const (
@@ -115,24 +166,45 @@ const defaultDirMode = os.FileMode(0775) // subject to umask
 
 // repoSync represents the remote repo and the local sync of it.
 type repoSync struct {
-	cmd            string         // the git command to run
-	root           absPath        // absolute path to the root directory
-	repo           string         // remote repo to sync
-	ref            string         // the ref to sync
-	depth          int            // for shallow sync
-	submodules     submodulesMode // how to handle submodules
-	gc             gcMode         // garbage collection
-	link           absPath        // absolute path to the symlink to publish
-	authURL        string         // a URL to re-fetch credentials, or ""
-	sparseFile     string         // path to a sparse-checkout file
-	syncCount      int            // how many times have we synced?
-	log            *logging.Logger
-	run            cmd.Runner
-	staleTimeout   time.Duration // time for worktrees to be cleaned up
-	appTokenExpiry time.Time     // time when github app auth token expires
+	cmd              string            // the git command to run
+	root             absPath           // absolute path to the root directory
+	repo             string            // remote repo to sync
+	ref              string            // the ref to sync
+	depth            int               // for shallow sync
+	submodules       submodulesMode    // how to handle submodules
+	gc               gcMode            // garbage collection
+	link             absPath           // absolute path to the symlink to publish
+	authURL          string            // a URL to re-fetch credentials, or ""
+	authTimeout      time.Duration     // timeout for authURL requests
+	authHeaders      map[string]string // extra headers for authURL requests
+	authTokenFile    string            // a bearer token file for authURL, or ""
+	authExpiry       time.Time         // when credentials from authURL expire, or zero
+	credExec         string            // a command which prints credentials, or ""
+	credExecTimeout  time.Duration     // timeout for credExec
+	credExecExpiry   time.Time         // when credentials from credExec expire, or zero
+	credExecRuns     int               // how many times credExec has succeeded
+	credExecRejected bool              // the credentials from credExec failed to authenticate
+	sparseFile       string            // path to a sparse-checkout file
+	syncCount        int               // how many times have we synced?
+	log              *logging.Logger
+	run              cmd.Runner
+	staleTimeout     time.Duration     // time for worktrees to be cleaned up
+	appTokenExpiry   map[int]time.Time // per installation ID, when its GitHub app token expires
+	appTokenDir      string            // where GitHub app tokens for URL prefixes are written, or ""
+	providerExpiry   time.Time         // when the token from a tokenProvider expires, or zero
+	vaultExpiry      time.Time         // when credentials from Vault should be re-read, or zero
+	httpClient       *http.Client      // for git-sync's own HTTP requests
+	sshCommand       string            // the ssh command, before options are added
+	sshConfigDir     string            // where a generated ssh_config is written, or ""
+	validator        *hook.Validator   // hooks which can reject a new hash, or nil
+	rejectedHash     string            // the last hash rejected by validator
+	rejectedErr      error             // the error for rejectedHash
 }
 
 func main() {
+	// In case we were started to apply resource limits to a command.
+	cmd.RunLimitsShim()
+
 	// In case we come up as pid 1, act as init.
 	if os.Getpid() == 1 {
 		fmt.Fprintf(os.Stderr, "INFO: detected pid 1, running init handler\n")
@@ -189,6 +261,9 @@ func main() {
 	flSyncTimeout := pflag.Duration("sync-timeout",
 		envDuration(120*time.Second, "GITSYNC_SYNC_TIMEOUT", "GIT_SYNC_SYNC_TIMEOUT"),
 		"the total time allowed for one complete sync, must be >= 10ms; --timeout overrides this")
+	flKillGracePeriod := pflag.Duration("kill-grace-period",
+		envDuration(5*time.Second, "GITSYNC_KILL_GRACE_PERIOD"),
+		"how long to wait after terminating a timed-out command before killing it and its children")
 	flOneTime := pflag.Bool("one-time",
 		envBool(false, "GITSYNC_ONE_TIME", "GIT_SYNC_ONE_TIME"),
 		"exit after the first sync")
@@ -198,6 +273,18 @@ func main() {
 	flMaxFailures := pflag.Int("max-failures",
 		envInt(0, "GITSYNC_MAX_FAILURES", "GIT_SYNC_MAX_FAILURES"),
 		"the number of consecutive failures allowed before aborting (-1 will retry forever")
+	flMaxPermanentFailures := pflag.String("max-permanent-failures",
+		envString("", "GITSYNC_MAX_PERMANENT_FAILURES"),
+		"the number of consecutive permanent failures (e.g. bad credentials) allowed before aborting (defaults to --max-failures)")
+	flMaxTransientFailures := pflag.String("max-transient-failures",
+		envString("", "GITSYNC_MAX_TRANSIENT_FAILURES"),
+		"the number of consecutive transient failures (e.g. network errors) allowed before aborting (defaults to --max-failures)")
+	flPermanentFailureBackoff := pflag.Duration("permanent-failure-backoff",
+		envDuration(0, "GITSYNC_PERMANENT_FAILURE_BACKOFF"),
+		"how long to wait before retrying after a permanent failure (defaults to --period)")
+	flTransientFailureBackoff := pflag.Duration("transient-failure-backoff",
+		envDuration(0, "GITSYNC_TRANSIENT_FAILURE_BACKOFF"),
+		"how long to wait before retrying after a transient failure (defaults to --period)")
 	flTouchFile := pflag.String("touch-file",
 		envString("", "GITSYNC_TOUCH_FILE", "GIT_SYNC_TOUCH_FILE"),
 		"the path (absolute or relative to --root) to an optional file which will be touched whenever a sync completes (defaults to disabled)")
@@ -220,6 +307,9 @@ func main() {
 	flExechookBackoff := pflag.Duration("exechook-backoff",
 		envDuration(3*time.Second, "GITSYNC_EXECHOOK_BACKOFF", "GIT_SYNC_EXECHOOK_BACKOFF"),
 		"the time to wait before retrying a failed exechook")
+	flExechookLimits := pflag.String("exechook-limits",
+		envString("", "GITSYNC_EXECHOOK_LIMITS"),
+		"resource limits for exechooks, e.g. 'cpu=60s,memory=512Mi,nofile=1024,nice=10,ionice=idle'")
 
 	flWebhookURL := pflag.String("webhook-url",
 		envString("", "GITSYNC_WEBHOOK_URL", "GIT_SYNC_WEBHOOK_URL"),
@@ -237,6 +327,9 @@ func main() {
 		envDuration(3*time.Second, "GITSYNC_WEBHOOK_BACKOFF", "GIT_SYNC_WEBHOOK_BACKOFF"),
 		"the time to wait before retrying a failed webhook")
 
+	flHooks := pflagHookConfigSlice("hook", envString("", "GITSYNC_HOOK"),
+		"one or more hooks (see --man for details) to run when syncs complete")
+
 	flHooksAsync := pflag.Bool("hooks-async",
 		envBool(true, "GITSYNC_HOOKS_ASYNC", "GIT_SYNC_HOOKS_ASYNC"),
 		"run hooks asynchronously")
@@ -264,14 +357,44 @@ func main() {
 	flSSHKnownHostsFile := pflag.String("ssh-known-hosts-file",
 		envString("/etc/git-secret/known_hosts", "GITSYNC_SSH_KNOWN_HOSTS_FILE", "GIT_SYNC_SSH_KNOWN_HOSTS_FILE", "GIT_SSH_KNOWN_HOSTS_FILE"),
 		"the known_hosts file to use")
+	flSSHHosts := pflagSSHHostSlice("ssh-host", envString("", "GITSYNC_SSH_HOST"), "SSH settings (see --man for details) for specific hosts")
 
 	flCookieFile := pflag.Bool("cookie-file",
 		envBool(false, "GITSYNC_COOKIE_FILE", "GIT_SYNC_COOKIE_FILE", "GIT_COOKIE_FILE"),
 		"use a git cookiefile (/etc/git-secret/cookie_file) for authentication")
 
+	flCABundle := pflag.String("ca-bundle",
+		envString("", "GITSYNC_CA_BUNDLE"),
+		"a PEM file of CA certificates to verify HTTPS servers with, instead of the system's")
+	flTLSMinVersion := pflag.String("tls-min-version",
+		envString("", "GITSYNC_TLS_MIN_VERSION"),
+		"the minimum TLS version for HTTPS connections: 1.2 or 1.3")
+	flTLSClientCertFile := pflag.String("tls-client-cert-file",
+		envString("", "GITSYNC_TLS_CLIENT_CERT_FILE"),
+		"a client certificate to present to HTTPS servers")
+	flTLSClientKeyFile := pflag.String("tls-client-key-file",
+		envString("", "GITSYNC_TLS_CLIENT_KEY_FILE"),
+		"the key for --tls-client-cert-file")
+
 	flAskPassURL := pflag.String("askpass-url",
 		envString("", "GITSYNC_ASKPASS_URL", "GIT_SYNC_ASKPASS_URL", "GIT_ASKPASS_URL"),
 		"a URL to query for git credentials (username=<value> and password=<value>)")
+	flAskPassURLTimeout := pflag.Duration("askpass-url-timeout",
+		envDuration(1*time.Second, "GITSYNC_ASKPASS_URL_TIMEOUT"),
+		"the timeout for --askpass-url requests")
+	flAskPassURLHeaders := pflag.String("askpass-url-headers",
+		envString("", "GITSYNC_ASKPASS_URL_HEADERS"),
+		"extra headers to send with --askpass-url requests, as a JSON object")
+	flAskPassURLBearerTokenFile := pflag.String("askpass-url-bearer-token-file",
+		envString("", "GITSYNC_ASKPASS_URL_BEARER_TOKEN_FILE"),
+		"a file from which a bearer token for --askpass-url requests is read")
+
+	flCredentialExec := pflag.String("credential-exec",
+		envString("", "GITSYNC_CREDENTIAL_EXEC"),
+		"a command to run to get git credentials, as JSON")
+	flCredentialExecTimeout := pflag.Duration("credential-exec-timeout",
+		envDuration(10*time.Second, "GITSYNC_CREDENTIAL_EXEC_TIMEOUT"),
+		"the timeout for --credential-exec")
 
 	flGithubBaseURL := pflag.String("github-base-url",
 		envString("https://api.github.com/", "GITSYNC_GITHUB_BASE_URL"),
@@ -290,6 +413,81 @@ func main() {
 	flGithubAppInstallationID := pflag.Int("github-app-installation-id",
 		envInt(0, "GITSYNC_GITHUB_APP_INSTALLATION_ID"),
 		"the GitHub app installation ID to use for GitHub app auth")
+	flGithubAppInstallations := pflagGitHubAppInstallationSlice("github-app-installation", envString("", "GITSYNC_GITHUB_APP_INSTALLATION"),
+		"more GitHub app installations (see --man for details), for URLs with specific prefixes")
+
+	flOAuth2TokenURL := pflag.String("oauth2-token-url",
+		envString("", "GITSYNC_OAUTH2_TOKEN_URL"),
+		"the OAuth2 token endpoint to get tokens for git auth from")
+	flOAuth2Grant := pflag.String("oauth2-grant",
+		envString(oauth2GrantClientCredentials, "GITSYNC_OAUTH2_GRANT"),
+		"how to get OAuth2 tokens: client-credentials or token-exchange")
+	flOAuth2ClientID := pflag.String("oauth2-client-id",
+		envString("", "GITSYNC_OAUTH2_CLIENT_ID"),
+		"the OAuth2 client ID")
+	flOAuth2ClientSecret := envFlagString("GITSYNC_OAUTH2_CLIENT_SECRET", "",
+		"the OAuth2 client secret")
+	flOAuth2ClientSecretFile := pflag.String("oauth2-client-secret-file",
+		envString("", "GITSYNC_OAUTH2_CLIENT_SECRET_FILE"),
+		"the file from which the OAuth2 client secret will be sourced")
+	flOAuth2Scopes := pflag.String("oauth2-scopes",
+		envString("", "GITSYNC_OAUTH2_SCOPES"),
+		"the OAuth2 scopes to request, separated by spaces")
+	flOAuth2Audience := pflag.String("oauth2-audience",
+		envString("", "GITSYNC_OAUTH2_AUDIENCE"),
+		"the audience to request OAuth2 tokens for")
+	flOAuth2SubjectTokenFile := pflag.String("oauth2-subject-token-file",
+		envString("", "GITSYNC_OAUTH2_SUBJECT_TOKEN_FILE"),
+		"the file holding the token to exchange, for --oauth2-grant=token-exchange")
+	flOAuth2SubjectTokenType := pflag.String("oauth2-subject-token-type",
+		envString(tokenTypeJWT, "GITSYNC_OAUTH2_SUBJECT_TOKEN_TYPE"),
+		"the type of --oauth2-subject-token-file")
+	flOAuth2Username := pflag.String("oauth2-username",
+		envString("oauth2", "GITSYNC_OAUTH2_USERNAME"),
+		"the username to use with OAuth2 tokens for git auth")
+
+	flVaultAddr := pflag.String("vault-addr",
+		envString("", "GITSYNC_VAULT_ADDR"),
+		"the URL of a Vault server to get git credentials from")
+	flVaultNamespace := pflag.String("vault-namespace",
+		envString("", "GITSYNC_VAULT_NAMESPACE"),
+		"the Vault namespace to use")
+	flVaultAuth := pflag.String("vault-auth",
+		envString(vaultAuthKubernetes, "GITSYNC_VAULT_AUTH"),
+		"how to log in to Vault: kubernetes or approle")
+	flVaultAuthMount := pflag.String("vault-auth-mount",
+		envString("", "GITSYNC_VAULT_AUTH_MOUNT"),
+		"where the Vault auth method is mounted, if not at its default path")
+	flVaultRole := pflag.String("vault-role",
+		envString("", "GITSYNC_VAULT_ROLE"),
+		"the Vault role to log in as, for --vault-auth=kubernetes")
+	flVaultSATokenFile := pflag.String("vault-sa-token-file",
+		envString("/var/run/secrets/kubernetes.io/serviceaccount/token", "GITSYNC_VAULT_SA_TOKEN_FILE"),
+		"the service account token file to log in to Vault with, for --vault-auth=kubernetes")
+	flVaultAppRoleRoleID := pflag.String("vault-approle-role-id",
+		envString("", "GITSYNC_VAULT_APPROLE_ROLE_ID"),
+		"the AppRole role ID, for --vault-auth=approle")
+	flVaultAppRoleSecretIDFile := pflag.String("vault-approle-secret-id-file",
+		envString("", "GITSYNC_VAULT_APPROLE_SECRET_ID_FILE"),
+		"the file from which the AppRole secret ID will be sourced, for --vault-auth=approle")
+	flVaultKVMount := pflag.String("vault-kv-mount",
+		envString("secret", "GITSYNC_VAULT_KV_MOUNT"),
+		"where the Vault KV version 2 secrets engine is mounted")
+	flVaultSecretPath := pflag.String("vault-secret-path",
+		envString("", "GITSYNC_VAULT_SECRET_PATH"),
+		"the path of the Vault secret which holds git credentials")
+	flVaultUsernameField := pflag.String("vault-username-field",
+		envString("username", "GITSYNC_VAULT_USERNAME_FIELD"),
+		"the field of the Vault secret which holds the username")
+	flVaultPasswordField := pflag.String("vault-password-field",
+		envString("password", "GITSYNC_VAULT_PASSWORD_FIELD"),
+		"the field of the Vault secret which holds the password or token")
+	flVaultSSHKeyField := pflag.String("vault-ssh-key-field",
+		envString("ssh-privatekey", "GITSYNC_VAULT_SSH_KEY_FIELD"),
+		"the field of the Vault secret which holds the SSH private key")
+	flVaultSecretRefresh := pflag.Duration("vault-secret-refresh",
+		envDuration(5*time.Minute, "GITSYNC_VAULT_SECRET_REFRESH"),
+		"how often to re-read the Vault secret, if it has no lease")
 
 	flGitCmd := pflag.String("git",
 		envString("git", "GITSYNC_GIT", "GIT_SYNC_GIT"),
@@ -300,6 +498,9 @@ func main() {
 	flGitGC := pflag.String("git-gc",
 		envString("always", "GITSYNC_GIT_GC", "GIT_SYNC_GIT_GC"),
 		"git garbage collection behavior: one of 'auto', 'always', 'aggressive', or 'off'")
+	flGitLimits := pflag.String("git-limits",
+		envString("", "GITSYNC_GIT_LIMITS"),
+		"resource limits for git, e.g. 'cpu=60s,memory=512Mi,nofile=1024,nice=10,ionice=idle'")
 
 	flHTTPBind := pflag.String("http-bind",
 		envString("", "GITSYNC_HTTP_BIND", "GIT_SYNC_HTTP_BIND"),
@@ -310,6 +511,25 @@ func main() {
 	flHTTPprof := pflag.Bool("http-pprof",
 		envBool(false, "GITSYNC_HTTP_PPROF", "GIT_SYNC_HTTP_PPROF"),
 		"enable the pprof debug endpoints on git-sync's HTTP endpoint")
+	flHTTPHookOutput := pflag.Bool("http-hook-output",
+		envBool(false, "GITSYNC_HTTP_HOOK_OUTPUT"),
+		"enable the exechook output endpoint on git-sync's HTTP endpoint")
+
+	flKubeEvents := pflag.Bool("kube-events",
+		envBool(false, "GITSYNC_KUBE_EVENTS"),
+		"record Kubernetes Events on this pod when a new hash is published and when syncing fails")
+	flKubeEventFailures := pflag.Int("kube-event-failures",
+		envInt(3, "GITSYNC_KUBE_EVENT_FAILURES"),
+		"the number of consecutive sync failures before a Kubernetes Event is recorded")
+	flKubeAnnotation := pflag.String("kube-annotation",
+		envString("", "GITSYNC_KUBE_ANNOTATION"),
+		"an annotation on this pod to set to the published hash, e.g. git-sync/hash")
+	flPodName := pflag.String("pod-name",
+		envString("", "GITSYNC_POD_NAME"),
+		"the name of this pod, for --kube-events and --kube-annotation")
+	flPodNamespace := pflag.String("pod-namespace",
+		envString("", "GITSYNC_POD_NAMESPACE"),
+		"the namespace of this pod, for --kube-events and --kube-annotation (defaults to the service account's namespace)")
 
 	// Obsolete flags, kept for compat.
 	flDeprecatedBranch := pflag.String("branch", envString("", "GIT_SYNC_BRANCH"),
@@ -420,6 +640,10 @@ func main() {
 		dir, file := makeAbsPath(*flErrorFile, absRoot).Split()
 		return logging.New(dir.String(), file, *flVerbose)
 	}()
+	// Secrets which are read later (e.g. from files, or tokens) are added as
+	// they are stored.  Runners made from this log redact secrets from their
+	// errors, too.
+	log.AddSecrets(*flPassword, *flGithubAppPrivateKey, *flOAuth2ClientSecret)
 	cmdRunner := cmd.NewRunner(log)
 
 	if *flRepo == "" {
@@ -459,6 +683,11 @@ func main() {
 		fatalConfigErrorf(log, true, "invalid flag: --git-gc must be one of %q, %q, %q, or %q", gcAuto, gcAlways, gcAggressive, gcOff)
 	}
 
+	gitLimits, err := cmd.ParseLimits(*flGitLimits)
+	if err != nil {
+		fatalConfigErrorf(log, true, "invalid flag: --git-limits: %v", err)
+	}
+
 	if *flDeprecatedDest != "" {
 		// Back-compat
 		log.V(0).Info("setting --link from deprecated --dest")
@@ -484,20 +713,11 @@ func main() {
 
 	var syncSig syscall.Signal
 	if *flSyncOnSignal != "" {
-		if num, err := strconv.ParseInt(*flSyncOnSignal, 0, 0); err == nil {
-			// sync-on-signal value is a number
-			syncSig = syscall.Signal(num)
-		} else {
-			// sync-on-signal value is a name
-			syncSig = unix.SignalNum(*flSyncOnSignal)
-			if syncSig == 0 {
-				// last resort - maybe they said "HUP", meaning "SIGHUP"
-				syncSig = unix.SignalNum("SIG" + *flSyncOnSignal)
-			}
-		}
-		if syncSig == 0 {
+		sig, err := hook.ParseSignal(*flSyncOnSignal)
+		if err != nil {
 			fatalConfigErrorf(log, true, "invalid flag: --sync-on-signal must be a valid signal name or number")
 		}
+		syncSig = sig
 	}
 
 	if *flDeprecatedTimeout != 0 {
@@ -508,6 +728,9 @@ func main() {
 	if *flSyncTimeout < 10*time.Millisecond {
 		fatalConfigErrorf(log, true, "invalid flag: --sync-timeout must be at least 10ms")
 	}
+	if *flKillGracePeriod < 0 {
+		fatalConfigErrorf(log, true, "invalid flag: --kill-grace-period must be at least 0")
+	}
 
 	if *flDeprecatedMaxSyncFailures != 0 {
 		// Back-compat
@@ -515,6 +738,26 @@ func main() {
 		*flMaxFailures = *flDeprecatedMaxSyncFailures
 	}
 
+	failurePolicies := map[errorClass]failurePolicy{
+		errorClassUnknown: {maxFailures: *flMaxFailures},
+	}
+	if n, err := parseMaxFailures(*flMaxPermanentFailures, *flMaxFailures); err != nil {
+		fatalConfigErrorf(log, true, "invalid flag: --max-permanent-failures must be an integer: %v", err)
+	} else {
+		failurePolicies[errorClassPermanent] = failurePolicy{maxFailures: n, backoff: *flPermanentFailureBackoff}
+	}
+	if n, err := parseMaxFailures(*flMaxTransientFailures, *flMaxFailures); err != nil {
+		fatalConfigErrorf(log, true, "invalid flag: --max-transient-failures must be an integer: %v", err)
+	} else {
+		failurePolicies[errorClassTransient] = failurePolicy{maxFailures: n, backoff: *flTransientFailureBackoff}
+	}
+	if *flPermanentFailureBackoff < 0 {
+		fatalConfigErrorf(log, true, "invalid flag: --permanent-failure-backoff must be at least 0")
+	}
+	if *flTransientFailureBackoff < 0 {
+		fatalConfigErrorf(log, true, "invalid flag: --transient-failure-backoff must be at least 0")
+	}
+
 	if *flDeprecatedSyncHookCommand != "" {
 		// Back-compat
 		log.V(0).Info("setting --exechook-command from deprecated --sync-hook-command")
@@ -545,6 +788,41 @@ func main() {
 		}
 	}
 
+	// The older single-hook flags are converted into hook configs, so they
+	// can be handled the same as --hook.
+	hookConfigs := []hookConfig{}
+	if *flExechookCommand != "" {
+		hookConfigs = append(hookConfigs, hookConfig{
+			Name:    "exechook",
+			Type:    hookTypeExec,
+			Command: *flExechookCommand,
+			Limits:  *flExechookLimits,
+			Timeout: jsonDuration(*flExechookTimeout),
+			Backoff: jsonDuration(*flExechookBackoff),
+		})
+	}
+	// --exechook-limits is the default for all exec hooks.
+	for i := range *flHooks {
+		if hc := &(*flHooks)[i]; hc.Type == hookTypeExec && hc.Limits == "" {
+			hc.Limits = *flExechookLimits
+		}
+	}
+	if *flWebhookURL != "" {
+		hookConfigs = append(hookConfigs, hookConfig{
+			Name:          "webhook",
+			Type:          hookTypeWeb,
+			URL:           *flWebhookURL,
+			Method:        *flWebhookMethod,
+			SuccessStatus: flWebhookStatusSuccess,
+			Timeout:       jsonDuration(*flWebhookTimeout),
+			Backoff:       jsonDuration(*flWebhookBackoff),
+		})
+	}
+	hookConfigs = append(hookConfigs, *flHooks...)
+	if err := validateHookConfigs(hookConfigs, *flHooksAsync); err != nil {
+		fatalConfigErrorf(log, true, "invalid flag: --hook: %v", err)
+	}
+
 	if *flDeprecatedPassword != "" {
 		log.V(0).Info("setting $GITSYNC_PASSWORD from deprecated --password")
 		*flPassword = *flDeprecatedPassword
@@ -574,8 +852,11 @@ func main() {
 		if *flGithubAppApplicationID != 0 && *flGithubAppClientID != "" {
 			fatalConfigErrorf(log, true, "invalid flag: only one of --github-app-application-id or --github-app-client-id may be specified")
 		}
-		if *flGithubAppInstallationID == 0 {
-			fatalConfigErrorf(log, true, "invalid flag: --github-app-installation-id must be specified when --github-app-application-id or --github-app-client-id are specified")
+		if *flGithubAppInstallationID == 0 && len(*flGithubAppInstallations) == 0 {
+			fatalConfigErrorf(log, true, "invalid flag: --github-app-installation-id or --github-app-installation must be specified when --github-app-application-id or --github-app-client-id are specified")
+		}
+		if err := validateGitHubAppInstallations(*flGithubAppInstallations, *flGithubAppInstallationID); err != nil {
+			fatalConfigErrorf(log, true, "invalid flag: --github-app-installation: %v", err)
 		}
 		if *flGithubAppPrivateKey == "" && *flGithubAppPrivateKeyFile == "" {
 			fatalConfigErrorf(log, true, "invalid flag: $GITSYNC_GITHUB_APP_PRIVATE_KEY or --github-app-private-key-file must be specified when --github-app-application-id or --github-app-client-id are specified")
@@ -599,6 +880,147 @@ func main() {
 		if *flGithubAppInstallationID != 0 {
 			fatalConfigErrorf(log, true, "invalid flag: --github-app-installation-id may only be specified when --github-app-private-key-file is specified")
 		}
+		if len(*flGithubAppInstallations) > 0 {
+			fatalConfigErrorf(log, true, "invalid flag: --github-app-installation may only be specified when --github-app-private-key-file is specified")
+		}
+	}
+
+	if _, found := tlsVersions[*flTLSMinVersion]; !found && *flTLSMinVersion != "" {
+		fatalConfigErrorf(log, true, "invalid flag: --tls-min-version must be 1.2 or 1.3")
+	}
+	if (*flTLSClientCertFile == "") != (*flTLSClientKeyFile == "") {
+		fatalConfigErrorf(log, true, "invalid flag: --tls-client-cert-file and --tls-client-key-file must be specified together")
+	}
+	tlsConf := tlsSettings{
+		caBundle:   *flCABundle,
+		certFile:   *flTLSClientCertFile,
+		keyFile:    *flTLSClientKeyFile,
+		minVersion: *flTLSMinVersion,
+	}
+	// All of git-sync's own HTTP requests share this, so they all use the
+	// same TLS settings as git.
+	httpTransport, err := newReloadableTransport(tlsConf)
+	if err != nil {
+		fatalConfigErrorf(log, true, "invalid flag: can't load TLS settings: %v", err)
+	}
+	httpClient := &http.Client{Transport: httpTransport}
+
+	var tokenProv tokenProvider
+	if *flOAuth2TokenURL != "" {
+		if u, err := url.Parse(*flOAuth2TokenURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+			fatalConfigErrorf(log, true, "invalid flag: --oauth2-token-url must be an http or https URL")
+		}
+		if *flOAuth2ClientSecret != "" && *flOAuth2ClientSecretFile != "" {
+			fatalConfigErrorf(log, true, "invalid flag: only one of $GITSYNC_OAUTH2_CLIENT_SECRET or --oauth2-client-secret-file may be specified")
+		}
+		if *flUsername != "" {
+			fatalConfigErrorf(log, true, "invalid flag: --username may not be specified when --oauth2-token-url is specified")
+		}
+		if *flPassword != "" || *flPasswordFile != "" {
+			fatalConfigErrorf(log, true, "invalid flag: $GITSYNC_PASSWORD and --password-file may not be specified when --oauth2-token-url is specified")
+		}
+		if *flOAuth2Username == "" {
+			fatalConfigErrorf(log, true, "invalid flag: --oauth2-username must be specified")
+		}
+		client := oauth2Client{
+			tokenURL:         *flOAuth2TokenURL,
+			clientID:         *flOAuth2ClientID,
+			clientSecret:     *flOAuth2ClientSecret,
+			clientSecretFile: *flOAuth2ClientSecretFile,
+			scopes:           *flOAuth2Scopes,
+			audience:         *flOAuth2Audience,
+			httpClient:       httpClient,
+			secrets:          log,
+		}
+		switch *flOAuth2Grant {
+		case oauth2GrantClientCredentials:
+			if *flOAuth2ClientID == "" {
+				fatalConfigErrorf(log, true, "required flag: --oauth2-client-id must be specified when --oauth2-grant=%s", oauth2GrantClientCredentials)
+			}
+			if *flOAuth2ClientSecret == "" && *flOAuth2ClientSecretFile == "" {
+				fatalConfigErrorf(log, true, "required flag: $GITSYNC_OAUTH2_CLIENT_SECRET or --oauth2-client-secret-file must be specified when --oauth2-grant=%s", oauth2GrantClientCredentials)
+			}
+			if *flOAuth2SubjectTokenFile != "" {
+				fatalConfigErrorf(log, true, "invalid flag: --oauth2-subject-token-file may only be specified when --oauth2-grant=%s", oauth2GrantTokenExchange)
+			}
+			tokenProv = clientCredentialsProvider{oauth2Client: client}
+		case oauth2GrantTokenExchange:
+			if *flOAuth2SubjectTokenFile == "" {
+				fatalConfigErrorf(log, true, "required flag: --oauth2-subject-token-file must be specified when --oauth2-grant=%s", oauth2GrantTokenExchange)
+			}
+			if *flOAuth2SubjectTokenType == "" {
+				fatalConfigErrorf(log, true, "invalid flag: --oauth2-subject-token-type must be specified")
+			}
+			tokenProv = tokenExchangeProvider{
+				oauth2Client:     client,
+				subjectTokenFile: *flOAuth2SubjectTokenFile,
+				subjectTokenType: *flOAuth2SubjectTokenType,
+			}
+		default:
+			fatalConfigErrorf(log, true, "invalid flag: --oauth2-grant must be one of %q or %q", oauth2GrantClientCredentials, oauth2GrantTokenExchange)
+		}
+	} else if *flOAuth2ClientID != "" || *flOAuth2ClientSecret != "" || *flOAuth2ClientSecretFile != "" || *flOAuth2Scopes != "" || *flOAuth2Audience != "" || *flOAuth2SubjectTokenFile != "" {
+		fatalConfigErrorf(log, true, "invalid flag: --oauth2-* flags may only be specified when --oauth2-token-url is specified")
+	}
+
+	var vaultCreds *vaultCredentials
+	if *flVaultAddr != "" {
+		if u, err := url.Parse(*flVaultAddr); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			fatalConfigErrorf(log, true, "invalid flag: --vault-addr must be an http or https URL")
+		}
+		if *flVaultSecretPath == "" {
+			fatalConfigErrorf(log, true, "required flag: --vault-secret-path must be specified when --vault-addr is specified")
+		}
+		if *flVaultKVMount == "" {
+			fatalConfigErrorf(log, true, "invalid flag: --vault-kv-mount must be specified")
+		}
+		if *flVaultUsernameField == "" || *flVaultPasswordField == "" || *flVaultSSHKeyField == "" {
+			fatalConfigErrorf(log, true, "invalid flag: --vault-username-field, --vault-password-field, and --vault-ssh-key-field must be specified")
+		}
+		if *flVaultSecretRefresh < 0 {
+			fatalConfigErrorf(log, true, "invalid flag: --vault-secret-refresh must be at least 0")
+		}
+		if *flUsername != "" {
+			fatalConfigErrorf(log, true, "invalid flag: --username may not be specified when --vault-addr is specified")
+		}
+		if *flPassword != "" || *flPasswordFile != "" {
+			fatalConfigErrorf(log, true, "invalid flag: $GITSYNC_PASSWORD and --password-file may not be specified when --vault-addr is specified")
+		}
+		var auth vault.Auth
+		switch *flVaultAuth {
+		case vaultAuthKubernetes:
+			if *flVaultRole == "" {
+				fatalConfigErrorf(log, true, "required flag: --vault-role must be specified when --vault-auth=%s", vaultAuthKubernetes)
+			}
+			if *flVaultSATokenFile == "" {
+				fatalConfigErrorf(log, true, "invalid flag: --vault-sa-token-file must be specified")
+			}
+			if *flVaultAppRoleRoleID != "" || *flVaultAppRoleSecretIDFile != "" {
+				fatalConfigErrorf(log, true, "invalid flag: --vault-approle-* flags may only be specified when --vault-auth=%s", vaultAuthAppRole)
+			}
+			auth = vault.KubernetesAuth{Mount: *flVaultAuthMount, Role: *flVaultRole, TokenFile: *flVaultSATokenFile}
+		case vaultAuthAppRole:
+			if *flVaultAppRoleRoleID == "" {
+				fatalConfigErrorf(log, true, "required flag: --vault-approle-role-id must be specified when --vault-auth=%s", vaultAuthAppRole)
+			}
+			if *flVaultRole != "" {
+				fatalConfigErrorf(log, true, "invalid flag: --vault-role may only be specified when --vault-auth=%s", vaultAuthKubernetes)
+			}
+			auth = vault.AppRoleAuth{Mount: *flVaultAuthMount, RoleID: *flVaultAppRoleRoleID, SecretIDFile: *flVaultAppRoleSecretIDFile}
+		default:
+			fatalConfigErrorf(log, true, "invalid flag: --vault-auth must be one of %q or %q", vaultAuthKubernetes, vaultAuthAppRole)
+		}
+		vaultCreds = &vaultCredentials{
+			client:        vault.NewClient(*flVaultAddr, *flVaultNamespace, httpClient, auth),
+			mount:         *flVaultKVMount,
+			path:          *flVaultSecretPath,
+			usernameField: *flVaultUsernameField,
+			passwordField: *flVaultPasswordField,
+			sshKeyField:   *flVaultSSHKeyField,
+			refresh:       *flVaultSecretRefresh,
+		}
+	} else if *flVaultNamespace != "" || *flVaultAuthMount != "" || *flVaultRole != "" || *flVaultAppRoleRoleID != "" || *flVaultAppRoleSecretIDFile != "" || *flVaultSecretPath != "" {
+		fatalConfigErrorf(log, true, "invalid flag: --vault-* flags may only be specified when --vault-addr is specified")
 	}
 
 	if len(*flCredentials) > 0 {
@@ -618,6 +1040,28 @@ func main() {
 		}
 	}
 
+	var askpassHeaders map[string]string
+	if *flAskPassURL != "" {
+		if *flAskPassURLTimeout <= 0 {
+			fatalConfigErrorf(log, true, "invalid flag: --askpass-url-timeout must be greater than zero")
+		}
+		if *flAskPassURLHeaders != "" {
+			if err := json.Unmarshal([]byte(*flAskPassURLHeaders), &askpassHeaders); err != nil {
+				fatalConfigErrorf(log, true, "invalid flag: --askpass-url-headers must be a JSON object of strings: %v", err)
+			}
+		}
+	} else if *flAskPassURLHeaders != "" || *flAskPassURLBearerTokenFile != "" {
+		fatalConfigErrorf(log, true, "invalid flag: --askpass-url-headers and --askpass-url-bearer-token-file may only be specified when --askpass-url is specified")
+	}
+
+	if err := validateSSHHosts(*flSSHHosts); err != nil {
+		fatalConfigErrorf(log, true, "invalid flag: --ssh-host: %v", err)
+	}
+
+	if *flCredentialExec != "" && *flCredentialExecTimeout <= 0 {
+		fatalConfigErrorf(log, true, "invalid flag: --credential-exec-timeout must be greater than zero")
+	}
+
 	if *flHTTPBind == "" {
 		if *flHTTPMetrics {
 			fatalConfigErrorf(log, true, "required flag: --http-bind must be specified when --http-metrics is set")
@@ -625,6 +1069,18 @@ func main() {
 		if *flHTTPprof {
 			fatalConfigErrorf(log, true, "required flag: --http-bind must be specified when --http-pprof is set")
 		}
+		if *flHTTPHookOutput {
+			fatalConfigErrorf(log, true, "required flag: --http-bind must be specified when --http-hook-output is set")
+		}
+	}
+
+	if *flKubeEvents || *flKubeAnnotation != "" {
+		if *flPodName == "" {
+			fatalConfigErrorf(log, true, "required flag: --pod-name must be specified when --kube-events or --kube-annotation is set")
+		}
+		if *flKubeEventFailures < 1 {
+			fatalConfigErrorf(log, true, "invalid flag: --kube-event-failures must be at least 1")
+		}
 	}
 
 	//
@@ -712,19 +1168,26 @@ func main() {
 
 	// Capture the various git parameters.
 	git := &repoSync{
-		cmd:          *flGitCmd,
-		root:         absRoot,
-		repo:         *flRepo,
-		ref:          *flRef,
-		depth:        *flDepth,
-		submodules:   submodulesMode(*flSubmodules),
-		gc:           gcMode(*flGitGC),
-		link:         absLink,
-		authURL:      *flAskPassURL,
-		sparseFile:   *flSparseCheckoutFile,
-		log:          log,
-		run:          cmdRunner,
-		staleTimeout: *flStaleWorktreeTimeout,
+		cmd:             *flGitCmd,
+		root:            absRoot,
+		repo:            *flRepo,
+		ref:             *flRef,
+		depth:           *flDepth,
+		submodules:      submodulesMode(*flSubmodules),
+		gc:              gcMode(*flGitGC),
+		link:            absLink,
+		authURL:         *flAskPassURL,
+		authTimeout:     *flAskPassURLTimeout,
+		authHeaders:     askpassHeaders,
+		authTokenFile:   *flAskPassURLBearerTokenFile,
+		credExec:        *flCredentialExec,
+		credExecTimeout: *flCredentialExecTimeout,
+		sparseFile:      *flSparseCheckoutFile,
+		log:             log,
+		run:             cmdRunner.WithGracePeriod(*flKillGracePeriod).WithLimits(gitLimits),
+		staleTimeout:    *flStaleWorktreeTimeout,
+		appTokenExpiry:  map[int]time.Time{},
+		httpClient:      httpClient,
 	}
 
 	// This context is used only for git credentials initialization. There are
@@ -757,22 +1220,111 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Finish populating credentials.
-	for i := range *flCredentials {
-		cred := &(*flCredentials)[i]
-		if cred.PasswordFile != "" {
+	// Credential files are re-read when they change, so mounted Secrets can
+	// be rotated without restarting.
+	credFiles := newCredentialFiles()
+	sshFiles := append([]string{}, *flSSHKeyFiles...)
+	// A key from Vault is written when it is read, so it is not reloaded.
+	sshKeyFiles := *flSSHKeyFiles
+	if vaultCreds != nil {
+		dir, err := os.MkdirTemp("", "git-sync.vault.*")
+		if err != nil {
+			log.Error(err, "can't create vault key dir")
+			os.Exit(1)
+		}
+		vaultCreds.sshKeyFile = filepath.Join(dir, "ssh-key")
+		sshKeyFiles = append(append([]string{}, sshKeyFiles...), vaultCreds.sshKeyFile)
+	}
+	if *flSSHKnownHosts {
+		sshFiles = append(sshFiles, *flSSHKnownHostsFile)
+	}
+	for _, h := range *flSSHHosts {
+		sshFiles = append(sshFiles, h.files()...)
+	}
+	reloadCredentialFiles := func(initial bool) error {
+		for i := range *flCredentials {
+			cred := &(*flCredentials)[i]
+			if cred.PasswordFile == "" {
+				continue
+			}
+			changed, err := credFiles.changed(cred.PasswordFile)
+			if err != nil {
+				return fmt.Errorf("can't check password file: %w", err)
+			}
+			if !changed {
+				continue
+			}
 			passwordFileBytes, err := os.ReadFile(cred.PasswordFile)
 			if err != nil {
-				log.Error(err, "can't read password file", "file", cred.PasswordFile)
-				os.Exit(1)
+				return fmt.Errorf("can't read password file: %w", err)
 			}
 			cred.Password = string(passwordFileBytes)
+			if !initial {
+				log.V(0).Info("password file changed, reloaded it", "file", cred.PasswordFile)
+				metricCredentialReloadCount.WithLabelValues("password-file").Inc()
+			}
+		}
+
+		// SSH reads these files every time it runs, but the command is
+		// re-applied in case that ever changes.
+		sshChanged := false
+		for _, path := range sshFiles {
+			changed, err := credFiles.changed(path)
+			if err != nil {
+				return fmt.Errorf("can't check SSH file: %w", err)
+			}
+			sshChanged = sshChanged || changed
 		}
+		if sshChanged && !initial {
+			log.V(0).Info("SSH key or known_hosts file changed, reloading", "files", sshFiles)
+			if err := git.SetupGitSSH(*flSSHKnownHosts, sshKeyFiles, *flSSHKnownHostsFile, *flSSHHosts); err != nil {
+				return err
+			}
+			metricCredentialReloadCount.WithLabelValues("ssh").Inc()
+		}
+
+		tlsChanged := false
+		for _, path := range tlsConf.files() {
+			changed, err := credFiles.changed(path)
+			if err != nil {
+				return fmt.Errorf("can't check TLS file: %w", err)
+			}
+			tlsChanged = tlsChanged || changed
+		}
+		if tlsChanged && !initial {
+			// git reads these files every time it runs.
+			log.V(0).Info("CA bundle or client cert file changed, reloading", "files", tlsConf.files())
+			if err := httpTransport.reload(tlsConf); err != nil {
+				return err
+			}
+			metricCredentialReloadCount.WithLabelValues("tls").Inc()
+		}
+
+		if *flGithubAppPrivateKeyFile != "" {
+			changed, err := credFiles.changed(*flGithubAppPrivateKeyFile)
+			if err != nil {
+				return fmt.Errorf("can't check GitHub app private key file: %w", err)
+			}
+			if changed && !initial {
+				// The old key may have been revoked, so don't wait for the
+				// token to expire.
+				log.V(0).Info("GitHub app private key file changed, refreshing token", "file", *flGithubAppPrivateKeyFile)
+				clear(git.appTokenExpiry)
+				metricCredentialReloadCount.WithLabelValues("github-app-key").Inc()
+			}
+		}
+		return nil
+	}
+
+	// Finish populating credentials.
+	if err := reloadCredentialFiles(true); err != nil {
+		log.Error(err, "can't read credential files")
+		os.Exit(1)
 	}
 
 	// If the --repo or any submodule uses SSH, we need to know which keys.
-	if err := git.SetupGitSSH(*flSSHKnownHosts, *flSSHKeyFiles, *flSSHKnownHostsFile); err != nil {
-		log.Error(err, "can't set up git SSH", "keyFiles", *flSSHKeyFiles, "useKnownHosts", *flSSHKnownHosts, "knownHostsFile", *flSSHKnownHostsFile)
+	if err := git.SetupGitSSH(*flSSHKnownHosts, sshKeyFiles, *flSSHKnownHostsFile, *flSSHHosts); err != nil {
+		log.Error(err, "can't set up git SSH", "keyFiles", sshKeyFiles, "useKnownHosts", *flSSHKnownHosts, "knownHostsFile", *flSSHKnownHostsFile)
 		os.Exit(1)
 	}
 
@@ -783,6 +1335,11 @@ func main() {
 		}
 	}
 
+	if err := git.SetupGitTLS(ctx, tlsConf); err != nil {
+		log.Error(err, "can't set up git TLS")
+		os.Exit(1)
+	}
+
 	// This needs to be after all other git-related config flags.
 	if *flGitConfig != "" {
 		if err := git.SetupExtraGitConfigs(ctx, *flGitConfig); err != nil {
@@ -794,6 +1351,31 @@ func main() {
 	// The scope of the initialization context ends here, so we call cancel to release resources associated with it.
 	cancel()
 
+	var kubeRep *kubeReporter
+	if *flKubeEvents || *flKubeAnnotation != "" {
+		client, err := kube.NewInClusterClient(*flPodNamespace, *flPodName)
+		if err != nil {
+			log.Error(err, "can't configure Kubernetes API client")
+			os.Exit(1)
+		}
+		kubeRep = &kubeReporter{
+			client:     client,
+			log:        log,
+			events:     *flKubeEvents,
+			annotation: *flKubeAnnotation,
+			failures:   *flKubeEventFailures,
+		}
+	}
+
+	// Exechook output is recorded from when the hooks start, but may be
+	// served before then.
+	hookOutputs := map[string]*hook.OutputHistory{}
+	for _, hc := range hookConfigs {
+		if hc.Type == hookTypeExec {
+			hookOutputs[hc.Name] = hook.NewOutputHistory(hc.OutputHistory)
+		}
+	}
+
 	if *flHTTPBind != "" {
 		ln, err := net.Listen("tcp", *flHTTPBind)
 		if err != nil {
@@ -827,6 +1409,13 @@ func main() {
 			reasons = append(reasons, "pprof")
 		}
 
+		if *flHTTPHookOutput {
+			mux.HandleFunc("/hooks/output", func(w http.ResponseWriter, r *http.Request) {
+				serveHookOutput(w, r, hookOutputs)
+			})
+			reasons = append(reasons, "hook-output")
+		}
+
 		log.V(0).Info("serving HTTP", "endpoint", *flHTTPBind, "reasons", reasons)
 		go func() {
 			err := http.Serve(ln, mux)
@@ -835,68 +1424,177 @@ func main() {
 		}()
 	}
 
-	// Startup webhooks goroutine
-	var webhookRunner *hook.HookRunner
-	if *flWebhookURL != "" {
-		log := log.WithName("webhook")
-		webhook := hook.NewWebhook(
-			*flWebhookURL,
-			*flWebhookMethod,
-			*flWebhookStatusSuccess,
-			*flWebhookTimeout,
-			log,
-		)
-		webhookRunner = hook.NewHookRunner(
-			webhook,
-			*flWebhookBackoff,
-			hook.NewHookData(),
-			log,
-			*flOneTime,
-			*flHooksAsync,
-		)
-		go webhookRunner.Run(context.Background())
+	// Startup hook goroutines
+	hookRunners := []*hook.HookRunner{}        // triggered on sync
+	hookFailureRunners := []*hook.HookRunner{} // triggered on hook failure
+	hookRunnersByName := map[string]*hook.HookRunner{}
+	validator := hook.NewValidator(log.WithName("validate"))
+	exitNotifier := hook.NewNotifier(log.WithName("exit"))
+	// Hooks which are triggered when syncing fails or recovers, after some
+	// number of consecutive failures.
+	type transitionHook struct {
+		runner   *hook.HookRunner
+		failures int
+	}
+	syncFailureHooks := []transitionHook{}
+	syncRecoveryHooks := []transitionHook{}
+	// sendTransition sends one event to each of the hooks for which match
+	// returns true.  Hooks which depend on each other must see the same
+	// event, so it is built at most once.
+	sendTransition := func(hooks []transitionHook, match func(failures int) bool, event func() hook.Event) {
+		var ev *hook.Event
+		for _, th := range hooks {
+			if !match(th.failures) {
+				continue
+			}
+			if ev == nil {
+				e := event()
+				ev = &e
+			}
+			if err := th.runner.Send(*ev); err != nil {
+				log.Error(err, "state hook failed", "name", th.runner.Name(), "event", ev.Type)
+			}
+		}
 	}
-
-	// Startup exechooks goroutine
-	var exechookRunner *hook.HookRunner
-	if *flExechookCommand != "" {
-		log := log.WithName("exechook")
-		exechook := hook.NewExechook(
-			cmd.NewRunner(log),
-			*flExechookCommand,
-			func(hash string) string {
-				return git.worktreeFor(hash).Path().String()
-			},
-			[]string{},
-			*flExechookTimeout,
-			log,
-		)
-		exechookRunner = hook.NewHookRunner(
-			exechook,
-			*flExechookBackoff,
-			hook.NewHookData(),
+	allRunners := []*hook.HookRunner{}
+	for _, hc := range hookConfigs {
+		// Named loggers are plain logr.Loggers, which redact what they log
+		// but can't redact command errors and output.
+		redactor := log
+		log := log.WithName(hc.Name)
+		var h hook.Hook
+		switch hc.Type {
+		case hookTypeExec:
+			// This was validated along with the rest of the config.
+			limits, _ := cmd.ParseLimits(hc.Limits)
+			exechook := hook.NewExechook(
+				hc.Name,
+				cmd.NewRunner(log).WithRedactor(redactor).WithGracePeriod(*flKillGracePeriod).WithLimits(limits),
+				hc.Command,
+				hc.Args,
+				time.Duration(hc.Timeout),
+				log,
+			)
+			exechook.SetOutputHistory(hookOutputs[hc.Name])
+			if hc.OutputFile {
+				exechook.SetOutputFile(hookOutputFile(git.root, hc.Name).String())
+			}
+			h = exechook
+		case hookTypeWeb:
+			webhook := hook.NewWebhook(
+				hc.Name,
+				hc.URL,
+				hc.Method,
+				*hc.SuccessStatus,
+				time.Duration(hc.Timeout),
+				log,
+			)
+			webhook.SetHeaders(hc.Headers, hc.HeaderFiles)
+			webhook.SetBearerTokenFile(hc.BearerTokenFile)
+			webhook.SetSigningSecretFile(hc.HMACSecretFile)
+			webhook.SetSecretStore(redactor)
+			webhook.SetClient(httpClient)
+			if err := webhook.SetBody(hc.Body, hc.BodyTemplate); err != nil {
+				log.Error(err, "FATAL: can't configure webhook")
+				os.Exit(1)
+			}
+			if err := webhook.SetTLS(httpTransport.TLSConfig, hc.TLSCAFile, hc.TLSCertFile, hc.TLSKeyFile); err != nil {
+				log.Error(err, "FATAL: can't configure webhook TLS")
+				os.Exit(1)
+			}
+			h = webhook
+		case hookTypeSignal:
+			// This was validated along with the rest of the config.
+			sig, _ := hook.ParseSignal(hc.Signal)
+			h = hook.NewSignalhook(hc.Name, sig, hc.processSelector(), time.Duration(hc.Timeout), log)
+		case hookTypeSocket, hookTypeFIFO, hookTypeSpool:
+			h = hook.NewIPChook(hc.Name, hc.Type, hc.Path, time.Duration(hc.Timeout), log)
+		}
+		if hc.On == hookOnValidate {
+			validator.Add(h)
+			continue
+		}
+		if hc.On == hookOnExit {
+			exitNotifier.Add(h)
+			continue
+		}
+		data := hook.NewHookData()
+		if hc.Delivery == hookDeliveryEvery {
+			data = hook.NewHookQueue(hc.QueueSize, hc.QueueOverflow)
+		}
+		runner := hook.NewHookRunner(
+			h,
+			time.Duration(hc.Backoff),
+			data,
 			log,
 			*flOneTime,
-			*flHooksAsync,
+			*hc.Async,
 		)
-		go exechookRunner.Run(context.Background())
+		runner.SetRetryPolicy(time.Duration(hc.MaxBackoff), hc.MaxAttempts, hc.FailureThreshold)
+		switch hc.On {
+		case hookOnSync:
+			// Remember what was delivered, across restarts.
+			if err := runner.SetStateFile(hookStateFile(git.root, hc.Name).String()); err != nil {
+				log.Error(err, "can't load hook state, hook will be re-run")
+			}
+			hookRunners = append(hookRunners, runner)
+		case hookOnHookFailure:
+			hookFailureRunners = append(hookFailureRunners, runner)
+		case hookOnSyncFailure:
+			syncFailureHooks = append(syncFailureHooks, transitionHook{runner, hc.Failures})
+		case hookOnSyncRecovery:
+			syncRecoveryHooks = append(syncRecoveryHooks, transitionHook{runner, hc.Failures})
+		}
+		hookRunnersByName[hc.Name] = runner
+		allRunners = append(allRunners, runner)
+	}
+	if validator.Len() > 0 {
+		git.validator = validator
+	}
+	for _, hc := range hookConfigs {
+		if hc.On == hookOnValidate || hc.On == hookOnExit {
+			continue
+		}
+		deps := []*hook.HookRunner{}
+		for _, name := range hc.After {
+			deps = append(deps, hookRunnersByName[name])
+		}
+		hookRunnersByName[hc.Name].SetDependencies(deps)
+	}
+	for _, runner := range hookRunners {
+		// Keep hook failures in the error-file until the hook succeeds again,
+		// so they are visible even if the sync loop is otherwise healthy.
+		runner.OnCircuitOpen(func(name string, err error) {
+			log.HoldError("hook "+name, err, "hook is unhealthy", "name", name)
+		})
+		runner.OnRecovery(func(name string) {
+			log.ReleaseError("hook " + name)
+		})
+		runner.OnTerminalFailure(func(tf hook.TerminalFailure) {
+			log.HoldError("hook "+tf.Name, tf.Err, "hook gave up", "name", tf.Name, "hash", tf.Event.Hash, "attempts", tf.Attempts)
+			for _, fr := range hookFailureRunners {
+				if err := fr.Send(tf.Event); err != nil {
+					log.Error(err, "hook-failure hook failed", "name", fr.Name(), "failed", tf.Name)
+				}
+			}
+		})
+	}
+	for _, runner := range allRunners {
+		go runner.Run(context.Background())
 	}
 
-	runHooks := func(hash string) error {
-		var err error
-		if exechookRunner != nil {
-			log.V(3).Info("sending exechook")
-			err = exechookRunner.Send(hash)
-			if err != nil {
-				return err
+	// Hooks are triggered in the order they were specified.  A failed hook
+	// does not stop the others from being triggered.
+	runHooks := func(ev hook.Event) error {
+		var errs multiError
+		for _, runner := range hookRunners {
+			log.V(3).Info("sending hook", "name", runner.Name())
+			if err := runner.Send(ev); err != nil {
+				errs = append(errs, fmt.Errorf("hook %q: %w", runner.Name(), err))
 			}
 		}
-		if webhookRunner != nil {
-			log.V(3).Info("sending webhook")
-			err = webhookRunner.Send(hash)
-		}
-		if err != nil {
-			return err
+		if len(errs) > 0 {
+			return errs
 		}
 		return nil
 	}
@@ -908,8 +1606,21 @@ func main() {
 		signal.Notify(sigChan, syncSig)
 	}
 
+	// The installation from --github-app-installation-id is used for --repo.
+	var appInstallations []githubAppInstallation
+	if (*flGithubAppPrivateKeyFile != "" || *flGithubAppPrivateKey != "") && (*flGithubAppApplicationID != 0 || *flGithubAppClientID != "") {
+		if *flGithubAppInstallationID != 0 {
+			appInstallations = append(appInstallations, githubAppInstallation{ID: *flGithubAppInstallationID})
+		}
+		appInstallations = append(appInstallations, *flGithubAppInstallations...)
+	}
+
 	// Craft a function that can be called to refresh credentials when needed.
 	refreshCreds := func(ctx context.Context) error {
+		if err := reloadCredentialFiles(false); err != nil {
+			return err
+		}
+
 		// These should all be mutually-exclusive configs.
 		for _, cred := range *flCredentials {
 			if err := git.StoreCredentials(ctx, cred.URL, cred.Username, cred.Password); err != nil {
@@ -918,17 +1629,52 @@ func main() {
 		}
 		if *flAskPassURL != "" {
 			// When using an auth URL, the credentials can be dynamic, and need
-			// to be re-fetched each time.
-			if err := git.CallAskPassURL(ctx); err != nil {
-				metricAskpassCount.WithLabelValues(metricKeyError).Inc()
-				return err
+			// to be re-fetched each time, unless the URL said when they
+			// expire.
+			if git.authExpiry.Before(time.Now().Add(30 * time.Second)) {
+				if err := git.CallAskPassURL(ctx); err != nil {
+					metricAskpassCount.WithLabelValues(metricKeyError).Inc()
+					return err
+				}
+				metricAskpassCount.WithLabelValues(metricKeySuccess).Inc()
+			}
+		}
+		if *flCredentialExec != "" {
+			if reason := git.credExecReason(time.Now()); reason != "" {
+				if err := git.CallCredentialExec(ctx, reason); err != nil {
+					metricCredentialExecCount.WithLabelValues(metricKeyError).Inc()
+					return err
+				}
+				metricCredentialExecCount.WithLabelValues(metricKeySuccess).Inc()
+			}
+		}
+
+		if tokenProv != nil {
+			// Tokens without an expiry are fetched before every sync.
+			if git.providerExpiry.Before(time.Now().Add(30 * time.Second)) {
+				if err := git.RefreshProviderToken(ctx, tokenProv, *flOAuth2Username); err != nil {
+					metricTokenProviderCount.WithLabelValues(tokenProv.Name(), metricKeyError).Inc()
+					return err
+				}
+				metricTokenProviderCount.WithLabelValues(tokenProv.Name(), metricKeySuccess).Inc()
+			}
+		}
+
+		if vaultCreds != nil {
+			// The Vault token is renewed by the client as needed.
+			if git.vaultExpiry.Before(time.Now().Add(30 * time.Second)) {
+				if err := git.RefreshVaultCredentials(ctx, vaultCreds); err != nil {
+					metricVaultReadCount.WithLabelValues(metricKeyError).Inc()
+					return err
+				}
+				metricVaultReadCount.WithLabelValues(metricKeySuccess).Inc()
 			}
-			metricAskpassCount.WithLabelValues(metricKeySuccess).Inc()
 		}
 
-		if (*flGithubAppPrivateKeyFile != "" || *flGithubAppPrivateKey != "") && *flGithubAppInstallationID != 0 && (*flGithubAppApplicationID != 0 || *flGithubAppClientID != "") {
-			if git.appTokenExpiry.Before(time.Now().Add(30 * time.Second)) {
-				if err := git.RefreshGitHubAppToken(ctx, *flGithubBaseURL, *flGithubAppPrivateKey, *flGithubAppPrivateKeyFile, *flGithubAppClientID, *flGithubAppApplicationID, *flGithubAppInstallationID); err != nil {
+		// Each installation's token expires independently.
+		for _, inst := range appInstallations {
+			if git.appTokenExpiry[inst.ID].Before(time.Now().Add(30 * time.Second)) {
+				if err := git.RefreshGitHubAppToken(ctx, *flGithubBaseURL, *flGithubAppPrivateKey, *flGithubAppPrivateKeyFile, *flGithubAppClientID, *flGithubAppApplicationID, inst); err != nil {
 					metricRefreshGitHubAppTokenCount.WithLabelValues(metricKeyError).Inc()
 					return err
 				}
@@ -940,24 +1686,78 @@ func main() {
 	}
 
 	failCount := 0
+	classFailCounts := map[errorClass]int{}
 	syncCount := uint64(0)
+	var lastRejection error
+	var lastFailure *syncError
 
 	for {
 		start := time.Now()
 		ctx, cancel := context.WithTimeout(context.Background(), *flSyncTimeout)
-
-		if changed, hash, err := git.SyncRepo(ctx, refreshCreds, runHooks, *flHooksBeforeSymlink); err != nil {
+		waitTime := *flPeriod
+
+		changed, ev, err := git.SyncRepo(ctx, refreshCreds, runHooks, *flHooksBeforeSymlink)
+		if serr := classifyError(err); serr != nil && serr.class == errorClassRejected {
+			// The previous hash, if any, is still published, so this is not
+			// counted as a failure.  The same error is returned until the
+			// remote hash changes, but is only reported once.
+			if err != lastRejection {
+				lastRejection = err
+				updateSyncMetrics(metricKeyError, start)
+				metricSyncFailureCount.WithLabelValues(string(serr.class), serr.reason).Inc()
+				log.Error(err, "new hash was rejected, will not retry it")
+			}
+			if *flOneTime {
+				os.Exit(errorClassRejected.exitCode())
+			}
+		} else if serr != nil {
 			failCount++
+			// Only failures of the same class in a row count towards its
+			// limit.
+			for class := range classFailCounts {
+				if class != serr.class {
+					delete(classFailCounts, class)
+				}
+			}
+			classFailCounts[serr.class]++
+			lastFailure = serr
+			if serr.reason == "auth" {
+				// Cached credentials may have been revoked early.
+				git.authExpiry = time.Time{}
+				git.credExecRejected = true
+				clear(git.appTokenExpiry)
+				git.providerExpiry = time.Time{}
+				git.vaultExpiry = time.Time{}
+			}
 			updateSyncMetrics(metricKeyError, start)
-			if *flMaxFailures >= 0 && failCount >= *flMaxFailures {
+			metricSyncFailureCount.WithLabelValues(string(serr.class), serr.reason).Inc()
+			sendTransition(syncFailureHooks, func(failures int) bool { return failCount == failures },
+				func() hook.Event { return git.stateEvent(hook.EventSyncFailure, failCount, serr) })
+			kubeRep.syncFailed(failCount, serr)
+			policy := failurePolicies[serr.class]
+			if policy.maxFailures >= 0 && classFailCounts[serr.class] >= policy.maxFailures {
 				// Exit after too many retries, maybe the error is not recoverable.
-				log.Error(err, "too many failures, aborting", "failCount", failCount)
+				log.Error(err, "too many failures, aborting", "failCount", failCount, "class", serr.class, "reason", serr.reason)
+				if exitNotifier.Len() > 0 {
+					if err := exitNotifier.Notify(context.Background(), git.stateEvent(hook.EventExit, failCount, serr)); err != nil {
+						log.Error(err, "exit hook failed")
+					}
+				}
+				if *flOneTime {
+					os.Exit(serr.class.exitCode())
+				}
 				os.Exit(1)
 			}
-			log.Error(err, "error syncing repo, will retry", "failCount", failCount)
+			log.Error(err, "error syncing repo, will retry", "failCount", failCount, "class", serr.class, "reason", serr.reason)
+			if policy.backoff > 0 {
+				waitTime = policy.backoff
+			}
 		} else {
 			// this might have been called before, but also might not have
 			setRepoReady()
+			sendTransition(syncRecoveryHooks, func(failures int) bool { return failCount >= failures },
+				func() hook.Event { return git.stateEvent(hook.EventSyncRecovery, failCount, lastFailure) })
+			kubeRep.syncRecovered(failCount)
 			// We treat the first loop as a sync, including sending hooks.
 			if changed || syncCount == 0 {
 				if absTouchFile != "" {
@@ -970,8 +1770,9 @@ func main() {
 				// if --hooks-before-symlink is set, these will have already been sent and completed.
 				// otherwise, we send them now.
 				if !*flHooksBeforeSymlink {
-					runHooks(hash)
+					runHooks(ev)
 				}
+				kubeRep.published(redactURL(git.repo), git.ref, ev.Hash)
 				updateSyncMetrics(metricKeySuccess, start)
 			} else {
 				updateSyncMetrics(metricKeyNoOp, start)
@@ -990,17 +1791,14 @@ func main() {
 				// Assumes that if hook channels are not nil, they will have at
 				// least one value before getting closed
 				exitCode := 0 // is 0 if all hooks succeed, else is 1
-				// This will not be needed if async == false, because the Send func for the hookRunners will wait
-				if *flHooksAsync {
-					if exechookRunner != nil {
-						if err := exechookRunner.WaitForCompletion(); err != nil {
-							exitCode = 1
-						}
+				for _, runner := range hookRunners {
+					// This is not needed if async == false, because the Send
+					// func for the hookRunners will wait.
+					if !runner.IsAsync() {
+						continue
 					}
-					if webhookRunner != nil {
-						if err := webhookRunner.WaitForCompletion(); err != nil {
-							exitCode = 1
-						}
+					if err := runner.WaitForCompletion(); err != nil {
+						exitCode = 1
 					}
 				}
 				log.DeleteErrorFile()
@@ -1008,7 +1806,7 @@ func main() {
 				os.Exit(exitCode)
 			}
 
-			if hash == git.ref {
+			if ev.Hash == git.ref {
 				log.V(0).Info("ref appears to be a git hash, no further sync needed", "ref", git.ref)
 				log.DeleteErrorFile()
 				sleepForever()
@@ -1017,16 +1815,17 @@ func main() {
 			if failCount > 0 {
 				log.V(4).Info("resetting failure count", "failCount", failCount)
 				failCount = 0
+				clear(classFailCounts)
 			}
 			log.DeleteErrorFile()
 		}
 
-		log.V(3).Info("next sync", "waitTime", flPeriod.String(), "syncCount", syncCount)
+		log.V(3).Info("next sync", "waitTime", waitTime.String(), "syncCount", syncCount)
 		cancel()
 
 		// Sleep until the next sync. If syncSig is set then the sleep may
 		// be interrupted by that signal.
-		t := time.NewTimer(*flPeriod)
+		t := time.NewTimer(waitTime)
 		select {
 		case <-t.C:
 		case <-sigChan:
@@ -1088,7 +1887,7 @@ func touch(path absPath) error {
 	}
 }
 
-const redactedString = "REDACTED"
+const redactedString = logging.Redacted
 
 func redactURL(urlstr string) string {
 	u, err := url.Parse(urlstr)
@@ -1137,8 +1936,8 @@ func logSafeFlags(v int) []string {
 		}
 		// Handle --credential
 		if arg == "credential" {
-			orig := fl.Value.(*credentialSliceValue) //nolint:forcetypeassert
-			sl := []credential{}                     // make a copy of the slice so we can mutate it
+			orig := fl.Value.(*jsonSliceValue[credential]) //nolint:forcetypeassert
+			sl := []credential{}                           // make a copy of the slice so we can mutate it
 			for _, cred := range orig.value {
 				if cred.Password != "" {
 					cred.Password = redactedString
@@ -1149,6 +1948,21 @@ func logSafeFlags(v int) []string {
 			tmp.value = sl
 			val = tmp.String()
 		}
+		// Handle --askpass-url-headers, which may hold tokens
+		if arg == "askpass-url-headers" && val != "" {
+			val = redactedString
+		}
+		// Handle --hook
+		if arg == "hook" {
+			orig := fl.Value.(*jsonSliceValue[hookConfig]) //nolint:forcetypeassert
+			sl := []hookConfig{}                           // make a copy of the slice so we can mutate it
+			for _, hc := range orig.value {
+				sl = append(sl, hc.redacted())
+			}
+			tmp := *orig // make a copy
+			tmp.value = sl
+			val = tmp.String()
+		}
 
 		ret = append(ret, "--"+arg+"="+val)
 	})
@@ -1747,23 +2561,23 @@ func (git *repoSync) currentWorktree() (worktree, error) {
 
 // SyncRepo syncs the repository to the desired ref, publishes it via the link,
 // and tries to clean up any detritus.  This function returns whether the
-// current hash has changed and what the new hash is.
-func (git *repoSync) SyncRepo(ctx context.Context, refreshCreds func(context.Context) error, runHooks func(hash string) error, flHooksBeforeSymlink bool) (bool, string, error) {
+// current hash has changed and an event describing the sync, for hooks.
+func (git *repoSync) SyncRepo(ctx context.Context, refreshCreds func(context.Context) error, runHooks func(hook.Event) error, flHooksBeforeSymlink bool) (bool, hook.Event, error) {
 	git.log.V(3).Info("syncing", "repo", redactURL(git.repo))
 
 	if err := refreshCreds(ctx); err != nil {
-		return false, "", fmt.Errorf("credential refresh failed: %w", err)
+		return false, hook.Event{}, fmt.Errorf("credential refresh failed: %w", err)
 	}
 
 	// Initialize the repo directory if needed.
 	if err := git.initRepo(ctx); err != nil {
-		return false, "", err
+		return false, hook.Event{}, err
 	}
 
 	// Find out what we currently have synced, if anything.
 	var currentWorktree worktree
 	if wt, err := git.currentWorktree(); err != nil {
-		return false, "", err
+		return false, hook.Event{}, err
 	} else {
 		currentWorktree = wt
 	}
@@ -1773,7 +2587,7 @@ func (git *repoSync) SyncRepo(ctx context.Context, refreshCreds func(context.Con
 	// This should be very fast if we already have the hash we need. Parameters
 	// like depth are set at fetch time.
 	if err := git.fetch(ctx, git.ref); err != nil {
-		return false, "", err
+		return false, hook.Event{}, err
 	}
 
 	// Figure out what we got.  The ^{} syntax "peels" annotated tags to
@@ -1781,7 +2595,7 @@ func (git *repoSync) SyncRepo(ctx context.Context, refreshCreds func(context.Con
 	// branch, plain tag, or hash.
 	var remoteHash string
 	if output, _, err := git.Run(ctx, git.root, "rev-parse", "FETCH_HEAD^{}"); err != nil {
-		return false, "", err
+		return false, hook.Event{}, err
 	} else {
 		remoteHash = strings.Trim(output, "\n")
 	}
@@ -1793,7 +2607,7 @@ func (git *repoSync) SyncRepo(ctx context.Context, refreshCreds func(context.Con
 			// Sanity check failed, nuke it and start over.
 			git.log.V(0).Info("worktree failed checks or was empty", "path", currentWorktree)
 			if err := git.removeWorktree(ctx, currentWorktree); err != nil {
-				return false, "", err
+				return false, hook.Event{}, err
 			}
 			currentHash = ""
 		}
@@ -1803,11 +2617,14 @@ func (git *repoSync) SyncRepo(ctx context.Context, refreshCreds func(context.Con
 	// path was different.
 	changed := (currentHash != remoteHash) || (currentWorktree != git.worktreeFor(currentHash))
 
-	// Fire hooks if needed.
-	if flHooksBeforeSymlink {
-		runHooks(remoteHash)
+	// Don't bother with a hash which was already rejected.
+	if changed && remoteHash == git.rejectedHash {
+		git.log.V(2).Info("hash was previously rejected", "hash", remoteHash)
+		return false, hook.Event{}, git.rejectedErr
 	}
 
+	ev := git.hookEvent(ctx, currentWorktree, currentHash, remoteHash)
+
 	// We have to do at least one fetch, to ensure that parameters like depth
 	// are set properly.  This is cheap when we already have the target hash.
 	if changed || git.syncCount == 0 {
@@ -1818,7 +2635,7 @@ func (git *repoSync) SyncRepo(ctx context.Context, refreshCreds func(context.Con
 		// ref.  This makes subsequent fetches much less expensive.  It uses --soft
 		// so no files are checked out.
 		if _, _, err := git.Run(ctx, git.root, "reset", "--soft", remoteHash, "--"); err != nil {
-			return false, "", err
+			return false, hook.Event{}, err
 		}
 
 		// If we have a new hash, make a new worktree
@@ -1826,7 +2643,7 @@ func (git *repoSync) SyncRepo(ctx context.Context, refreshCreds func(context.Con
 		if changed {
 			// Create a worktree for this hash in git.root.
 			if wt, err := git.createWorktree(ctx, remoteHash); err != nil {
-				return false, "", err
+				return false, hook.Event{}, err
 			} else {
 				newWorktree = wt
 			}
@@ -1836,14 +2653,37 @@ func (git *repoSync) SyncRepo(ctx context.Context, refreshCreds func(context.Con
 		// the correct settings (e.g. sparse checkout).  The best way to get
 		// it all set is just to re-run the configuration,
 		if err := git.configureWorktree(ctx, newWorktree); err != nil {
-			return false, "", err
+			return false, hook.Event{}, err
+		}
+
+		// Give validation hooks a chance to reject the new hash before it
+		// is published.
+		if changed && git.validator != nil {
+			if err := git.validator.Validate(ctx, ev); err != nil {
+				// Only a definite rejection is remembered.  Other failures
+				// (e.g. timeouts) are retried like any other sync error.
+				var rejected *hook.RejectedError
+				if errors.As(err, &rejected) {
+					git.rejectedHash = remoteHash
+					git.rejectedErr = err
+				}
+				if err := git.removeWorktree(ctx, newWorktree); err != nil {
+					git.log.Error(err, "can't remove rejected worktree", "path", newWorktree)
+				}
+				return false, hook.Event{}, err
+			}
+		}
+
+		// Fire hooks if needed.
+		if flHooksBeforeSymlink {
+			runHooks(ev)
 		}
 
 		// If we have a new hash, update the symlink to point to the new worktree.
 		if changed {
 			err := git.publishSymlink(newWorktree)
 			if err != nil {
-				return false, "", err
+				return false, hook.Event{}, err
 			}
 			if currentWorktree != "" {
 				// Start the stale worktree removal timer.
@@ -1874,7 +2714,107 @@ func (git *repoSync) SyncRepo(ctx context.Context, refreshCreds func(context.Con
 		git.log.V(2).Info("update not required", "ref", git.ref, "remote", remoteHash, "syncCount", git.syncCount)
 	}
 
-	return changed, remoteHash, nil
+	return changed, ev, nil
+}
+
+// hookStateFile returns the path of the file which holds the last hash
+// delivered by the named hook.
+func hookStateFile(root absPath, name string) absPath {
+	return root.Join(".git-sync", "hooks", url.PathEscape(name))
+}
+
+// hookOutputFile returns the path of the file which holds the output of the
+// named exechook's latest run.
+func hookOutputFile(root absPath, name string) absPath {
+	return root.Join(".git-sync", "hook-output", url.PathEscape(name))
+}
+
+// serveHookOutput writes the recent runs of exechooks as JSON, keyed by hook
+// name.  If the "name" query parameter is set, only that hook is included.
+func serveHookOutput(w http.ResponseWriter, r *http.Request, outputs map[string]*hook.OutputHistory) {
+	result := map[string][]hook.ExecRun{}
+	if name := r.URL.Query().Get("name"); name != "" {
+		history, found := outputs[name]
+		if !found {
+			http.Error(w, fmt.Sprintf("no such exechook: %q", name), http.StatusNotFound)
+			return
+		}
+		result[name] = history.Runs()
+	} else {
+		for name, history := range outputs {
+			result[name] = history.Runs()
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// hookEvent returns the event which is passed to hooks when syncing from
+// oldHash (in oldWorktree) to newHash.
+func (git *repoSync) hookEvent(ctx context.Context, oldWorktree worktree, oldHash, newHash string) hook.Event {
+	ev := hook.Event{
+		Hash:      newHash,
+		Ref:       git.ref,
+		Repo:      redactURL(git.repo),
+		Link:      git.link.String(),
+		Worktree:  git.worktreeFor(newHash).Path().String(),
+		SyncCount: git.syncCount + 1,
+	}
+	if oldHash == "" || oldHash == newHash {
+		return ev
+	}
+	ev.PreviousHash = oldHash
+	ev.PreviousWorktree = oldWorktree.Path().String()
+	files, err := git.changedFiles(ctx, oldHash, newHash)
+	if err != nil {
+		// This is not fatal, the hooks just get less information.
+		git.log.Error(err, "can't list changed files", "from", oldHash, "to", newHash)
+	}
+	ev.ChangedFiles = files
+	return ev
+}
+
+// stateEvent returns the event which is passed to hooks when git-sync's state
+// changes, e.g. when syncing fails.  It describes the currently published
+// hash, if any, and the last error.
+func (git *repoSync) stateEvent(typ string, failures int, serr *syncError) hook.Event {
+	ev := hook.Event{
+		Ref:       git.ref,
+		Repo:      redactURL(git.repo),
+		Link:      git.link.String(),
+		SyncCount: git.syncCount,
+		Type:      typ,
+		Time:      time.Now().UTC().Format(time.RFC3339Nano),
+		Failures:  failures,
+	}
+	if wt, err := git.currentWorktree(); err != nil {
+		git.log.Error(err, "can't find current worktree")
+	} else if wt != "" {
+		ev.Hash = wt.Hash()
+		ev.Worktree = wt.Path().String()
+	}
+	if serr != nil {
+		ev.ErrorClass = string(serr.class)
+		ev.Error = git.log.Redact(serr.Error())
+	}
+	return ev
+}
+
+// changedFiles returns the files which differ between two commits.
+func (git *repoSync) changedFiles(ctx context.Context, from, to string) ([]string, error) {
+	stdout, _, err := git.Run(ctx, git.root, "diff", "--name-only", "--no-renames", "-z", from, to)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, f := range strings.Split(stdout, "\x00") {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files, nil
 }
 
 // fetch retrieves the specified ref from the upstream repo.
@@ -1883,7 +2823,7 @@ func (git *repoSync) fetch(ctx context.Context, ref string) error {
 
 	// Fetch the ref and do some cleanup, setting or un-setting the repo's
 	// shallow flag as appropriate.
-	args := []string{"fetch", git.repo, ref, "--verbose", "--no-progress", "--prune", "--no-auto-gc"}
+	args := []string{"fetch", git.repo, ref, "--verbose", "--progress", "--prune", "--no-auto-gc"}
 	if git.depth > 0 {
 		args = append(args, "--depth", strconv.Itoa(git.depth))
 	} else {
@@ -1897,13 +2837,53 @@ func (git *repoSync) fetch(ctx context.Context, ref string) error {
 			args = append(args, "--unshallow")
 		}
 	}
-	if _, _, err := git.Run(ctx, git.root, args...); err != nil {
+	progress := newFetchProgress(git.log)
+	if _, _, err := git.run.RunWithProgress(ctx, git.root.String(), nil, progress.update, git.cmd, args...); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// fetchProgressInterval is how often the progress of a long fetch is logged.
+const fetchProgressInterval = 10 * time.Second
+
+// fetchProgress reports the progress of a git fetch, in metrics and
+// periodically in the logs, so a long fetch (e.g. the initial clone of a
+// large repo) does not look hung.
+type fetchProgress struct {
+	log     *logging.Logger
+	start   time.Time
+	lastLog time.Time
+}
+
+func newFetchProgress(log *logging.Logger) *fetchProgress {
+	metricFetchProgressPercent.Reset()
+	metricFetchObjects.Set(0)
+	metricFetchBytes.Set(0)
+	now := time.Now()
+	return &fetchProgress{log: log, start: now, lastLog: now}
+}
+
+func (fp *fetchProgress) update(p cmd.Progress) {
+	if p.Percent >= 0 {
+		metricFetchProgressPercent.WithLabelValues(p.Phase).Set(float64(p.Percent))
+	}
+	if p.Phase == "Receiving objects" {
+		metricFetchObjects.Set(float64(p.Count))
+		if p.Bytes > 0 {
+			metricFetchBytes.Set(float64(p.Bytes))
+		}
+	}
+
+	now := time.Now()
+	if now.Sub(fp.lastLog) < fetchProgressInterval {
+		return
+	}
+	fp.lastLog = now
+	fp.log.V(0).Info("fetch in progress", "phase", p.Phase, "percent", p.Percent, "count", p.Count, "total", p.Total, "bytes", p.Bytes, "elapsed", now.Sub(fp.start).Round(time.Second))
+}
+
 func (git *repoSync) isShallow(ctx context.Context) (bool, error) {
 	boolStr, _, err := git.Run(ctx, git.root, "rev-parse", "--is-shallow-repository")
 	if err != nil {
@@ -1930,6 +2910,9 @@ func md5sum(s string) string {
 
 // StoreCredentials stores a username and password for later use.
 func (git *repoSync) StoreCredentials(ctx context.Context, url, username, password string) error {
+	// Tokens may change on every call, so only the latest for each URL is
+	// kept.
+	git.log.SetSecrets("credential "+url, password)
 	git.log.V(1).Info("storing git credential", "url", redactURL(url))
 	git.log.V(9).Info("md5 of credential", "url", url, "username", md5sum(username), "password", md5sum(password))
 
@@ -1942,14 +2925,18 @@ func (git *repoSync) StoreCredentials(ctx context.Context, url, username, passwo
 	return nil
 }
 
-func (git *repoSync) SetupGitSSH(setupKnownHosts bool, pathsToSSHSecrets []string, pathToSSHKnownHosts string) error {
+func (git *repoSync) SetupGitSSH(setupKnownHosts bool, pathsToSSHSecrets []string, pathToSSHKnownHosts string, sshHosts []sshHost) error {
 	git.log.V(1).Info("setting up git SSH credentials")
 
-	// If the user sets GIT_SSH_COMMAND we try to respect it.
-	sshCmd := os.Getenv("GIT_SSH_COMMAND")
-	if sshCmd == "" {
-		sshCmd = "ssh"
+	// If the user sets GIT_SSH_COMMAND we try to respect it.  This may be
+	// called again if the files change, so remember the original.
+	if git.sshCommand == "" {
+		git.sshCommand = os.Getenv("GIT_SSH_COMMAND")
+		if git.sshCommand == "" {
+			git.sshCommand = "ssh"
+		}
 	}
+	sshCmd := git.sshCommand
 
 	// We can't pre-verify that key-files exist because we call this path
 	// without knowing whether we actually need SSH or not, in which case the
@@ -1963,14 +2950,24 @@ func (git *repoSync) SetupGitSSH(setupKnownHosts bool, pathsToSSHSecrets []strin
 		sshCmd += " -v"
 	}
 
-	for _, p := range pathsToSSHSecrets {
-		sshCmd += fmt.Sprintf(" -i %s", p)
-	}
-
-	if setupKnownHosts {
-		sshCmd += fmt.Sprintf(" -o StrictHostKeyChecking=yes -o UserKnownHostsFile=%s", pathToSSHKnownHosts)
+	if len(sshHosts) > 0 {
+		// Per-host settings need an ssh_config file, which also holds the
+		// settings for all other hosts.
+		path, err := git.writeSSHConfig(setupKnownHosts, pathsToSSHSecrets, pathToSSHKnownHosts, sshHosts)
+		if err != nil {
+			return err
+		}
+		sshCmd += fmt.Sprintf(" -F %s", path)
 	} else {
-		sshCmd += " -o StrictHostKeyChecking=no"
+		for _, p := range pathsToSSHSecrets {
+			sshCmd += fmt.Sprintf(" -i %s", p)
+		}
+
+		if setupKnownHosts {
+			sshCmd += fmt.Sprintf(" -o StrictHostKeyChecking=yes -o UserKnownHostsFile=%s", pathToSSHKnownHosts)
+		} else {
+			sshCmd += " -o StrictHostKeyChecking=no"
+		}
 	}
 
 	git.log.V(9).Info("setting $GIT_SSH_COMMAND", "value", sshCmd)
@@ -1981,6 +2978,32 @@ func (git *repoSync) SetupGitSSH(setupKnownHosts bool, pathsToSSHSecrets []strin
 	return nil
 }
 
+// writeSSHConfig renders an ssh_config file and the files it refers to into
+// a private directory, and returns the path to the ssh_config.  If it is
+// called again, the files are replaced.
+func (git *repoSync) writeSSHConfig(setupKnownHosts bool, pathsToSSHSecrets []string, pathToSSHKnownHosts string, sshHosts []sshHost) (string, error) {
+	if git.sshConfigDir == "" {
+		dir, err := os.MkdirTemp("", "git-sync.ssh.*")
+		if err != nil {
+			return "", fmt.Errorf("can't create SSH config dir: %w", err)
+		}
+		git.sshConfigDir = dir
+	}
+	files, err := renderSSHConfig(git.sshConfigDir, sshHosts, pathsToSSHSecrets, setupKnownHosts, pathToSSHKnownHosts)
+	if err != nil {
+		return "", err
+	}
+	for name, content := range files {
+		path := filepath.Join(git.sshConfigDir, name)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			return "", fmt.Errorf("can't write SSH config: %w", err)
+		}
+	}
+	path := filepath.Join(git.sshConfigDir, sshConfigFile)
+	git.log.V(2).Info("wrote ssh_config", "path", path)
+	return path, nil
+}
+
 func (git *repoSync) SetupCookieFile(ctx context.Context) error {
 	git.log.V(1).Info("configuring git cookie file")
 
@@ -2001,8 +3024,9 @@ func (git *repoSync) SetupCookieFile(ctx context.Context) error {
 // CallAskPassURL consults the specified URL looking for git credentials in the
 // response.
 //
-// The expected URL callback output is below,
-// see https://git-scm.com/docs/gitcredentials for more examples:
+// The expected URL callback output is below (see parseAskpassResponse for
+// the JSON form), see https://git-scm.com/docs/gitcredentials for more
+// examples:
 //
 //	username=xxx@example.com
 //	password=xxxyyyzzz
@@ -2010,7 +3034,8 @@ func (git *repoSync) CallAskPassURL(ctx context.Context) error {
 	git.log.V(3).Info("calling auth URL to get credentials")
 
 	var netClient = &http.Client{
-		Timeout: time.Second * 1,
+		Transport: git.httpClient.Transport,
+		Timeout:   git.authTimeout,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		},
@@ -2019,6 +3044,17 @@ func (git *repoSync) CallAskPassURL(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("can't create auth request: %w", err)
 	}
+	for k, v := range git.authHeaders {
+		httpReq.Header.Set(k, v)
+	}
+	if git.authTokenFile != "" {
+		token, err := os.ReadFile(git.authTokenFile)
+		if err != nil {
+			return fmt.Errorf("can't read auth URL bearer token: %w", err)
+		}
+		git.log.SetSecrets(git.authTokenFile, string(token))
+		httpReq.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
 	resp, err := netClient.Do(httpReq)
 	if err != nil {
 		return fmt.Errorf("can't access auth URL: %w", err)
@@ -2038,39 +3074,42 @@ func (git *repoSync) CallAskPassURL(ctx context.Context) error {
 		return fmt.Errorf("can't read auth response: %w", err)
 	}
 
-	username := ""
-	password := ""
-	for _, line := range strings.Split(string(authData), "\n") {
-		keyValues := strings.SplitN(line, "=", 2)
-		if len(keyValues) != 2 {
-			continue
+	authResp, err := parseAskpassResponse(resp.Header.Get("Content-Type"), authData)
+	if err != nil {
+		return err
+	}
+
+	// A response with only per-URL credentials need not include the main
+	// one.
+	if authResp.Username != "" || authResp.Password != "" || len(authResp.Credentials) == 0 {
+		if err := git.StoreCredentials(ctx, git.repo, authResp.Username, authResp.Password); err != nil {
+			return err
 		}
-		switch keyValues[0] {
-		case "username":
-			username = keyValues[1]
-		case "password":
-			password = keyValues[1]
+	}
+	for _, cred := range authResp.Credentials {
+		if err := git.StoreCredentials(ctx, cred.URL, cred.Username, cred.Password); err != nil {
+			return err
 		}
 	}
-
-	if err := git.StoreCredentials(ctx, git.repo, username, password); err != nil {
-		return err
+	git.authExpiry = cacheExpiry(time.Now(), authResp.ExpiresAt)
+	if !authResp.ExpiresAt.IsZero() {
+		git.log.V(3).Info("auth URL credentials will be cached", "expiresAt", authResp.ExpiresAt, "refreshAt", git.authExpiry)
 	}
 
 	return nil
 }
 
 // RefreshGitHubAppToken generates a new installation token for a GitHub app
-// and stores it as a credential.
-func (git *repoSync) RefreshGitHubAppToken(ctx context.Context, githubBaseURL, privateKey, privateKeyFile, clientID string, appID, installationID int) error {
-	git.log.V(3).Info("refreshing GitHub app token")
+// and stores it as a credential.  The token can only read contents, and only
+// of the installation's repositories, if any are specified.
+func (git *repoSync) RefreshGitHubAppToken(ctx context.Context, githubBaseURL, privateKey, privateKeyFile, clientID string, appID int, inst githubAppInstallation) error {
+	git.log.V(3).Info("refreshing GitHub app token", "installation", inst.ID, "urlPrefix", inst.URLPrefix)
 
 	privateKeyBytes := []byte(privateKey)
 	if privateKey == "" {
 		b, err := os.ReadFile(privateKeyFile)
 		if err != nil {
-			git.log.Error(err, "can't read private key file", "file", privateKeyFile)
-			os.Exit(1)
+			return fmt.Errorf("can't read private key file: %w", err)
 		}
 
 		privateKeyBytes = b
@@ -2100,20 +3139,34 @@ func (git *repoSync) RefreshGitHubAppToken(ctx context.Context, githubBaseURL, p
 		return err
 	}
 
-	url, err := url.JoinPath(githubBaseURL, fmt.Sprintf("app/installations/%d/access_tokens", installationID))
+	url, err := url.JoinPath(githubBaseURL, fmt.Sprintf("app/installations/%d/access_tokens", inst.ID))
+	if err != nil {
+		return err
+	}
+
+	// git-sync never pushes, so the token does not need more than this.
+	tokenRequest := struct {
+		Repositories []string          `json:"repositories,omitempty"`
+		Permissions  map[string]string `json:"permissions"`
+	}{
+		Repositories: inst.Repositories,
+		Permissions:  map[string]string{"contents": "read"},
+	}
+	body, err := json.Marshal(tokenRequest)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
 		return err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+jwt)
 	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := git.httpClient.Do(req)
 	if err != nil {
 		return err
 	}
@@ -2136,19 +3189,102 @@ func (git *repoSync) RefreshGitHubAppToken(ctx context.Context, githubBaseURL, p
 		return err
 	}
 
-	git.appTokenExpiry = tokenResponse.ExpiresAt
-
 	// username must be non-empty
 	username := "-"
 	password := tokenResponse.Token
 
-	if err := git.StoreCredentials(ctx, git.repo, username, password); err != nil {
+	if inst.URLPrefix == "" {
+		if err := git.StoreCredentials(ctx, git.repo, username, password); err != nil {
+			return err
+		}
+	} else {
+		if err := git.storeCredentialsForPrefix(ctx, inst, username, password); err != nil {
+			return err
+		}
+	}
+	git.appTokenExpiry[inst.ID] = tokenResponse.ExpiresAt
+
+	return nil
+}
+
+// storeCredentialsForPrefix stores a username and password for URLs which
+// start with the installation's URL prefix.  git's credential cache matches
+// only by host, so these are written to a file, and a credential helper
+// which reads that file replaces the cache for the prefix.
+func (git *repoSync) storeCredentialsForPrefix(ctx context.Context, inst githubAppInstallation, username, password string) error {
+	git.log.SetSecrets("credential "+inst.URLPrefix, password)
+	git.log.V(1).Info("storing git credential", "url", redactURL(inst.URLPrefix))
+
+	if git.appTokenDir == "" {
+		dir, err := os.MkdirTemp("", "git-sync.tokens.*")
+		if err != nil {
+			return fmt.Errorf("can't create token dir: %w", err)
+		}
+		git.appTokenDir = dir
+	}
+	path := filepath.Join(git.appTokenDir, fmt.Sprintf("installation-%d", inst.ID))
+	_, err := os.Stat(path)
+	configured := err == nil
+
+	creds := fmt.Sprintf("username=%s\npassword=%s\n", username, password)
+	if err := os.WriteFile(path, []byte(creds), 0600); err != nil {
+		return fmt.Errorf("can't write git credentials: %w", err)
+	}
+	if configured {
+		return nil
+	}
+
+	// An empty helper clears the list, so the cache (which would mix these
+	// up with other credentials for the same host) is not used.
+	key := fmt.Sprintf("credential.%s.helper", inst.URLPrefix)
+	helper := fmt.Sprintf(`!f() { if test "$1" = get; then cat '%s'; fi; }; f`, path)
+	for _, val := range []string{"", helper} {
+		if _, _, err := git.Run(ctx, "", "config", "--global", "--add", key, val); err != nil {
+			return fmt.Errorf("can't configure git credential helper: %w", err)
+		}
+	}
+	return nil
+}
+
+// RefreshProviderToken gets a new token from a tokenProvider and stores it
+// as a credential.
+func (git *repoSync) RefreshProviderToken(ctx context.Context, provider tokenProvider, username string) error {
+	git.log.V(3).Info("refreshing token", "provider", provider.Name())
+
+	token, expiry, err := provider.Token(ctx)
+	if err != nil {
+		return fmt.Errorf("can't get %s token: %w", provider.Name(), err)
+	}
+	if err := git.StoreCredentials(ctx, git.repo, username, token); err != nil {
 		return err
 	}
+	git.providerExpiry = cacheExpiry(time.Now(), expiry)
+	if !expiry.IsZero() {
+		git.log.V(3).Info("token will be cached", "provider", provider.Name(), "expiresAt", expiry, "refreshAt", git.providerExpiry)
+	}
 
 	return nil
 }
 
+// credentialCacheTimeout is how long git's credential cache (see
+// SetupDefaultGitConfigs) keeps credentials.
+const credentialCacheTimeout = time.Hour
+
+// cacheExpiry returns when credentials which were stored in git's credential
+// cache at now, and which expire at expiry, must be stored again.  That is
+// shortly before the cache forgets them, if they last longer than that.  A
+// zero expiry, which means the credentials are stored before every sync, is
+// returned as-is.
+func cacheExpiry(now, expiry time.Time) time.Time {
+	if expiry.IsZero() {
+		return expiry
+	}
+	if limit := now.Add(credentialCacheTimeout - 30*time.Second); expiry.After(limit) {
+		return limit
+	}
+	return expiry
+}
+
 // SetupDefaultGitConfigs configures the global git environment with some
 // default settings that we need.
 func (git *repoSync) SetupDefaultGitConfigs(ctx context.Context) error {
@@ -2163,7 +3299,7 @@ func (git *repoSync) SetupDefaultGitConfigs(ctx context.Context) error {
 	}, {
 		// How to manage credentials (for those modes that need it).
 		key: "credential.helper",
-		val: "cache --timeout 3600",
+		val: fmt.Sprintf("cache --timeout %d", int(credentialCacheTimeout.Seconds())),
 	}, {
 		// Never prompt for a password.
 		key: "core.askPass",
@@ -2448,8 +3584,49 @@ OPTIONS
 
     --askpass-url <string>, $GITSYNC_ASKPASS_URL
             A URL to query for git credentials.  The query must return success
-            (200) and produce a series of key=value lines, including
-            "username=<value>" and "password=<value>".
+            (200) and produce either a series of key=value lines, including
+            "username=<value>" and "password=<value>", or a JSON object (if
+            the Content-Type is application/json, or the body starts with
+            '{') like:
+
+              {
+                "username": "<value>",
+                "password": "<value>",
+                "expires_at": "<RFC 3339 time, optional>",
+                "credentials": [
+                  {"url": "<value>", "username": "<value>", "password": "<value>"}
+                ]
+              }
+
+            The username and password are used for --repo, and the optional
+            credentials list gives credentials for other URLs (e.g.
+            submodules).  If expires_at (which may also be given as an
+            "expires_at=<value>" line) is set, the credentials are cached, and
+            the URL is not queried again until shortly before they expire (or
+            after at most an hour, when git's credential cache forgets them),
+            or until a sync fails because of authentication.  Otherwise the URL
+            is queried before every sync.
+
+    --askpass-url-bearer-token-file <string>, $GITSYNC_ASKPASS_URL_BEARER_TOKEN_FILE
+            A file from which a bearer token is read before every
+            --askpass-url request, and sent as 'Authorization: Bearer
+            <token>'.
+
+    --askpass-url-headers <string>, $GITSYNC_ASKPASS_URL_HEADERS
+            Extra headers to send with --askpass-url requests, as a JSON
+            object, e.g. '{"X-Env": "prod"}'.
+
+    --askpass-url-timeout <duration>, $GITSYNC_ASKPASS_URL_TIMEOUT
+            The timeout for --askpass-url requests.  If not specified, this
+            defaults to 1 second ("1s").
+
+    --ca-bundle <string>, $GITSYNC_CA_BUNDLE
+            A file of PEM-encoded CA certificates which are used, instead of
+            the system's, to verify HTTPS servers.  This applies to git (as
+            http.sslCAInfo) and to all of git-sync's own requests, such as
+            --askpass-url, GitHub app and OAuth2 tokens, Vault, and webhooks
+            (unless a webhook has its own tls-ca-file).  It is re-read when it
+            changes.
 
     --cookie-file <string>, $GITSYNC_COOKIE_FILE
             Use a git cookiefile (/etc/git-secret/cookie_file) for
@@ -2476,6 +3653,38 @@ OPTIONS
             Example:
               --credential='{"url":"https://github.com", "username":"myname", "password-file":"/creds/mypass"}'
 
+    --credential-exec <string>, $GITSYNC_CREDENTIAL_EXEC
+            A command to run to get git credentials, similar to a kubectl exec
+            credential plugin.  This flag takes no arguments, so a wrapper
+            script may be needed.  The command is run before syncing, with
+            $GITSYNC_REPO set to --repo, and $GITSYNC_CREDENTIAL_REASON set to
+            why it is being run: "initial" (the first time), "expiring" (the
+            cached credentials are about to expire), "rejected" (the last sync
+            failed to authenticate), or "sync" (the credentials did not say
+            when they expire).  It must print a JSON object like:
+
+              {
+                "url": "<value, optional, defaults to --repo>",
+                "username": "<value, optional>",
+                "password": "<value>",
+                "token": "<value, instead of password>",
+                "expires_at": "<RFC 3339 time, optional>",
+                "credentials": [
+                  {"url": "<value>", "username": "<value>", "token": "<value>"}
+                ]
+              }
+
+            The optional credentials list gives credentials for other URLs
+            (e.g. submodules).  The output of a kubectl exec credential plugin
+            ({"status": {"token": ..., "expirationTimestamp": ...}}) is also
+            accepted.  If the credentials expire, they are cached, and the
+            command is not run again until shortly before they expire (or
+            after at most an hour, when git's credential cache forgets them).
+
+    --credential-exec-timeout <duration>, $GITSYNC_CREDENTIAL_EXEC_TIMEOUT
+            The timeout for --credential-exec.  If not specified, this defaults
+            to 10 seconds ("10s").
+
     --depth <int>, $GITSYNC_DEPTH
             Create a shallow clone with history truncated to the specified
             number of commits.  If not specified, this defaults to syncing a
@@ -2496,12 +3705,19 @@ OPTIONS
             remote repository.  This command does not take any arguments and
             executes with the synced repo as its working directory.  The
             $GITSYNC_HASH environment variable will be set to the git hash that
-            was synced.  If, at startup, git-sync finds that the --root already
-            has the correct hash, this hook will still be invoked.  This means
-            that hooks can be invoked more than one time per hash, so they
-            must be idempotent.  This flag obsoletes --sync-hook-command, but
+            was synced, and other variables describe the sync (see EXECHOOKS
+            below).  If, at startup, git-sync finds that the --root already
+            has the correct hash, this hook will still be invoked unless it
+            already completed for that hash (see HOOKS below).  Hooks can be
+            invoked more than one time per hash, so they must be
+            idempotent.  This flag obsoletes --sync-hook-command, but
             if sync-hook-command is specified, it will take precedence.
 
+    --exechook-limits <string>, $GITSYNC_EXECHOOK_LIMITS
+            Resource limits and scheduling priorities for --exechook-command
+            and for exec hooks in --hook which do not specify "limits" (see
+            LIMITS below).  If not specified, no limits are applied.
+
     --exechook-timeout <duration>, $GITSYNC_EXECHOOK_TIMEOUT
             The timeout for the --exechook-command.  If not specifid, this
             defaults to 30 seconds ("30s").
@@ -2541,6 +3757,11 @@ OPTIONS
             - off: Disable explicit git garbage collection, which may be a good
               fit when also using --one-time.
 
+    --git-limits <string>, $GITSYNC_GIT_LIMITS
+            Resource limits and scheduling priorities for git commands,
+            including anything git runs, such as ssh (see LIMITS below).  If
+            not specified, no limits are applied.
+
     --github-base-url <string>, $GITSYNC_GITHUB_BASE_URL
             The GitHub base URL to use in GitHub requests when GitHub app
             authentication is used. If not specified, defaults to
@@ -2554,6 +3775,26 @@ OPTIONS
             The installation ID of the GitHub app used for GitHub app
             authentication.
 
+    --github-app-installation <string>, $GITSYNC_GITHUB_APP_INSTALLATION
+            More installations of the GitHub app, whose tokens are used for
+            URLs with specific prefixes, for example when submodules are in
+            other organizations.  The value for this flag is either a
+            JSON-encoded object (see the schema below) or a JSON-encoded list
+            of that same object type.  This flag may be specified more than
+            once.
+
+            Object schema:
+              - installation-id:  int, required
+              - url-prefix:       string, required
+              - repositories:     list of string, optional
+
+            If repositories are specified, the token can only access those
+            repositories (names, without the owner).  Each installation may
+            only be specified once, including --github-app-installation-id.
+
+            Example:
+              --github-app-installation='{"installation-id":1234, "url-prefix":"https://github.com/other-org/", "repositories":["submodule"]}'
+
     --github-app-application-id <int>, $GITSYNC_GITHUB_APP_APPLICATION_ID
             The app ID of the GitHub app used for GitHub app authentication.
             One of --github-app-application-id or --github-app-client-id is required
@@ -2574,12 +3815,116 @@ OPTIONS
     -?, -h, --help
             Print help text and exit.
 
-	--hooks-async, $GITSYNC_HOOKS_ASYNC
-			Whether to run the --exechook-command asynchronously.
+    --hook <string>, $GITSYNC_HOOK
+            Configure one or more hooks (see HOOKS below) to be run when syncs
+            complete.  This is a more flexible form of --exechook-command and
+            --webhook-url, which allows any number of hooks, each with its own
+            settings.  The value for this flag is either a JSON-encoded object
+            (see the schema below) or a JSON-encoded list of that same object
+            type.  This flag may be specified more than once.
+
+            Object schema:
+              - name:            string, required, must be unique
+              - type:            string, required, "exec", "webhook",
+                                 "signal", "socket", "fifo", or "spool"
+              - command:         string, required for exec hooks
+              - args:            list of strings, optional, for exec hooks
+              - url:             string, required for webhook hooks
+              - method:          string, optional, for webhook hooks
+              - success-status:  int, optional, for webhook hooks
+              - timeout:         duration string, optional
+              - backoff:         duration string, optional
+              - async:           bool, optional
+              - after:           list of strings, optional
+              - on:              string, optional, "sync", "hook-failure",
+                                 "validate", "sync-failure",
+                                 "sync-recovery", or "exit"
+              - failures:        int, optional
+              - max-backoff:     duration string, optional
+              - max-attempts:    int, optional
+              - failure-threshold:  int, optional
+              - delivery:            string, optional, "latest" or "every"
+              - queue-size:          int, optional
+              - queue-overflow:      string, optional, "drop-oldest",
+                                     "drop-newest", or "block"
+              - output-history:      int, optional, for exec hooks
+              - output-file:         bool, optional, for exec hooks
+              - limits:              string, optional, for exec hooks
+              - headers:             map of string to string, optional
+              - header-files:        map of string to string, optional
+              - bearer-token-file:   string, optional
+              - body:                string, optional
+              - body-template:       string, optional
+              - body-template-file:  string, optional
+              - hmac-secret-file:    string, optional
+              - tls-ca-file:         string, optional
+              - tls-cert-file:       string, optional
+              - tls-key-file:        string, optional
+              - signal:              string, optional, for signal hooks
+              - pid-file:            string, optional, for signal hooks
+              - process-name:        string, optional, for signal hooks
+              - process-regex:       string, optional, for signal hooks
+              - path:                string, required for socket, fifo, and
+                                     spool hooks
+
+            The name is used in logs and in metrics.  The timeout, backoff,
+            method, and success-status fields have the same meaning and
+            defaults as the equivalent --exechook-* and --webhook-* flags.  If
+            async is not specified, it defaults to --hooks-async.
+
+            Hooks are triggered in the order they are specified, after any
+            --exechook-command (named "exechook") and --webhook-url (named
+            "webhook").  The after field lists the names of other hooks which
+            must complete successfully for a given hash before this hook will
+            run for that hash.  Dependency cycles are not allowed, and hooks
+            may only depend on hooks with the same on value.
+
+            The on field specifies when the hook is triggered: "sync" (the
+            default) when a new hash is synced, or "hook-failure" when any
+            "sync" hook gives up on a hash (see HOOKS below), in which case
+            the hook receives the hash that was given up on, or "validate"
+            before a new hash is published (see VALIDATION below), or
+            "sync-failure", "sync-recovery", or "exit" when git-sync's own
+            state changes (see STATE HOOKS below).  The failures field is only
+            valid for "sync-failure" and "sync-recovery" hooks.  Hooks which
+            depend on each other must have the same failures value.
+
+            The max-backoff, max-attempts, and failure-threshold fields
+            control how failed hooks are retried (see HOOKS below).  By
+            default, hooks are retried forever with a fixed backoff.
+
+            The delivery, queue-size, and queue-overflow fields control
+            whether a busy hook sees every hash or only the latest one (see
+            HOOKS below).
+
+            The output-history and output-file fields control how the output
+            of exechooks is kept (see EXECHOOKS below).  The limits field has
+            the same meaning as --exechook-limits, which is its default.
+
+            The headers, header-files, bearer-token-file, body, hmac, and tls
+            fields are only valid for webhook hooks (see WEBHOOKS below).  The
+            signal, pid-file, process-name, and process-regex fields are only
+            valid for signal hooks (see SIGNAL HOOKS below).  The path field
+            is only valid for socket, fifo, and spool hooks (see IPC HOOKS
+            below).
 
-	--hooks-before-symlink, $GITSYNC_HOOKS_BEFORE_SYMLINK
-			Whether to run the --exechook-command before updating the symlink. Use in combination with --hooks-async set
-			to false if you need the hook to finish before the symlink is updated. 
+            Example:
+              --hook='{"name":"reload", "type":"exec", "command":"/bin/reload"}'
+              --hook='{"name":"notify", "type":"webhook", "url":"http://notify", "after":["reload"]}'
+
+    --hooks-async, $GITSYNC_HOOKS_ASYNC
+            Whether to run hooks asynchronously.  If false, git-sync will wait
+            for each hook to complete before triggering the next one.  This
+            can be overridden per-hook in --hook.  If not specified, this
+            defaults to true.
+
+    --hooks-before-symlink, $GITSYNC_HOOKS_BEFORE_SYMLINK
+            Whether to run hooks before updating the symlink.  Use in
+            combination with --hooks-async=false if you need hooks to finish
+            before the symlink is updated.  If not specified, this defaults to
+            false.  Note that a failed hook does not prevent the symlink from
+            being updated; use validation hooks (see VALIDATION below) for
+            that.
 
     --http-bind <string>, $GITSYNC_HTTP_BIND
             The bind address (including port) for git-sync's HTTP endpoint.
@@ -2592,6 +3937,11 @@ OPTIONS
               ":1234": listen on any IP, port 1234
               "127.0.0.1:1234": listen on localhost, port 1234
 
+    --http-hook-output, $GITSYNC_HTTP_HOOK_OUTPUT
+            Enable the exechook output endpoint on git-sync's HTTP endpoint at
+            /hooks/output (see EXECHOOKS below).  Requires --http-bind to be
+            specified.
+
     --http-metrics, $GITSYNC_HTTP_METRICS
             Enable metrics on git-sync's HTTP endpoint at /metrics.  Requires
             --http-bind to be specified.
@@ -2600,6 +3950,32 @@ OPTIONS
             Enable the pprof debug endpoints on git-sync's HTTP endpoint at
             /debug/pprof.  Requires --http-bind to be specified.
 
+    --kill-grace-period <duration>, $GITSYNC_KILL_GRACE_PERIOD
+            How long to wait for a git command or exechook to stop after it
+            has timed out (e.g. after --sync-timeout), before killing it.
+            Each command is run in its own process group; when it times out,
+            the whole group is sent SIGTERM, and anything still running after
+            this period (or after the command exits) is sent SIGKILL.  If
+            set to 0, commands are killed immediately.  If not specified,
+            this defaults to 5 seconds ("5s").
+
+    --kube-annotation <string>, $GITSYNC_KUBE_ANNOTATION
+            An annotation (e.g. "git-sync/hash") on this pod which is set to
+            the published hash whenever a hash is published, so tools like
+            kubectl can show which hash each replica is serving.  This
+            requires --pod-name (see KUBERNETES below).
+
+    --kube-event-failures <int>, $GITSYNC_KUBE_EVENT_FAILURES
+            The number of consecutive sync failures after which --kube-events
+            records a "SyncFailed" event.  If not specified, this defaults to
+            3.
+
+    --kube-events, $GITSYNC_KUBE_EVENTS
+            Record Kubernetes Events on this pod when a new hash is published,
+            when syncing has failed --kube-event-failures times in a row, and
+            when syncing recovers after that.  This requires --pod-name (see
+            KUBERNETES below).
+
     --link <string>, $GITSYNC_LINK
             The path to at which to create a symlink which points to the
             current git directory, at the currently synced hash.  This may be
@@ -2616,7 +3992,61 @@ OPTIONS
             The number of consecutive failures allowed before aborting.
             Setting this to a negative value will retry forever.  If not
             specified, this defaults to 0, meaning any sync failure will
-            terminate git-sync.
+            terminate git-sync.  This can be overridden for failures which
+            can be classified (see FAILURES below) by
+            --max-permanent-failures and --max-transient-failures.
+
+    --max-permanent-failures <int>, $GITSYNC_MAX_PERMANENT_FAILURES
+            The number of consecutive permanent failures (e.g. bad
+            credentials, or a ref or repo which does not exist) allowed before
+            aborting.  Setting this to a negative value will retry forever.
+            If not specified, this defaults to the value of --max-failures.
+
+    --max-transient-failures <int>, $GITSYNC_MAX_TRANSIENT_FAILURES
+            The number of consecutive transient failures (e.g. DNS errors,
+            timeouts, or HTTP 5xx responses) allowed before aborting.  Setting
+            this to a negative value will retry forever.  If not specified,
+            this defaults to the value of --max-failures.
+
+    --oauth2-audience <string>, $GITSYNC_OAUTH2_AUDIENCE
+            The audience to request OAuth2 tokens for (the "audience"
+            parameter), if the token endpoint needs one.
+
+    --oauth2-client-id <string>, $GITSYNC_OAUTH2_CLIENT_ID
+            The OAuth2 client ID.  This is required for the
+            client-credentials grant.
+
+    $GITSYNC_OAUTH2_CLIENT_SECRET
+            The OAuth2 client secret.  See also --oauth2-client-secret-file.
+
+    --oauth2-client-secret-file <string>, $GITSYNC_OAUTH2_CLIENT_SECRET_FILE
+            The file from which the OAuth2 client secret will be read, before
+            every token request.  See also $GITSYNC_OAUTH2_CLIENT_SECRET.
+
+    --oauth2-grant <string>, $GITSYNC_OAUTH2_GRANT
+            How to get OAuth2 tokens from --oauth2-token-url: either
+            "client-credentials" or "token-exchange".  If not specified, this
+            defaults to "client-credentials".
+
+    --oauth2-scopes <string>, $GITSYNC_OAUTH2_SCOPES
+            The OAuth2 scopes to request, separated by spaces.
+
+    --oauth2-subject-token-file <string>, $GITSYNC_OAUTH2_SUBJECT_TOKEN_FILE
+            The file from which the token to exchange will be read, before
+            every token request, when --oauth2-grant is "token-exchange" (e.g.
+            a projected Kubernetes service account token).
+
+    --oauth2-subject-token-type <string>, $GITSYNC_OAUTH2_SUBJECT_TOKEN_TYPE
+            The type of --oauth2-subject-token-file.  If not specified, this
+            defaults to "urn:ietf:params:oauth:token-type:jwt".
+
+    --oauth2-token-url <string>, $GITSYNC_OAUTH2_TOKEN_URL
+            The OAuth2 token endpoint from which to get tokens for git
+            authentication.  See the AUTHENTICATION section.
+
+    --oauth2-username <string>, $GITSYNC_OAUTH2_USERNAME
+            The username to use with OAuth2 tokens.  If not specified, this
+            defaults to "oauth2", which GitLab and Gitea expect.
 
     --one-time, $GITSYNC_ONE_TIME
             Exit after one sync.
@@ -2636,6 +4066,20 @@ OPTIONS
             will take precedence.  If not specified, this defaults to 10
             seconds ("10s").
 
+    --permanent-failure-backoff <duration>, $GITSYNC_PERMANENT_FAILURE_BACKOFF
+            How long to wait before retrying after a permanent failure (see
+            FAILURES below).  If not specified, this defaults to --period.
+
+    --pod-name <string>, $GITSYNC_POD_NAME
+            The name of the pod in which git-sync is running, for
+            --kube-events and --kube-annotation.  This is usually set from the
+            downward API (see KUBERNETES below).
+
+    --pod-namespace <string>, $GITSYNC_POD_NAMESPACE
+            The namespace of the pod in which git-sync is running.  If not
+            specified, this defaults to the namespace of the pod's service
+            account.
+
     --ref <string>, $GITSYNC_REF
             The git revision (branch, tag, or hash) to check out.  If not
             specified, this defaults to "HEAD" (of the upstream repo's default
@@ -2656,6 +4100,52 @@ OPTIONS
             details) which controls which files and directories will be checked
             out.  If not specified, the default is to check out the entire repo.
 
+    --ssh-host <string>, $GITSYNC_SSH_HOST
+            SSH settings for specific hosts, for example when submodules live
+            on different hosts.  The value for this flag is either a
+            JSON-encoded object (see the schema below) or a JSON-encoded list
+            of that same object type.  This flag may be specified more than
+            once.  If specified, git-sync generates an ssh_config file (see
+            "man ssh_config"), in which the settings from --ssh-key-file,
+            --ssh-known-hosts, and --ssh-known-hosts-file apply to all other
+            hosts.
+
+            Object schema:
+              - host:                   string, required
+              - hostname:               string, optional
+              - port:                   int, optional
+              - user:                   string, optional
+              - key-files:              list of string, optional
+              - cert-file:              string, optional
+              - known-hosts-file:       string, optional
+              - host-key-fingerprints:  list of string, optional
+              - cert-authority-file:    string, optional
+              - proxy-jump:             string, optional
+              - proxy-command:          string, optional
+
+            The host field is one or more ssh_config Host patterns, separated
+            by spaces, which are matched against the host in the git URL.
+
+            If key-files is specified, only those keys are used for this host,
+            otherwise the --ssh-key-file keys are used.  The cert-file field is
+            an SSH certificate (e.g. "id_ed25519-cert.pub") for one of them.
+
+            The host's key is verified with any of known-hosts-file, a
+            known_hosts file; host-key-fingerprints, a list of pinned key
+            fingerprints as printed by "ssh-keygen -l" (e.g.
+            "SHA256:nThbg6kXUpJWGl7E1IGOCspRomTxdCARLviKw6E5SY8"); and
+            cert-authority-file, a CA public key which signs the host's
+            certificate (like "@cert-authority" in known_hosts).  If none of
+            these are specified, --ssh-known-hosts and --ssh-known-hosts-file
+            are used.
+
+            Only one of proxy-jump (a bastion host, e.g. "user@bastion:22")
+            and proxy-command (e.g. "nc -X connect -x proxy:3128 %h %p") may
+            be specified.
+
+            Example:
+              --ssh-host='{"host":"gitlab.corp", "key-files":["/keys/corp"], "cert-authority-file":"/keys/ca.pub", "proxy-jump":"bastion.corp"}'
+
     --ssh-key-file <string>, $GITSYNC_SSH_KEY_FILE
             The SSH key(s) to use when using git over SSH.  This flag may be
             specified more than once and the environment variable will be
@@ -2694,16 +4184,102 @@ OPTIONS
             it will take precedence.  If not specified, this defaults to 120
             seconds ("120s").
 
+            The initial clone of a large repo can take a long time.  While
+            git is fetching, its progress is logged every 10 seconds, and is
+            reported in the git_sync_fetch_progress_percent (by phase),
+            git_sync_fetch_objects_received, and
+            git_sync_fetch_bytes_received metrics.
+
+    --tls-client-cert-file <string>, $GITSYNC_TLS_CLIENT_CERT_FILE
+            A PEM-encoded client certificate to present to HTTPS servers which
+            ask for one, for git (as http.sslCert) and for git-sync's own
+            requests.  This requires --tls-client-key-file.  It is re-read
+            when it changes.
+
+    --tls-client-key-file <string>, $GITSYNC_TLS_CLIENT_KEY_FILE
+            The PEM-encoded key for --tls-client-cert-file.
+
+    --tls-min-version <string>, $GITSYNC_TLS_MIN_VERSION
+            The minimum TLS version for HTTPS connections, from git (as
+            http.sslVersion) and from git-sync itself: either "1.2" or "1.3".
+            If not specified, git's own default is used, and git-sync's own
+            connections use "1.2".
+
     --touch-file <string>, $GITSYNC_TOUCH_FILE
             The path to an optional file which will be touched whenever a sync
             completes.  This may be an absolute path or a relative path, in
             which case it is relative to --root.
 
+    --transient-failure-backoff <duration>, $GITSYNC_TRANSIENT_FAILURE_BACKOFF
+            How long to wait before retrying after a transient failure (see
+            FAILURES below).  If not specified, this defaults to --period.
+
     --username <string>, $GITSYNC_USERNAME
             The username to use for git authentication (see --password-file or
             $GITSYNC_PASSWORD).  If more than one username and password is
             required (e.g. with submodules), use --credential.
 
+    --vault-addr <string>, $GITSYNC_VAULT_ADDR
+            The URL of a HashiCorp Vault server (e.g. "https://vault:8200")
+            from which git credentials will be read.  See also
+            --vault-secret-path and --vault-auth, and "Vault" in the
+            AUTHENTICATION section below.
+
+    --vault-approle-role-id <string>, $GITSYNC_VAULT_APPROLE_ROLE_ID
+            The AppRole role ID to log in to Vault with, when --vault-auth is
+            "approle".
+
+    --vault-approle-secret-id-file <string>, $GITSYNC_VAULT_APPROLE_SECRET_ID_FILE
+            The file from which the AppRole secret ID will be read, when
+            --vault-auth is "approle".  This is read for every login.  If not
+            specified, no secret ID is sent.
+
+    --vault-auth <string>, $GITSYNC_VAULT_AUTH
+            How to log in to Vault: either "kubernetes" (the default), with a
+            service account token, or "approle".
+
+    --vault-auth-mount <string>, $GITSYNC_VAULT_AUTH_MOUNT
+            The path at which the Vault auth method is mounted.  If not
+            specified, this defaults to the name of --vault-auth.
+
+    --vault-kv-mount <string>, $GITSYNC_VAULT_KV_MOUNT
+            The path at which the Vault KV version 2 secrets engine is
+            mounted.  If not specified, this defaults to "secret".
+
+    --vault-namespace <string>, $GITSYNC_VAULT_NAMESPACE
+            The Vault Enterprise namespace to use.
+
+    --vault-password-field <string>, $GITSYNC_VAULT_PASSWORD_FIELD
+            The field of the Vault secret which holds the git password or
+            token.  If not specified, this defaults to "password".
+
+    --vault-role <string>, $GITSYNC_VAULT_ROLE
+            The Vault role to log in as, when --vault-auth is "kubernetes".
+
+    --vault-sa-token-file <string>, $GITSYNC_VAULT_SA_TOKEN_FILE
+            The service account token to log in to Vault with, when
+            --vault-auth is "kubernetes".  This is read for every login, so
+            projected tokens can be used.  If not specified, this defaults to
+            "/var/run/secrets/kubernetes.io/serviceaccount/token".
+
+    --vault-secret-path <string>, $GITSYNC_VAULT_SECRET_PATH
+            The path of the Vault secret, under --vault-kv-mount, which holds
+            git credentials.
+
+    --vault-secret-refresh <duration>, $GITSYNC_VAULT_SECRET_REFRESH
+            How often to re-read the Vault secret, if it does not have a lease
+            (which KV version 2 secrets usually don't).  If this is 0, it is
+            read before every sync.  If not specified, this defaults to 5
+            minutes.
+
+    --vault-ssh-key-field <string>, $GITSYNC_VAULT_SSH_KEY_FIELD
+            The field of the Vault secret which holds an SSH private key.  If
+            not specified, this defaults to "ssh-privatekey".
+
+    --vault-username-field <string>, $GITSYNC_VAULT_USERNAME_FIELD
+            The field of the Vault secret which holds the git username.  If
+            not specified, this defaults to "username".
+
     -v, --verbose <int>, $GITSYNC_VERBOSE
             Set the log verbosity level.  Logs at this level and lower will be
             printed.  Logs follow these guidelines:
@@ -2738,11 +4314,13 @@ OPTIONS
 
     --webhook-url <string>, $GITSYNC_WEBHOOK_URL
             A URL for optional webhook notifications when syncs complete.  The
-            header 'Gitsync-Hash' will be set to the git hash that was synced.
-            If, at startup, git-sync finds that the --root already has the
-            correct hash, this hook will still be invoked.  This means that
-            hooks can be invoked more than one time per hash, so they must be
-            idempotent.
+            header 'Gitsync-Hash' will be set to the git hash that was synced,
+            and the body will be a JSON object (see WEBHOOKS below).  If, at
+            startup, git-sync finds that the --root already has the correct
+            hash, this hook will still be invoked unless it already completed
+            for that hash (see HOOKS below).  Hooks can be invoked more than
+            one time per hash, so they must be idempotent.  For more control
+            over the request, use --hook.
 
 EXAMPLE USAGE
 
@@ -2768,7 +4346,10 @@ AUTHENTICATION
 
             A variant of this is --askpass-url ($GITSYNC_ASKPASS_URL), which
             consults a URL (e.g. http://metadata) to get credentials on each
-            sync.
+            sync, or less often if the response says when the credentials
+            expire.  For credentials which come from something other than a
+            URL, --credential-exec ($GITSYNC_CREDENTIAL_EXEC) runs a command
+            which prints them.
 
             When using submodules it may be necessary to specify more than one
             username and password, which can be done with --credential
@@ -2781,7 +4362,9 @@ AUTHENTICATION
             --ssh-key-file ($GITSYNC_SSH_KEY_FILE) will be used.  Users are
             strongly advised to also use --ssh-known-hosts
             ($GITSYNC_SSH_KNOWN_HOSTS) and --ssh-known-hosts-file
-            ($GITSYNC_SSH_KNOWN_HOSTS_FILE) when using SSH.
+            ($GITSYNC_SSH_KNOWN_HOSTS_FILE) when using SSH.  Keys, host key
+            verification, and proxies can be configured for specific hosts
+            with --ssh-host ($GITSYNC_SSH_HOST).
 
     cookies
             When --cookie-file ($GITSYNC_COOKIE_FILE) is specified, the
@@ -2802,14 +4385,102 @@ AUTHENTICATION
 
            The GitHub app must have sufficient access to the repository to sync.
            It should be installed to the repository or organization containing
-           the repository, and given read access (see github docs).
+           the repository, and given read access (see github docs).  Tokens
+           are requested with only the "contents: read" permission.
+
+           Repositories (e.g. submodules) which belong to other installations
+           of the app can be given their own tokens with
+           --github-app-installation ($GITSYNC_GITHUB_APP_INSTALLATION).  Each
+           installation's token is refreshed when it expires.
+
+    OAuth2
+            When --oauth2-token-url ($GITSYNC_OAUTH2_TOKEN_URL) is specified,
+            tokens are requested from that OAuth2 token endpoint and used as
+            the git password, with --oauth2-username ($GITSYNC_OAUTH2_USERNAME)
+            as the username.  With --oauth2-grant=client-credentials, the
+            client authenticates with --oauth2-client-id and its secret.  With
+            --oauth2-grant=token-exchange (RFC 8693), the token in
+            --oauth2-subject-token-file, such as a projected Kubernetes service
+            account token, is exchanged for an access token.  Tokens are
+            requested again shortly before they expire or after at most an
+            hour, when git's credential cache forgets them (or before every
+            sync, if the endpoint does not say when they expire), or after a
+            sync fails to authenticate.  Requests are counted in the
+            git_sync_token_provider_refresh_count_total metric.
+
+    Vault
+            When --vault-addr ($GITSYNC_VAULT_ADDR) is specified, git-sync
+            logs in to HashiCorp Vault, with a Kubernetes service account
+            token (--vault-auth=kubernetes and --vault-role) or an AppRole
+            (--vault-auth=approle and --vault-approle-role-id), and reads the
+            KV version 2 secret at --vault-secret-path.  A username and
+            password in the secret are used for --repo, and an SSH private
+            key in the secret is used along with --ssh-key-file.  The secret
+            is read again when its lease expires (or every
+            --vault-secret-refresh, if it has no lease, but at least every hour
+            if it holds a password, before git's credential cache forgets it),
+            or after a sync fails to authenticate.  Vault tokens are renewed once half of their TTL
+            has passed, and git-sync logs in again when they can not be
+            renewed.  Reads are counted in the git_sync_vault_read_count_total
+            metric.
+
+    Files holding credentials (--password-file, the password-file fields of
+    --credential, --ssh-key-file, --ssh-known-hosts-file, the files in
+    --ssh-host, --github-app-private-key-file, --ca-bundle, and
+    --tls-client-cert-file and its key) are checked before every sync, and
+    are re-read if they have changed, so mounted Secrets can be rotated
+    without restarting git-sync.  A changed GitHub app private key
+    causes a new token to be requested immediately.  Each reload is logged and
+    counted in the git_sync_credential_reload_count_total metric.
+
+    Passwords and tokens which git-sync is given or obtains (from flags,
+    files, --askpass-url, --credential-exec, GitHub apps, OAuth2, Vault, or
+    webhook token, header and HMAC secret files), and passwords in URLs,
+    are redacted from logs, the --error-file, hook payloads and output, and
+    Kubernetes events.  When a file is rotated or a token is replaced, only
+    the latest value is redacted.  The output of --credential-exec is never
+    logged.  Secrets shorter than 4 characters are not redacted.
+
+FAILURES
+
+    When a sync fails, git-sync examines the error (mostly git's output) and
+    classifies it as one of:
+
+    permanent
+            Errors which are not expected to be fixed by retrying, such as
+            bad credentials, a --ref which does not exist, or a --repo which
+            can not be found.  These are controlled by
+            --max-permanent-failures and --permanent-failure-backoff.
+
+    transient
+            Errors which are expected to go away by themselves, such as DNS
+            failures, timeouts, dropped connections, or HTTP 5xx responses.
+            These are controlled by --max-transient-failures and
+            --transient-failure-backoff.
+
+    unknown
+            Errors which could not be classified.  These are controlled by
+            --max-failures and --period.
+
+    Each class counts its own consecutive failures, which are reset by a
+    failure of a different class or by a successful sync.  For example, "--max-failures=-1
+    --max-permanent-failures=0" will retry network problems forever, but will
+    exit immediately on a typo'd --ref.
+
+    When git-sync aborts in --one-time mode, the exit code indicates the class
+    of the last failure: 1 for unknown, 3 for permanent, and 4 for transient.
+    If a new hash is rejected by a validation hook (see VALIDATION below),
+    the exit code is 5.
 
 HOOKS
 
     Webhooks and exechooks are executed asynchronously from the main git-sync
-    process.  If a --webhook-url or --exechook-command is configured, they will
-    be invoked whenever a new hash is synced, including when git-sync starts up
-    and find that the --root directory already has the correct hash.  For
+    process.  If a --webhook-url, --exechook-command, or --hook is configured,
+    they will be invoked whenever a new hash is synced, including when git-sync
+    starts up and find that the --root directory already has the correct hash
+    (unless the hook already completed for that hash, see below).
+    Each hook is run independently, and the failure of one hook does not
+    prevent others (except those which list it in "after") from running.  For
     exechook, that means the command is exec()'ed, and for webhooks that means
     an HTTP request is sent using the method defined in --webhook-method.
     Git-sync will retry both forms of hooks until they succeed (exit code 0 for
@@ -2821,6 +4492,326 @@ HOOKS
     Hooks are not guaranteed to succeed on every single hash change.  For example,
     if a hook fails and a new hash is synced during the backoff period, the
     retried hook will fire for the newest hash.
+
+    When a "sync" hook completes successfully, git-sync records the hash in a
+    file under the --root directory (.git-sync/hooks/<name>).  When git-sync
+    restarts, hooks are not invoked again for the hash they last completed,
+    but are invoked for any other hash, including one that was synced while
+    a hook was running or failing when git-sync stopped.  This means that
+    every hook is invoked at least once for the published hash, even across
+    restarts.  If the --root directory is wiped, all hooks will be invoked
+    again.
+
+    Hooks configured with --hook can use a more careful retry policy.  The
+    backoff doubles after each failed attempt for a given hash, up to
+    max-backoff.  If max-attempts is set, the hook gives up on a hash after
+    that many attempts, and will not run again until a new hash is synced.
+    Any hooks which depend on it (via "after") also give up on that hash.
+    When a hook gives up, the failure is written to the --error-file (if
+    specified), where it stays until the hook next succeeds, even if syncs
+    succeed.  The git_sync_hook_terminal_failure_count_total metric is
+    incremented, and any hooks with "on" set to "hook-failure" are
+    triggered.  In --one-time mode, the first failure is final.
+
+    If failure-threshold is set, then after that many consecutive failures
+    (across all hashes) the hook's circuit opens: the hook is considered
+    unhealthy (the git_sync_hook_healthy metric is 0, and this is written to
+    the --error-file), further failures are logged only at -v 1 or higher,
+    and it is retried every max-backoff.  The circuit closes, and the
+    --error-file entry is cleared, when the hook next succeeds.
+
+    By default, if a hook is still running (or retrying) when new hashes are
+    synced, it is only invoked for the latest one.  Hooks configured with
+    --hook and "delivery" set to "every" are instead invoked for every hash,
+    in order.  Pending hashes are held in a queue of up to queue-size
+    (default 100) entries.  When the queue is full, queue-overflow decides
+    what happens: "drop-oldest" (the default) discards the oldest pending
+    hash, "drop-newest" discards the new hash, and "block" makes git-sync
+    wait for the hook, which stalls syncing until there is room.  The
+    git_sync_hook_queue_depth metric reports how many hashes are pending,
+    and git_sync_hook_queue_drop_count_total counts discarded hashes.  Hooks
+    with "delivery" set to "every" can not use "after", and other hooks can
+    not depend on them.
+
+VALIDATION
+
+    Hooks configured with --hook and "on" set to "validate" can reject a new
+    hash before it is published.  After the worktree for a new hash is
+    created, and before the --link is updated, each validation hook is run
+    once, in the order specified.  Exechooks must exit 0 and webhooks must
+    respond with success-status (by default, any 2xx status) for the hash to
+    be accepted.  If any validation hook rejects the hash (an exechook exits
+    with a non-zero status, or a webhook responds with any other status),
+    git-sync:
+
+      - does not update the --link, so the previous hash (if any) is still
+        published
+      - removes the new worktree
+      - writes the error to the --error-file (if specified), and increments
+        the git_sync_failure_count_total metric with class "rejected"
+      - remembers the rejected hash, and does not try it again until the
+        remote hash changes
+
+    A rejected hash is not counted against --max-failures.  In --one-time
+    mode, git-sync exits with code 5.  Other validation hook failures (e.g.
+    a timeout, a --sync-timeout, or a webhook which can't be reached) do not
+    reject the hash: the new worktree is removed and the sync fails and is
+    retried like any other sync error.  Validation hooks can not use the
+    after, async, max-backoff, max-attempts, failure-threshold, or delivery
+    fields.
+
+STATE HOOKS
+
+    Hooks configured with --hook can be triggered when git-sync's own state
+    changes, rather than when a new hash is synced:
+
+      - "sync-failure" hooks run when syncing has failed "failures" times in
+        a row (by default 1).  They run once per outage, not on every failed
+        attempt.
+      - "sync-recovery" hooks run when a sync succeeds after at least
+        "failures" (by default 1) consecutive failures.
+      - "exit" hooks run once, synchronously, just before git-sync exits
+        because of --max-failures (or --max-permanent-failures or
+        --max-transient-failures).  They are not retried, and can not use
+        the after, async, max-backoff, max-attempts, failure-threshold, or
+        delivery fields.
+
+    State hooks receive the currently published hash (if any) and worktree,
+    and some extra information.  Exechooks get these environment variables,
+    in addition to those described in EXECHOOKS below:
+
+        GITSYNC_EVENT_TYPE         "sync-failure", "sync-recovery", or "exit"
+        GITSYNC_EVENT_TIME         when the event happened, in RFC 3339
+                                   format
+        GITSYNC_FAILURES           how many consecutive syncs had failed
+        GITSYNC_ERROR_CLASS        the class of the last error, e.g.
+                                   "permanent" or "transient"
+        GITSYNC_ERROR              the last error message
+
+    Webhooks get the same information in the "type", "time", "failures",
+    "errorClass", and "error" fields of the JSON body (see WEBHOOKS below).
+
+EXECHOOKS
+
+    Exechooks are run in the worktree for the synced hash, with these
+    environment variables set:
+
+        GITSYNC_HASH               the git hash that was synced
+        GITSYNC_PREVIOUS_HASH      the hash that was synced before, or ""
+        GITSYNC_REF                the value of --ref
+        GITSYNC_REPO               the value of --repo, with any password
+                                   redacted
+        GITSYNC_LINK               the absolute path of --link
+        GITSYNC_WORKTREE           the absolute path of the worktree for
+                                   GITSYNC_HASH
+        GITSYNC_PREVIOUS_WORKTREE  the worktree for GITSYNC_PREVIOUS_HASH,
+                                   or "" (this may be removed soon after
+                                   the hook runs, see --stale-worktree-timeout)
+        GITSYNC_SYNC_COUNT         how many times the link has been updated
+        GITSYNC_EVENT_FILE         a temporary file holding all of the above,
+                                   plus the list of changed files, as JSON
+                                   (see WEBHOOKS below); this file is removed
+                                   when the hook completes
+
+    The standard output and standard error of exechooks are logged line by
+    line, as they are produced.  Git-sync also remembers the last few runs
+    of each exechook (by default 10, see output-history in --hook),
+    including the hash, start time, duration, exit code (-1 if the command
+    did not exit normally, e.g. it timed out), and the last 64KiB of each
+    output stream.  If --http-hook-output is set, these are served as JSON
+    at /hooks/output, keyed by hook name (use '?name=<hook>' to select one
+    hook).  If output-file is set in --hook, the latest run is also written
+    as JSON to a file under the --root directory
+    (.git-sync/hook-output/<name>), so other containers can see why a hook
+    failed.
+
+WEBHOOKS
+
+    Webhook requests always include the 'Gitsync-Hash' header.  By default,
+    the body is a JSON object (with 'Content-Type: application/json') like:
+
+        {
+          "hash": "<the git hash that was synced>",
+          "previousHash": "<the hash that was synced before, or "">",
+          "ref": "<the value of --ref>",
+          "repo": "<the value of --repo, with any password redacted>",
+          "link": "<the absolute path of --link>",
+          "worktree": "<the absolute path of the worktree for hash>",
+          "previousWorktree": "<the worktree for previousHash, or "">",
+          "syncCount": <how many times the link has been updated>,
+          "changedFiles": [<files changed since previousHash>],
+          "timestamp": "<the time of the request, in RFC 3339 format>"
+        }
+
+    Hooks triggered by state changes (see STATE HOOKS above) also include
+    the "type", "time", "failures", "errorClass", and "error" fields.
+
+    Webhooks configured with --hook can customize the request:
+
+    headers
+            Extra headers to send, e.g. {"X-Env": "prod"}.
+
+    header-files
+            Extra headers to send, with the values read from files before
+            every request, e.g. {"X-Api-Key": "/secrets/api-key"}.  This
+            allows mounted secrets to be rotated.
+
+    bearer-token-file
+            A file from which a bearer token is read before every request, and
+            sent as 'Authorization: Bearer <token>'.
+
+    body
+            One of "json" (the default), "empty" (send no body), or
+            "template" (see below).
+
+    body-template, body-template-file
+            A Go text/template (see https://pkg.go.dev/text/template), or the
+            path to a file holding one, which produces the body.  The template
+            is executed with an object holding the same fields as the JSON
+            body, but capitalized (e.g. {{.Hash}}, {{.PreviousHash}}).  The
+            function "json" is available to encode values, e.g. to quote
+            strings.  Setting this implies body "template".  For example, to
+            post a Slack message:
+
+              {"text": {{ printf "synced %s to %s" .Repo .Hash | json }}}
+
+            Templated bodies have no default Content-Type, so one should
+            usually be set in headers.
+
+    hmac-secret-file
+            A file from which a secret is read before every request, and used
+            to sign the request.  Signed requests include the headers
+            'Gitsync-Timestamp' (the time of the request, in seconds since
+            the epoch) and 'Gitsync-Signature-256' ("sha256=" followed by the
+            hex-encoded HMAC-SHA256 of "<timestamp>.<hash>.<body>").  Receivers
+            should verify the signature and reject requests whose timestamp is
+            too old, to prevent replays.
+
+    tls-ca-file
+            A file holding PEM-encoded CA certificates which are used, instead
+            of --ca-bundle (or the system roots), to verify the receiver.
+
+    tls-cert-file, tls-key-file
+            Files holding a PEM-encoded client certificate and key, for mutual
+            TLS, instead of --tls-client-cert-file and --tls-client-key-file.
+            These must be specified together.
+
+    Other global TLS settings (e.g. --tls-min-version) still apply.  The TLS
+    files are checked before every request, and are reloaded if they have
+    changed, so mounted secrets can be rotated without restarting.
+
+SIGNAL HOOKS
+
+    Hooks configured with --hook and "type" set to "signal" send a signal to
+    other processes, e.g. to make a web server reload its content.  The
+    processes must be visible to git-sync, e.g. in a pod with
+    shareProcessNamespace set.  Exactly one of these fields selects the
+    processes:
+
+    pid-file
+            A file holding a process ID, e.g. "/run/nginx.pid".  This is read
+            every time the hook runs.
+
+    process-name
+            The exact name of the command, e.g. "nginx".  This is compared to
+            the kernel's name for each process and to the base name of its
+            first argument.
+
+    process-regex
+            A regular expression (see https://pkg.go.dev/regexp/syntax) which
+            is matched against each process's full command line, with the
+            arguments separated by spaces, e.g. "^nginx: master".
+
+    The signal field is a name (e.g. "SIGHUP" or "HUP") or number, and
+    defaults to SIGHUP.  All matching processes are signalled, except for
+    git-sync itself.  If no process matches (including when pid-file names a
+    process which is no longer running), the hook fails and is retried like
+    any other hook.  The timeout bounds finding and signalling the
+    processes, and defaults to 1s.
+
+IPC HOOKS
+
+    Hooks configured with --hook can deliver events to other processes on the
+    same machine (e.g. other containers in the pod, through a shared volume)
+    without HTTP.  Each event is a single line of JSON, with the same fields
+    as a webhook body (see WEBHOOKS above).  The "path" field says where to
+    deliver it, and the "type" field says how:
+
+    socket
+            Connect to the Unix domain socket at path, write the event, and
+            close the connection.  The hook fails if nothing is listening.
+
+    fifo
+            Write the event to the named pipe (see mkfifo(1)) at path.  The
+            hook fails, and is retried, if the pipe has no reader.  Events of
+            up to 4KiB are written atomically.  Larger events (e.g. with many
+            changed files) may be interleaved with other writers' data, and
+            may be partly written if the timeout expires.
+
+    spool
+            Write the event to a new file in the directory at path, which is
+            created if needed.  Files are named "<time>-<hash>.json" (or
+            "<time>-<type>.json" for state events, see STATE HOOKS above), so
+            they sort in the order they were written.  Each file is written under a temporary name starting
+            with '.' and then renamed, so consumers should ignore such files.
+            Consumers are responsible for removing files they have handled.
+
+    The timeout bounds delivering each event, including waiting for a socket
+    or FIFO reader to accept it, and defaults to 1s.
+
+LIMITS
+
+    The --git-limits and --exechook-limits flags, and the limits field of
+    exec hooks in --hook, take a comma-separated list of key=value pairs,
+    e.g. "cpu=60s,memory=512Mi,nofile=1024,nice=10,ionice=idle":
+
+    cpu
+            The maximum CPU time, as a number of seconds or a duration
+            string (RLIMIT_CPU).
+
+    memory
+            The maximum size of the address space, in bytes, with an optional
+            suffix of Ki, Mi, Gi, K, M, or G (RLIMIT_AS).
+
+    nofile
+            The maximum number of open files (RLIMIT_NOFILE).
+
+    nice
+            The scheduling priority, from -20 (highest) to 19 (lowest).
+            Raising the priority usually requires privileges.
+
+    ionice
+            The IO scheduling class, one of "realtime", "best-effort", or
+            "idle", optionally followed by ":" and a priority from 0
+            (highest) to 7 (lowest), e.g. "best-effort:7".  If not specified,
+            the priority is 4.
+
+    Limits are applied to each command before it starts (git-sync runs a
+    copy of itself which applies them and then execs the command), and are
+    inherited by anything it runs.  They are only supported on Linux.  If a
+    limit can not be applied (e.g. a hard limit can not be raised without
+    privileges), the command is not run, and fails with exit code 126.
+
+KUBERNETES
+
+    When git-sync runs in a Kubernetes pod, it can describe its progress
+    through the Kubernetes API (see --kube-events and --kube-annotation), so
+    it is visible with 'kubectl describe pod' and 'kubectl get pod'.  This
+    uses the pod's service account token (which is re-read before every
+    request, so it can be rotated) and the in-cluster API server address.
+    The pod's name must be passed with --pod-name, usually from the
+    downward API:
+
+        env:
+        - name: GITSYNC_POD_NAME
+          valueFrom:
+            fieldRef:
+              fieldPath: metadata.name
+
+    The service account needs permission to "get" and "patch" its pod (for
+    --kube-annotation, and to find the pod's UID for events) and to "create"
+    events in the pod's namespace (for --kube-events).  Errors talking to
+    the API server are logged, but do not affect syncing.
 `
 
 func printManPage() {
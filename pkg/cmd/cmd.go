@@ -22,16 +22,30 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os/exec"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/go-logr/logr"
 )
 
 // Runner is an API to run commands and log them in a consistent way.
+//
+// Each command is run in its own process group.  If the context is done
+// before the command exits, the whole group is sent SIGTERM, and then
+// SIGKILL after the grace period (or when the command exits, if sooner).
 type Runner struct {
 	log logintf
+	// How long to wait after SIGTERM before killing a command's process
+	// group.
+	grace time.Duration
+	// Applied to each command before it starts.
+	limits Limits
+	// Redacts secrets from errors, or nil.
+	redactor redactor
 }
 
 // Just the logr methods we need in this package.
@@ -42,25 +56,87 @@ type logintf interface {
 	WithCallDepth(depth int) logr.Logger
 }
 
-// NewRunner returns a new CommandRunner.
+// redactor is implemented by loggers which redact secrets (e.g.
+// logging.Logger).  Their logs are already redacted, but errors are not.
+type redactor interface {
+	Redact(s string) string
+}
+
+// NewRunner returns a new CommandRunner.  If log redacts secrets, so do the
+// errors which the runner returns.
 func NewRunner(log logintf) Runner {
-	return Runner{log: log}
+	r := Runner{log: log}
+	if rd, ok := log.(redactor); ok {
+		r.redactor = rd
+	}
+	return r
+}
+
+// Redact returns s with secrets redacted, if the runner's logger redacts
+// secrets.
+func (r Runner) Redact(s string) string {
+	if r.redactor == nil {
+		return s
+	}
+	return r.redactor.Redact(s)
 }
 
 // Run runs the given command, returning the stdout, stderr, and any error.
 func (r Runner) Run(ctx context.Context, cwd string, env []string, command string, args ...string) (string, string, error) {
-	// call depth = 2 to erase the runWithStdin frame and this one
-	return runWithStdin(ctx, r.log.WithCallDepth(2), cwd, env, "", command, args...)
+	// call depth = 2 to erase the run frame and this one
+	return r.run(ctx, r.log.WithCallDepth(2), cwd, env, runOptions{}, command, args...)
+}
+
+// RunWithOutput runs the given command like Run, and also calls onLine for
+// each line of standard output ("stdout") and standard error ("stderr") as
+// it is produced.  Calls to onLine are serialized.  Only the last MaxStderr
+// bytes of standard output are returned.
+func (r Runner) RunWithOutput(ctx context.Context, cwd string, env []string, onLine func(stream, line string), command string, args ...string) (string, string, error) {
+	// call depth = 2 to erase the run frame and this one
+	return r.run(ctx, r.log.WithCallDepth(2), cwd, env, runOptions{onLine: onLine}, command, args...)
+}
+
+// RunWithProgress runs the given command like Run, and calls onProgress for
+// each git progress line (see ParseProgress) on standard error as it is
+// produced.  Progress lines are not included in the returned stderr.  This
+// is intended for git commands run with --progress.
+func (r Runner) RunWithProgress(ctx context.Context, cwd string, env []string, onProgress func(Progress), command string, args ...string) (string, string, error) {
+	// call depth = 2 to erase the run frame and this one
+	return r.run(ctx, r.log.WithCallDepth(2), cwd, env, runOptions{onProgress: onProgress}, command, args...)
 }
 
 // RunWithStdin runs the given command with standard input, returning the stdout,
 // stderr, and any error.
 func (r Runner) RunWithStdin(ctx context.Context, cwd string, env []string, stdin, command string, args ...string) (string, string, error) {
-	// call depth = 2 to erase the runWithStdin frame and this one
-	return runWithStdin(ctx, r.log.WithCallDepth(2), cwd, env, stdin, command, args...)
+	// call depth = 2 to erase the run frame and this one
+	return r.run(ctx, r.log.WithCallDepth(2), cwd, env, runOptions{stdin: stdin}, command, args...)
+}
+
+// RunWithSecretOutput runs the given command like Run, but its standard
+// output, which holds secrets (e.g. credentials), is neither logged nor
+// included in errors.
+func (r Runner) RunWithSecretOutput(ctx context.Context, cwd string, env []string, command string, args ...string) (string, string, error) {
+	// call depth = 2 to erase the run frame and this one
+	return r.run(ctx, r.log.WithCallDepth(2), cwd, env, runOptions{secretOutput: true}, command, args...)
 }
 
-func runWithStdin(ctx context.Context, log logintf, cwd string, env []string, stdin, command string, args ...string) (string, string, error) {
+// MaxStderr is how many bytes of a command's standard error are kept.  If a
+// command produces more than this, only the end is kept.
+const MaxStderr = 64 * 1024
+
+// runOptions are the less common ways to run a command.
+type runOptions struct {
+	// Passed to the command's standard input.
+	stdin string
+	// If not nil, called for each line of output.
+	onLine func(stream, line string)
+	// If not nil, called for each progress line on standard error.
+	onProgress func(Progress)
+	// If true, standard output is not logged or included in errors.
+	secretOutput bool
+}
+
+func (r Runner) run(ctx context.Context, log logintf, cwd string, env []string, opts runOptions, command string, args ...string) (string, string, error) {
 	cmdStr := cmdForLog(command, args...)
 	log.V(5).Info("running command", "cwd", cwd, "cmd", cmdStr)
 
@@ -71,28 +147,200 @@ func runWithStdin(ctx context.Context, log logintf, cwd string, env []string, st
 	if len(env) != 0 {
 		cmd.Env = env
 	}
-	outbuf := bytes.NewBuffer(nil)
-	errbuf := bytes.NewBuffer(nil)
+	var outbuf interface {
+		io.Writer
+		String() string
+	} = bytes.NewBuffer(nil)
+	errbuf := &tailBuffer{max: MaxStderr}
+	if opts.onLine != nil {
+		// The caller sees every line, so only the end needs to be kept.
+		outbuf = &tailBuffer{max: MaxStderr}
+	}
 	cmd.Stdout = outbuf
 	cmd.Stderr = errbuf
-	cmd.Stdin = bytes.NewBufferString(stdin)
+	var lineWriters []*lineWriter
+	mu := &sync.Mutex{}
+	if opts.onLine != nil {
+		outlines := &lineWriter{stream: "stdout", mutex: mu, fn: opts.onLine}
+		errlines := &lineWriter{stream: "stderr", mutex: mu, fn: opts.onLine}
+		cmd.Stdout = io.MultiWriter(outbuf, outlines)
+		cmd.Stderr = io.MultiWriter(errbuf, errlines)
+		lineWriters = append(lineWriters, outlines, errlines)
+	} else if opts.onProgress != nil {
+		// Progress is written as many short lines, which are not useful
+		// once they have been reported.
+		errlines := &lineWriter{stream: "stderr", mutex: mu, fn: func(_, line string) {
+			if p, ok := ParseProgress(line); ok {
+				opts.onProgress(p)
+				return
+			}
+			errbuf.Write([]byte(line + "\n"))
+		}}
+		cmd.Stderr = errlines
+		lineWriters = append(lineWriters, errlines)
+	}
+	cmd.Stdin = bytes.NewBufferString(opts.stdin)
+
+	// Run in a new process group, so that any children (e.g. ssh, or
+	// anything an exechook starts) can be stopped along with the command.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	canceled := false
+	cmd.Cancel = func() error {
+		canceled = true
+		if r.grace <= 0 {
+			return signalGroup(cmd.Process.Pid, syscall.SIGKILL)
+		}
+		log.V(5).Info("terminating command", "cmd", cmdStr, "grace", r.grace)
+		return signalGroup(cmd.Process.Pid, syscall.SIGTERM)
+	}
+	// If the command has not exited after the grace period, the command
+	// itself is killed and its output pipes are closed.
+	cmd.WaitDelay = r.grace
+
+	if !r.limits.IsZero() {
+		if err := r.limits.wrap(cmd); err != nil {
+			return "", "", &Error{Cmd: r.Redact(cmdStr), Err: fmt.Errorf("can't apply limits: %w", err)}
+		}
+	}
 
 	start := time.Now()
-	err := cmd.Run()
+	err := cmd.Start()
+	if err == nil {
+		err = cmd.Wait()
+	}
+	if canceled {
+		// Whatever is left of the group must go too.  The group can't be
+		// reused until all of its members are gone.
+		_ = signalGroup(cmd.Process.Pid, syscall.SIGKILL)
+	}
 	wallTime := time.Since(start)
+	for _, lw := range lineWriters {
+		lw.flush()
+	}
 	stdout := strings.TrimSpace(outbuf.String())
 	stderr := strings.TrimSpace(errbuf.String())
+	errStdout := r.Redact(stdout)
+	if opts.secretOutput {
+		errStdout = ""
+	}
+	// The output which is returned may hold secrets (e.g. from "git
+	// credential fill"), but errors are logged and reported.
 	if ctx.Err() == context.DeadlineExceeded {
-		return stdout, stderr, fmt.Errorf("Run(%s): %w: { stdout: %q, stderr: %q }", cmdStr, ctx.Err(), stdout, stderr)
+		return stdout, stderr, &Error{Cmd: r.Redact(cmdStr), Stdout: errStdout, Stderr: r.Redact(stderr), Err: ctx.Err()}
 	}
 	if err != nil {
-		return stdout, stderr, fmt.Errorf("Run(%s): %w: { stdout: %q, stderr: %q }", cmdStr, err, stdout, stderr)
+		return stdout, stderr, &Error{Cmd: r.Redact(cmdStr), Stdout: errStdout, Stderr: r.Redact(stderr), Err: err}
+	}
+	if opts.secretOutput {
+		log.V(6).Info("command result", "stderr", stderr, "time", wallTime)
+	} else {
+		log.V(6).Info("command result", "stdout", stdout, "stderr", stderr, "time", wallTime)
 	}
-	log.V(6).Info("command result", "stdout", stdout, "stderr", stderr, "time", wallTime)
 
 	return stdout, stderr, nil
 }
 
+// Error is returned by Runner when a command fails.  It carries the output of
+// the command, so callers can inspect it (e.g. to classify the failure).  If
+// the Runner redacts secrets, they are redacted from the command and output.
+type Error struct {
+	// The command, as it was logged.
+	Cmd string
+	// The (trimmed) standard output of the command.
+	Stdout string
+	// The (trimmed) standard error of the command.
+	Stderr string
+	// The underlying error.
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("Run(%s): %v: { stdout: %q, stderr: %q }", e.Cmd, e.Err, e.Stdout, e.Stderr)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// lineWriter is an io.Writer which calls a function for each complete line
+// written to it.  Lines may end in "\n", "\r\n", or "\r" (which is how
+// progress is updated in place).  Very long lines are split, so memory use
+// is bounded.
+type lineWriter struct {
+	stream string
+	// Shared between the writers for a command, so fn is never called
+	// concurrently.
+	mutex *sync.Mutex
+	fn    func(stream, line string)
+	// Any partial line which has not been passed to fn yet.
+	partial []byte
+	// Whether the last line ended in "\r".
+	lastCR bool
+}
+
+// maxLine is the longest line which lineWriter will pass to fn.
+const maxLine = MaxStderr
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for _, b := range p {
+		if b == '\n' && w.lastCR && len(w.partial) == 0 {
+			// The second half of "\r\n".
+			w.lastCR = false
+			continue
+		}
+		if b == '\n' || b == '\r' {
+			w.fn(w.stream, string(w.partial))
+			w.partial = w.partial[:0]
+			w.lastCR = b == '\r'
+			continue
+		}
+		w.lastCR = false
+		w.partial = append(w.partial, b)
+		if len(w.partial) >= maxLine {
+			w.fn(w.stream, string(w.partial))
+			w.partial = w.partial[:0]
+		}
+	}
+	return len(p), nil
+}
+
+// flush passes any final, unterminated line to fn.
+func (w *lineWriter) flush() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if len(w.partial) > 0 {
+		w.fn(w.stream, string(w.partial))
+		w.partial = nil
+	}
+}
+
+// tailBuffer is an io.Writer which keeps the last max bytes written to it.
+type tailBuffer struct {
+	max       int
+	buf       []byte
+	truncated bool
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.max {
+		// Copy, so the discarded data can be freed.
+		b.buf = append([]byte(nil), b.buf[len(b.buf)-b.max:]...)
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+// String returns the data which was kept, noting if anything was discarded.
+func (b *tailBuffer) String() string {
+	if b.truncated {
+		return "[...]" + string(b.buf)
+	}
+	return string(b.buf)
+}
+
 func cmdForLog(command string, args ...string) string {
 	if strings.ContainsAny(command, " \t\n") {
 		command = fmt.Sprintf("%q", command)
@@ -108,7 +356,38 @@ func cmdForLog(command string, args ...string) string {
 }
 
 func (r Runner) WithCallDepth(depth int) Runner {
-	return Runner{
-		log: r.log.WithCallDepth(depth),
+	r.log = r.log.WithCallDepth(depth)
+	return r
+}
+
+// WithGracePeriod returns a Runner which waits for the specified duration
+// after asking a command's process group to terminate, before killing it.
+// If d is 0, the group is killed immediately.
+func (r Runner) WithGracePeriod(d time.Duration) Runner {
+	r.grace = d
+	return r
+}
+
+// WithRedactor returns a Runner which redacts secrets from errors with rd.
+// This is for runners whose logger does not redact by itself, e.g. one
+// returned by logging.Logger's WithName.
+func (r Runner) WithRedactor(rd redactor) Runner {
+	r.redactor = rd
+	return r
+}
+
+// WithLimits returns a Runner which applies the specified limits to each
+// command it runs.  The program must call RunLimitsShim at startup.
+func (r Runner) WithLimits(limits Limits) Runner {
+	r.limits = limits
+	return r
+}
+
+// signalGroup sends a signal to the process group led by pid.  It is not an
+// error if the group no longer exists.
+func signalGroup(pid int, sig syscall.Signal) error {
+	if err := syscall.Kill(-pid, sig); err != nil && err != syscall.ESRCH {
+		return err
 	}
+	return nil
 }
@@ -0,0 +1,225 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// testLogger adapts logr.Discard to logintf.
+type testLogger struct {
+	logr.Logger
+}
+
+func (l testLogger) WithCallDepth(depth int) logr.Logger {
+	return l.Logger
+}
+
+// isRunning returns true if the process in pidfile is still running.
+func isRunning(t *testing.T, pidfile string) bool {
+	t.Helper()
+	b, err := os.ReadFile(pidfile)
+	if err != nil {
+		t.Fatalf("can't read pid file: %v", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		t.Fatalf("can't parse pid file: %v", err)
+	}
+	return syscall.Kill(pid, 0) == nil
+}
+
+func TestRunKillsProcessGroup(t *testing.T) {
+	pidfile := filepath.Join(t.TempDir(), "pid")
+	r := NewRunner(testLogger{logr.Discard()})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, _, err := r.Run(ctx, "", nil, "/bin/sh", "-c", "sleep 30 & echo $! > "+pidfile+"; wait")
+	if err == nil {
+		t.Fatalf("expected error but got nil")
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("command took too long to stop: %v", d)
+	}
+	// The child might take a moment to be reaped.
+	for i := 0; i < 50 && isRunning(t, pidfile); i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	if isRunning(t, pidfile) {
+		t.Errorf("child process is still running")
+	}
+}
+
+func TestRunGracePeriod(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	r := NewRunner(testLogger{logr.Discard()}).WithGracePeriod(5 * time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	// The command cleans up when asked to terminate.
+	_, _, err := r.Run(ctx, "", nil, "/bin/sh", "-c", "trap 'echo cleaned up > "+out+"; exit 1' TERM; while true; do sleep 0.1; done")
+	if err == nil {
+		t.Fatalf("expected error but got nil")
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("command was not allowed to clean up: %v", err)
+	}
+	if got := strings.TrimSpace(string(b)); got != "cleaned up" {
+		t.Errorf("unexpected output: %q", got)
+	}
+}
+
+func TestRunWithProgress(t *testing.T) {
+	r := NewRunner(testLogger{logr.Discard()})
+	script := `printf 'Receiving objects:  50%% (1/2)\r' >&2
+		printf 'Receiving objects: 100%% (2/2), 1.00 KiB | 1.00 MiB/s, done.\n' >&2
+		echo 'fatal: something went wrong' >&2
+		echo out`
+	var got []Progress
+	stdout, stderr, err := r.RunWithProgress(context.Background(), "", nil, func(p Progress) {
+		got = append(got, p)
+	}, "/bin/sh", "-c", script)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stdout != "out" {
+		t.Errorf("unexpected stdout: %q", stdout)
+	}
+	if want := "fatal: something went wrong"; stderr != want {
+		t.Errorf("expected stderr %q, got %q", want, stderr)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 progress updates, got %+v", got)
+	}
+	if got[0].Percent != 50 || got[1].Percent != 100 || got[1].Bytes != 1024 || !got[1].Done {
+		t.Errorf("unexpected progress: %+v", got)
+	}
+}
+
+func TestRunStderrBounded(t *testing.T) {
+	r := NewRunner(testLogger{logr.Discard()})
+	_, stderr, err := r.Run(context.Background(), "", nil, "/bin/sh", "-c", "head -c 1000000 /dev/zero | tr '\\0' x >&2; echo the end >&2; exit 1")
+	if err == nil {
+		t.Fatalf("expected error but got nil")
+	}
+	if len(stderr) > MaxStderr+10 {
+		t.Errorf("stderr was not bounded: %d bytes", len(stderr))
+	}
+	if !strings.HasPrefix(stderr, "[...]") || !strings.HasSuffix(stderr, "the end") {
+		t.Errorf("expected the tail of stderr, got %q...%q", stderr[:10], stderr[len(stderr)-10:])
+	}
+}
+
+// redactingLogger is a testLogger which redacts "s3cret".
+type redactingLogger struct {
+	testLogger
+}
+
+func (l redactingLogger) Redact(s string) string {
+	return strings.ReplaceAll(s, "s3cret", "REDACTED")
+}
+
+func TestRunRedactsErrors(t *testing.T) {
+	ctx := context.Background()
+	r := NewRunner(redactingLogger{testLogger{logr.Discard()}})
+
+	// The output is returned as-is, but the error is redacted.
+	stdout, stderr, err := r.Run(ctx, "", nil, "/bin/sh", "-c", "echo out-s3cret; echo err-s3cret >&2; exit 1", "s3cret")
+	if stdout != "out-s3cret" || stderr != "err-s3cret" {
+		t.Errorf("expected unredacted output, got %q, %q", stdout, stderr)
+	}
+	if err == nil {
+		t.Fatalf("expected error but got nil")
+	}
+	if strings.Contains(err.Error(), "s3cret") || !strings.Contains(err.Error(), "out-REDACTED") {
+		t.Errorf("expected redacted error, got %q", err.Error())
+	}
+
+	// Secret output is left out of errors entirely.
+	stdout, _, err = r.RunWithSecretOutput(ctx, "", nil, "/bin/sh", "-c", "echo password=hunter$((1+1)); exit 1")
+	if stdout != "password=hunter2" {
+		t.Errorf("expected unredacted output, got %q", stdout)
+	}
+	if err == nil {
+		t.Fatalf("expected error but got nil")
+	}
+	if strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("expected no output in error, got %q", err.Error())
+	}
+
+	// Loggers which don't redact are fine.
+	if got := NewRunner(testLogger{logr.Discard()}).Redact("s3cret"); got != "s3cret" {
+		t.Errorf("expected no redaction, got %q", got)
+	}
+}
+
+func TestRunRedactsErrorsWithNamedLogger(t *testing.T) {
+	ctx := context.Background()
+	root := redactingLogger{testLogger{logr.Discard()}}
+	// A named logger is a plain logr.Logger, which can't redact.
+	named := testLogger{root.WithName("hook")}
+
+	if got := NewRunner(named).Redact("s3cret"); got != "s3cret" {
+		t.Errorf("expected no redaction without a redactor, got %q", got)
+	}
+
+	r := NewRunner(named).WithRedactor(root)
+	if got := r.Redact("out-s3cret"); got != "out-REDACTED" {
+		t.Errorf("expected redaction, got %q", got)
+	}
+	_, _, err := r.Run(ctx, "", nil, "/bin/sh", "-c", "echo out-s3cret; exit 1")
+	if err == nil {
+		t.Fatalf("expected error but got nil")
+	}
+	if strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("expected redacted error, got %q", err.Error())
+	}
+}
+
+func TestRunWithOutputStdoutBounded(t *testing.T) {
+	r := NewRunner(testLogger{logr.Discard()})
+	lines := 0
+	stdout, _, err := r.RunWithOutput(context.Background(), "", nil, func(stream, _ string) {
+		if stream == "stdout" {
+			lines++
+		}
+	}, "/bin/sh", "-c", "yes line | head -n 100000; echo the end")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lines != 100001 {
+		t.Errorf("expected 100001 lines, got %d", lines)
+	}
+	if len(stdout) > MaxStderr+10 {
+		t.Errorf("stdout was not bounded: %d bytes", len(stdout))
+	}
+	if !strings.HasPrefix(stdout, "[...]") || !strings.HasSuffix(stdout, "the end") {
+		t.Errorf("expected the tail of stdout, got %q...%q", stdout[:10], stdout[len(stdout)-10:])
+	}
+}
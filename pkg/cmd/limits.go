@@ -0,0 +1,232 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// IO scheduling classes, as used by ionice.
+const (
+	IOClassRealtime   = "realtime"
+	IOClassBestEffort = "best-effort"
+	IOClassIdle       = "idle"
+)
+
+// Limits are resource limits and scheduling priorities which are applied to
+// a command before it starts.  They are inherited by anything the command
+// runs.  Zero values are not applied.
+type Limits struct {
+	// Maximum CPU time (RLIMIT_CPU), in seconds.
+	CPUSeconds uint64
+	// Maximum size of the address space (RLIMIT_AS), in bytes.
+	MemoryBytes uint64
+	// Maximum number of open files (RLIMIT_NOFILE).
+	OpenFiles uint64
+	// Scheduling priority, from -20 (highest) to 19 (lowest).
+	Nice int
+	// IO scheduling class, one of the IOClass* values, or "".
+	IOClass string
+	// IO priority within IOClass, from 0 (highest) to 7 (lowest).  This is
+	// ignored for IOClassIdle.
+	IOPriority int
+}
+
+// IsZero returns true if no limits are set.
+func (l Limits) IsZero() bool {
+	return l == Limits{}
+}
+
+// limitsShimEnv holds the limits, as JSON, when this program is run as a
+// limits shim (see RunLimitsShim).
+const limitsShimEnv = "GITSYNC_INTERNAL_LIMITS"
+
+// limitsShimExitCode is the exit code of a limits shim which can't apply the
+// limits or run the command.
+const limitsShimExitCode = 126
+
+// wrap changes c to run through a limits shim: this program, which applies
+// the limits to itself and then execs c's command.  Limits are inherited
+// across exec, so they are in place before the command runs any code.
+func (l Limits) wrap(c *exec.Cmd) error {
+	if err := l.supported(); err != nil {
+		return err
+	}
+	if c.Err != nil {
+		// Start will fail anyway.
+		return nil
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("can't find this program: %w", err)
+	}
+	jb, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	env := c.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	c.Env = append(env, limitsShimEnv+"="+string(jb))
+	c.Args = append([]string{"git-sync-limits", c.Path}, c.Args...)
+	c.Path = self
+	return nil
+}
+
+// RunLimitsShim must be called at the start of any program which uses a
+// Runner with limits (see Runner.WithLimits).  If this process was started
+// as a limits shim, it applies the limits and execs the command, and never
+// returns.  Otherwise it does nothing.
+func RunLimitsShim() {
+	jl, found := os.LookupEnv(limitsShimEnv)
+	if !found {
+		return
+	}
+	fail := func(format string, a ...any) {
+		fmt.Fprintf(os.Stderr, "git-sync: "+format+"\n", a...)
+		os.Exit(limitsShimExitCode)
+	}
+	// The command should not see this, or anything it runs would think it
+	// is a shim, too.
+	_ = os.Unsetenv(limitsShimEnv)
+	if len(os.Args) < 3 {
+		fail("limits shim: no command")
+	}
+	l := Limits{}
+	if err := json.Unmarshal([]byte(jl), &l); err != nil {
+		fail("limits shim: can't decode limits: %v", err)
+	}
+	// Scheduling priorities belong to a thread, which must be the one which
+	// execs the command.
+	runtime.LockOSThread()
+	if err := l.apply(); err != nil {
+		fail("can't apply limits: %v", err)
+	}
+	err := syscall.Exec(os.Args[1], os.Args[2:], os.Environ())
+	fail("can't run %s: %v", os.Args[1], err)
+}
+
+// ParseLimits parses a comma-separated list of limits, e.g.
+// "cpu=60s,memory=512Mi,nofile=1024,nice=10,ionice=best-effort:7".
+func ParseLimits(s string) (Limits, error) {
+	l := Limits{}
+	if strings.TrimSpace(s) == "" {
+		return l, nil
+	}
+	for _, kv := range strings.Split(s, ",") {
+		k, v, found := strings.Cut(strings.TrimSpace(kv), "=")
+		if !found || v == "" {
+			return l, fmt.Errorf("invalid limit %q: must be key=value", kv)
+		}
+		var err error
+		switch k {
+		case "cpu":
+			l.CPUSeconds, err = parseCPU(v)
+		case "memory":
+			l.MemoryBytes, err = parseBytes(v)
+		case "nofile":
+			l.OpenFiles, err = strconv.ParseUint(v, 10, 64)
+		case "nice":
+			l.Nice, err = strconv.Atoi(v)
+			if err == nil && (l.Nice < -20 || l.Nice > 19) {
+				err = fmt.Errorf("must be between -20 and 19")
+			}
+		case "ionice":
+			l.IOClass, l.IOPriority, err = parseIONice(v)
+		default:
+			return l, fmt.Errorf("invalid limit %q: key must be one of cpu, memory, nofile, nice, or ionice", kv)
+		}
+		if err != nil {
+			return l, fmt.Errorf("invalid limit %q: %w", kv, err)
+		}
+	}
+	return l, nil
+}
+
+// parseCPU parses a number of seconds, or a duration string like "1m".
+func parseCPU(s string) (uint64, error) {
+	if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return n, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < time.Second {
+		return 0, fmt.Errorf("must be at least 1s")
+	}
+	return uint64(d / time.Second), nil
+}
+
+// parseBytes parses a number of bytes, with an optional binary (Ki, Mi, Gi)
+// or decimal (K, M, G) suffix.
+func parseBytes(s string) (uint64, error) {
+	suffixes := []struct {
+		suffix string
+		mult   uint64
+	}{
+		{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30},
+		{"K", 1000}, {"M", 1000 * 1000}, {"G", 1000 * 1000 * 1000},
+	}
+	mult := uint64(1)
+	for _, sfx := range suffixes {
+		if strings.HasSuffix(s, sfx.suffix) {
+			s = strings.TrimSuffix(s, sfx.suffix)
+			mult = sfx.mult
+			break
+		}
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return n * mult, nil
+}
+
+// parseIONice parses an IO class, optionally followed by ":" and a priority.
+func parseIONice(s string) (string, int, error) {
+	class, prio, hasPrio := strings.Cut(s, ":")
+	switch class {
+	case IOClassRealtime, IOClassBestEffort, IOClassIdle:
+	default:
+		return "", 0, fmt.Errorf("class must be one of %q, %q, or %q", IOClassRealtime, IOClassBestEffort, IOClassIdle)
+	}
+	if !hasPrio {
+		// The same default as ionice.
+		return class, 4, nil
+	}
+	if class == IOClassIdle {
+		return "", 0, fmt.Errorf("class %q does not take a priority", IOClassIdle)
+	}
+	n, err := strconv.Atoi(prio)
+	if err != nil {
+		return "", 0, err
+	}
+	if n < 0 || n > 7 {
+		return "", 0, fmt.Errorf("priority must be between 0 and 7")
+	}
+	return class, n, nil
+}
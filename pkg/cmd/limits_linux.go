@@ -0,0 +1,76 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// From linux/ioprio.h.
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+)
+
+var ioprioClasses = map[string]int{
+	IOClassRealtime:   1,
+	IOClassBestEffort: 2,
+	IOClassIdle:       3,
+}
+
+// supported returns an error if the limits can't be applied on this
+// platform.
+func (l Limits) supported() error {
+	return nil
+}
+
+// apply sets the limits on this process, and on the calling thread, which
+// must be locked to the calling goroutine.  They are inherited across exec.
+func (l Limits) apply() error {
+	rlimits := []struct {
+		name     string
+		resource int
+		value    uint64
+	}{
+		{"cpu", unix.RLIMIT_CPU, l.CPUSeconds},
+		{"memory", unix.RLIMIT_AS, l.MemoryBytes},
+		{"nofile", unix.RLIMIT_NOFILE, l.OpenFiles},
+	}
+	for _, rl := range rlimits {
+		if rl.value == 0 {
+			continue
+		}
+		lim := unix.Rlimit{Cur: rl.value, Max: rl.value}
+		if err := unix.Setrlimit(rl.resource, &lim); err != nil {
+			return fmt.Errorf("can't set %s limit: %w", rl.name, err)
+		}
+	}
+	if l.Nice != 0 {
+		if err := unix.Setpriority(unix.PRIO_PROCESS, 0, l.Nice); err != nil {
+			return fmt.Errorf("can't set nice: %w", err)
+		}
+	}
+	if l.IOClass != "" {
+		prio := ioprioClasses[l.IOClass]<<ioprioClassShift | l.IOPriority
+		if _, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, 0, uintptr(prio)); errno != 0 {
+			return fmt.Errorf("can't set ionice: %w", errno)
+		}
+	}
+	return nil
+}
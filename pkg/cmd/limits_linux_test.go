@@ -0,0 +1,65 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+func TestMain(m *testing.M) {
+	// Runners with limits run this test binary as a shim.
+	RunLimitsShim()
+	os.Exit(m.Run())
+}
+
+func TestRunLimits(t *testing.T) {
+	r := NewRunner(testLogger{logr.Discard()}).WithLimits(Limits{OpenFiles: 42, Nice: 3})
+	// The limits are in place before the command runs.
+	stdout, _, err := r.Run(context.Background(), "", nil, "/bin/sh", "-c", "ulimit -n; cut -d' ' -f19 /proc/$$/stat; env")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(stdout, "\n")
+	if want := []string{"42", "3"}; !reflect.DeepEqual(lines[:2], want) {
+		t.Errorf("expected %q, got %q", want, lines[:2])
+	}
+	for _, kv := range lines[2:] {
+		if strings.HasPrefix(kv, limitsShimEnv+"=") {
+			t.Errorf("the shim's environment leaked to the command: %q", kv)
+		}
+	}
+
+	// A command which can't be run fails like it would without limits.
+	if _, _, err := r.Run(context.Background(), "", nil, "/does/not/exist"); err == nil {
+		t.Errorf("expected an error")
+	}
+
+	// So does a limit which can't be applied.
+	r = NewRunner(testLogger{logr.Discard()}).WithLimits(Limits{OpenFiles: 1 << 40})
+	_, stderr, err := r.Run(context.Background(), "", nil, "/bin/true")
+	if err == nil {
+		t.Errorf("expected an error")
+	} else if !strings.Contains(stderr, "can't apply limits") {
+		t.Errorf("expected a limits error, got %q", stderr)
+	}
+}
@@ -0,0 +1,37 @@
+//go:build !linux
+
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+)
+
+// supported returns an error if the limits can't be applied on this
+// platform.  Limits are only supported on Linux.
+func (l Limits) supported() error {
+	if l.IsZero() {
+		return nil
+	}
+	return fmt.Errorf("resource limits are not supported on this platform")
+}
+
+// apply sets the limits on this process.  This is only supported on Linux.
+func (l Limits) apply() error {
+	return l.supported()
+}
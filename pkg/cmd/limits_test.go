@@ -0,0 +1,67 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"testing"
+)
+
+func TestParseLimits(t *testing.T) {
+	cases := []struct {
+		input string
+		want  Limits
+		fail  bool
+	}{
+		{input: "", want: Limits{}},
+		{input: "cpu=60", want: Limits{CPUSeconds: 60}},
+		{input: "cpu=2m", want: Limits{CPUSeconds: 120}},
+		{input: "cpu=10ms", fail: true},
+		{input: "memory=1024", want: Limits{MemoryBytes: 1024}},
+		{input: "memory=512Mi", want: Limits{MemoryBytes: 512 << 20}},
+		{input: "memory=1G", want: Limits{MemoryBytes: 1000 * 1000 * 1000}},
+		{input: "memory=lots", fail: true},
+		{input: "nofile=1024", want: Limits{OpenFiles: 1024}},
+		{input: "nice=10", want: Limits{Nice: 10}},
+		{input: "nice=20", fail: true},
+		{input: "ionice=idle", want: Limits{IOClass: IOClassIdle, IOPriority: 4}},
+		{input: "ionice=best-effort:7", want: Limits{IOClass: IOClassBestEffort, IOPriority: 7}},
+		{input: "ionice=idle:1", fail: true},
+		{input: "ionice=best-effort:8", fail: true},
+		{input: "ionice=fast", fail: true},
+		{
+			input: "cpu=60s, memory=1Gi, nofile=64, nice=5, ionice=realtime:0",
+			want:  Limits{CPUSeconds: 60, MemoryBytes: 1 << 30, OpenFiles: 64, Nice: 5, IOClass: IOClassRealtime},
+		},
+		{input: "cpu", fail: true},
+		{input: "disk=1G", fail: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := ParseLimits(tc.input)
+			if err != nil && !tc.fail {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err == nil && tc.fail {
+				t.Fatalf("unexpected success: %+v", got)
+			}
+			if !tc.fail && got != tc.want {
+				t.Errorf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
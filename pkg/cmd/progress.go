@@ -0,0 +1,81 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Progress is one update from git's progress output (e.g. from
+// `git fetch --progress`).
+type Progress struct {
+	// What git is doing, e.g. "Receiving objects".
+	Phase string
+	// Whether this was reported by the remote side.
+	Remote bool
+	// How far through the phase git is, or -1 if not known.
+	Percent int
+	// How many things (e.g. objects) have been processed so far.
+	Count uint64
+	// How many things there are in total, or 0 if not known.
+	Total uint64
+	// How many bytes have been transferred, or 0 if not reported.
+	Bytes uint64
+	// Whether the phase is complete.
+	Done bool
+}
+
+// e.g. "Receiving objects:  45% (450/1000), 1.20 MiB | 2.00 MiB/s" or
+// "remote: Enumerating objects: 5, done."
+var progressRE = regexp.MustCompile(`^(remote: )?([A-Z][a-z]+(?: [a-z]+)*): +(?:(\d+)% \((\d+)/(\d+)\)|(\d+))(?:, ([\d.]+) (bytes|KiB|MiB|GiB|TiB))?(?: \| [\d.]+ [KMGT]?i?B/s)?(, done\.?)?$`)
+
+var progressUnits = map[string]float64{
+	"bytes": 1,
+	"KiB":   1 << 10,
+	"MiB":   1 << 20,
+	"GiB":   1 << 30,
+	"TiB":   1 << 40,
+}
+
+// ParseProgress parses a line of git's progress output.  It returns false if
+// the line is not a progress line.
+func ParseProgress(line string) (Progress, bool) {
+	m := progressRE.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return Progress{}, false
+	}
+	p := Progress{
+		Remote:  m[1] != "",
+		Phase:   m[2],
+		Percent: -1,
+		Done:    m[9] != "",
+	}
+	if m[3] != "" {
+		p.Percent, _ = strconv.Atoi(m[3])
+		p.Count, _ = strconv.ParseUint(m[4], 10, 64)
+		p.Total, _ = strconv.ParseUint(m[5], 10, 64)
+	} else {
+		p.Count, _ = strconv.ParseUint(m[6], 10, 64)
+	}
+	if m[7] != "" {
+		f, _ := strconv.ParseFloat(m[7], 64)
+		p.Bytes = uint64(f * progressUnits[m[8]])
+	}
+	return p, true
+}
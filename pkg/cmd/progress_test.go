@@ -0,0 +1,67 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"testing"
+)
+
+func TestParseProgress(t *testing.T) {
+	cases := []struct {
+		line string
+		want Progress
+		ok   bool
+	}{{
+		line: "remote: Enumerating objects: 5, done.",
+		want: Progress{Phase: "Enumerating objects", Remote: true, Percent: -1, Count: 5, Done: true},
+		ok:   true,
+	}, {
+		line: "remote: Counting objects:  40% (2/5)",
+		want: Progress{Phase: "Counting objects", Remote: true, Percent: 40, Count: 2, Total: 5},
+		ok:   true,
+	}, {
+		line: "Receiving objects:  45% (450/1000), 1.50 MiB | 2.00 MiB/s",
+		want: Progress{Phase: "Receiving objects", Percent: 45, Count: 450, Total: 1000, Bytes: 3 << 19},
+		ok:   true,
+	}, {
+		line: "Receiving objects: 100% (1000/1000), 512 bytes | 512.00 KiB/s, done.",
+		want: Progress{Phase: "Receiving objects", Percent: 100, Count: 1000, Total: 1000, Bytes: 512, Done: true},
+		ok:   true,
+	}, {
+		line: "Resolving deltas: 100% (3/3), done.",
+		want: Progress{Phase: "Resolving deltas", Percent: 100, Count: 3, Total: 3, Done: true},
+		ok:   true,
+	}, {
+		line: "From https://github.com/kubernetes/git-sync",
+	}, {
+		line: " * branch            main       -> FETCH_HEAD",
+	}, {
+		line: "fatal: couldn't find remote ref nonexistent",
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.line, func(t *testing.T) {
+			got, ok := ParseProgress(tc.line)
+			if ok != tc.ok {
+				t.Fatalf("expected ok=%v, got %v (%+v)", tc.ok, ok, got)
+			}
+			if got != tc.want {
+				t.Errorf("expected %+v, got %+v", tc.want, got)
+			}
+		})
+	}
+}
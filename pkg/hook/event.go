@@ -0,0 +1,75 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+// Types of events, other than syncs.
+const (
+	// Syncing has failed a certain number of times in a row.
+	EventSyncFailure = "sync-failure"
+	// Syncing has succeeded after failing.
+	EventSyncRecovery = "sync-recovery"
+	// git-sync is about to exit because of too many failures.
+	EventExit = "exit"
+)
+
+// Event describes a sync, and is passed to hooks.  Events which describe a
+// change in git-sync's state (e.g. failing to sync) also describe the
+// currently published hash, if any.
+type Event struct {
+	// The git hash that was synced.
+	Hash string `json:"hash"`
+	// The git hash that was synced before this one, or "".
+	PreviousHash string `json:"previousHash"`
+	// The ref which was synced, as specified by the user.
+	Ref string `json:"ref"`
+	// The repo which was synced, with any credentials redacted.
+	Repo string `json:"repo"`
+	// The absolute path of the published symlink.
+	Link string `json:"link"`
+	// The absolute path of the worktree for Hash.
+	Worktree string `json:"worktree"`
+	// The absolute path of the worktree for PreviousHash, or "".
+	PreviousWorktree string `json:"previousWorktree"`
+	// How many times the link has been updated since git-sync started,
+	// including this one.
+	SyncCount int `json:"syncCount"`
+	// The files which changed between PreviousHash and Hash, relative to the
+	// root of the repo.  This is empty if there is no previous hash.
+	ChangedFiles []string `json:"changedFiles"`
+
+	// The fields below are only set for events other than syncs.
+
+	// What happened, one of the Event* values.
+	Type string `json:"type,omitempty"`
+	// When it happened, in RFC 3339 format.
+	Time string `json:"time,omitempty"`
+	// How many syncs in a row have failed (or had failed, for recovery).
+	Failures int `json:"failures,omitempty"`
+	// The class of the last error, e.g. "transient".
+	ErrorClass string `json:"errorClass,omitempty"`
+	// The last error.
+	Error string `json:"error,omitempty"`
+}
+
+// key identifies an event, so it is not delivered more than once.  Syncs are
+// identified by their hash, and other events by their type and time.
+func (ev Event) key() string {
+	if ev.Type == "" {
+		return ev.Hash
+	}
+	return ev.Type + "@" + ev.Time
+}
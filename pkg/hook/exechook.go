@@ -18,8 +18,13 @@ package hook
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
+	"strconv"
+	"sync"
 	"time"
 
 	"k8s.io/git-sync/pkg/cmd"
@@ -27,55 +32,176 @@ import (
 
 // Exechook implements Hook in terms of executing a command.
 type Exechook struct {
+	// Name of this hook, for logs and metrics
+	name string
 	// Runner
 	cmdrunner cmd.Runner
 	// Command to run
 	command string
 	// Command args
 	args []string
-	// How to get a worktree path
-	getWorktree func(hash string) string
 	// Timeout for the command
 	timeout time.Duration
+	// Recent runs, or nil
+	history *OutputHistory
+	// A file in which to write the latest run, or ""
+	outputFile string
 	// Logger
 	log logintf
 }
 
 // NewExechook returns a new Exechook.
-func NewExechook(cmdrunner cmd.Runner, command string, getWorktree func(string) string, args []string, timeout time.Duration, log logintf) *Exechook {
+func NewExechook(name string, cmdrunner cmd.Runner, command string, args []string, timeout time.Duration, log logintf) *Exechook {
 	return &Exechook{
-		cmdrunner:   cmdrunner,
-		command:     command,
-		getWorktree: getWorktree,
-		args:        args,
-		timeout:     timeout,
-		log:         log,
+		name:      name,
+		cmdrunner: cmdrunner,
+		command:   command,
+		args:      args,
+		timeout:   timeout,
+		log:       log,
 	}
 }
 
+// SetOutputHistory sets where the output of each run is recorded.  This must
+// be called before the hook is used.
+func (h *Exechook) SetOutputHistory(history *OutputHistory) {
+	h.history = history
+}
+
+// SetOutputFile sets a file in which the output of the latest run is
+// written, as JSON.  This must be called before the hook is used.
+func (h *Exechook) SetOutputFile(path string) {
+	h.outputFile = path
+}
+
 // Name describes hook, implements Hook.Name.
 func (h *Exechook) Name() string {
-	return "exechook"
+	return h.name
 }
 
-// Do runs exechook.command, implements Hook.Do.
-func (h *Exechook) Do(ctx context.Context, hash string) error {
+// Do runs exechook.command, implements Hook.Do.  The command is run in the
+// event's worktree.
+func (h *Exechook) Do(ctx context.Context, ev Event) error {
 	ctx, cancel := context.WithTimeout(ctx, h.timeout)
 	defer cancel()
 
-	worktreePath := h.getWorktree(hash)
+	eventFile, err := writeEventFile(ev)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(eventFile)
 
 	env := os.Environ()
-	env = append(env, envKV("GITSYNC_HASH", hash))
+	env = append(env, eventEnv(ev)...)
+	env = append(env, envKV("GITSYNC_EVENT_FILE", eventFile))
 
-	h.log.V(0).Info("running exechook", "hash", hash, "command", h.command, "timeout", h.timeout)
-	stdout, stderr, err := h.cmdrunner.Run(ctx, worktreePath, env, h.command, h.args...)
-	if err == nil {
-		h.log.V(1).Info("exechook succeeded", "hash", hash, "stdout", stdout, "stderr", stderr)
+	h.log.V(0).Info("running exechook", "name", h.name, "hash", ev.Hash, "command", h.command, "timeout", h.timeout)
+	var mutex sync.Mutex
+	var stdout, stderr outputTail
+	onLine := func(stream, line string) {
+		h.log.V(0).Info("exechook output", "name", h.name, "hash", ev.Hash, "stream", stream, "line", line)
+		mutex.Lock()
+		defer mutex.Unlock()
+		if stream == "stderr" {
+			stderr.addLine(line)
+		} else {
+			stdout.addLine(line)
+		}
+	}
+	start := time.Now()
+	_, _, err = h.cmdrunner.RunWithOutput(ctx, ev.Worktree, env, onLine, h.command, h.args...)
+	// The output is served and written to a file, so it must not leak
+	// secrets (the logged lines are redacted by the logger).
+	run := ExecRun{
+		Hash:            ev.Hash,
+		Start:           start,
+		DurationSeconds: time.Since(start).Seconds(),
+		ExitCode:        exitCode(err),
+		Stdout:          h.cmdrunner.Redact(stdout.String()),
+		Stderr:          h.cmdrunner.Redact(stderr.String()),
+		Truncated:       stdout.truncated || stderr.truncated,
 	}
+	if err != nil {
+		run.Error = err.Error()
+	} else {
+		h.log.V(1).Info("exechook succeeded", "name", h.name, "hash", ev.Hash, "duration", time.Since(start))
+	}
+	h.record(run)
 	return err
 }
 
+// record saves a run in the history and the output file, if configured.
+func (h *Exechook) record(run ExecRun) {
+	if h.history != nil {
+		h.history.add(run)
+	}
+	if h.outputFile != "" {
+		jb, err := json.MarshalIndent(run, "", "  ")
+		if err == nil {
+			err = writeFileAtomic(h.outputFile, append(jb, '\n'))
+		}
+		if err != nil {
+			h.log.Error(err, "can't write exechook output", "name", h.name, "path", h.outputFile)
+		}
+	}
+}
+
+// exitCode returns the exit code of a command which returned err.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var ee *exec.ExitError
+	if errors.As(err, &ee) {
+		return ee.ExitCode()
+	}
+	return -1
+}
+
+// eventEnv returns environment variables describing an event.  The list of
+// changed files is only available in the event file, since it can be large.
+func eventEnv(ev Event) []string {
+	env := []string{
+		envKV("GITSYNC_HASH", ev.Hash),
+		envKV("GITSYNC_PREVIOUS_HASH", ev.PreviousHash),
+		envKV("GITSYNC_REF", ev.Ref),
+		envKV("GITSYNC_REPO", ev.Repo),
+		envKV("GITSYNC_LINK", ev.Link),
+		envKV("GITSYNC_WORKTREE", ev.Worktree),
+		envKV("GITSYNC_PREVIOUS_WORKTREE", ev.PreviousWorktree),
+		envKV("GITSYNC_SYNC_COUNT", strconv.Itoa(ev.SyncCount)),
+	}
+	if ev.Type != "" {
+		env = append(env,
+			envKV("GITSYNC_EVENT_TYPE", ev.Type),
+			envKV("GITSYNC_EVENT_TIME", ev.Time),
+			envKV("GITSYNC_FAILURES", strconv.Itoa(ev.Failures)),
+			envKV("GITSYNC_ERROR_CLASS", ev.ErrorClass),
+			envKV("GITSYNC_ERROR", ev.Error),
+		)
+	}
+	return env
+}
+
+// writeEventFile writes an event, as JSON, to a new temporary file and
+// returns its path.  The caller is responsible for removing it.
+func writeEventFile(ev Event) (string, error) {
+	jb, err := json.Marshal(ev)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp("", "git-sync-event-*.json")
+	if err != nil {
+		return "", fmt.Errorf("can't create event file: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(jb); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("can't write event file: %w", err)
+	}
+	return f.Name(), nil
+}
+
 func envKV(k, v string) string {
 	return fmt.Sprintf("%s=%s", k, v)
 }
@@ -19,8 +19,12 @@ package hook
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 
@@ -33,10 +37,60 @@ var (
 		Name: "git_sync_hook_run_count_total",
 		Help: "How many hook runs completed, partitioned by name and state (success, error)",
 	}, []string{"name", "status"})
+
+	hookHealthy = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "git_sync_hook_healthy",
+		Help: "Whether a hook is healthy (1) or its circuit is open after repeated failures (0), partitioned by name",
+	}, []string{"name"})
+
+	hookTerminalFailureCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "git_sync_hook_terminal_failure_count_total",
+		Help: "How many times a hook gave up on a hash, partitioned by name",
+	}, []string{"name"})
+
+	hookQueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "git_sync_hook_queue_depth",
+		Help: "How many events are queued for a hook, including the one in progress, partitioned by name",
+	}, []string{"name"})
+
+	hookQueueDropCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "git_sync_hook_queue_drop_count_total",
+		Help: "How many events were dropped because a hook's queue was full, partitioned by name",
+	}, []string{"name"})
 )
 
 func init() {
 	prometheus.MustRegister(hookRunCount)
+	prometheus.MustRegister(hookHealthy)
+	prometheus.MustRegister(hookTerminalFailureCount)
+	prometheus.MustRegister(hookQueueDepth)
+	prometheus.MustRegister(hookQueueDropCount)
+}
+
+// What to do when a hook's queue is full.
+const (
+	// Drop the oldest queued event, which may be in progress.
+	QueueDropOldest = "drop-oldest"
+	// Drop the new event.
+	QueueDropNewest = "drop-newest"
+	// Wait for space in the queue.
+	QueueBlock = "block"
+)
+
+// errDependencyGaveUp is returned when a dependency gave up on a hash.
+var errDependencyGaveUp = errors.New("dependency gave up")
+
+// TerminalFailure describes a hook which gave up on a hash, after exhausting
+// its attempts.
+type TerminalFailure struct {
+	// The name of the hook.
+	Name string
+	// The event which the hook gave up on.
+	Event Event
+	// How many times the hook was tried for this hash.
+	Attempts int
+	// The last error.
+	Err error
 }
 
 // Hook describes a single hook of some sort, which can be run by HookRunner.
@@ -44,44 +98,124 @@ type Hook interface {
 	// Describes hook
 	Name() string
 	// Function that called by HookRunner
-	Do(ctx context.Context, hash string) error
+	Do(ctx context.Context, ev Event) error
 }
 
 type hookData struct {
 	ch    chan struct{}
 	mutex sync.Mutex
-	hash  string
+	// The latest event, or in queue mode the last event which was done.
+	ev Event
+
+	// If queueSize is greater than 0, every event is queued, rather than
+	// only keeping the latest.
+	queue     []Event
+	queueSize int
+	overflow  string
+	space     *sync.Cond
 }
 
-// NewHookData returns a new HookData.
+// NewHookData returns a new HookData, which only keeps the latest event.
 func NewHookData() *hookData {
 	return &hookData{
 		ch: make(chan struct{}, 1),
 	}
 }
 
+// NewHookQueue returns a new HookData, which keeps up to size events, in
+// order.  The overflow argument must be one of QueueDropOldest,
+// QueueDropNewest, or QueueBlock.
+func NewHookQueue(size int, overflow string) *hookData {
+	d := &hookData{
+		ch:        make(chan struct{}, 1),
+		queueSize: size,
+		overflow:  overflow,
+	}
+	d.space = sync.NewCond(&d.mutex)
+	return d
+}
+
 func (d *hookData) events() chan struct{} {
 	return d.ch
 }
 
-func (d *hookData) get() string {
+// get returns the event to be processed: the latest one, or in queue mode
+// the oldest one which is not done.
+func (d *hookData) get() Event {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
-	return d.hash
+	if len(d.queue) > 0 {
+		return d.queue[0]
+	}
+	return d.ev
+}
+
+// send records a new event.  In queue mode, if the queue is full, an event
+// might be dropped, in which case it is returned.
+func (d *hookData) send(ev Event) (Event, bool) {
+	dropped, wasDropped := d.add(ev)
+
+	// Non-blocking write.  If the channel is full, the consumer will see the
+	// newest value.  If the channel was not full, the consumer will get another
+	// event.
+	d.signal()
+	return dropped, wasDropped
 }
 
-func (d *hookData) set(newHash string) {
+func (d *hookData) add(ev Event) (Event, bool) {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
-	d.hash = newHash
+
+	if d.queueSize == 0 {
+		d.ev = ev
+		return Event{}, false
+	}
+
+	if d.overflow == QueueBlock {
+		for len(d.queue) >= d.queueSize {
+			d.space.Wait()
+		}
+	}
+	if len(d.queue) >= d.queueSize {
+		if d.overflow == QueueDropNewest {
+			return ev, true
+		}
+		dropped := d.queue[0]
+		d.queue = append(d.queue[1:], ev)
+		return dropped, true
+	}
+	d.queue = append(d.queue, ev)
+	return Event{}, false
 }
 
-func (d *hookData) send(newHash string) {
-	d.set(newHash)
+// done marks an event as processed.  In queue mode, this removes it from
+// the queue, and signals the consumer if there are more events.
+func (d *hookData) done(ev Event) {
+	d.mutex.Lock()
+	if len(d.queue) == 0 || d.queue[0].key() != ev.key() {
+		d.mutex.Unlock()
+		return
+	}
+	d.ev = d.queue[0]
+	d.queue = d.queue[1:]
+	remaining := len(d.queue)
+	d.space.Broadcast()
+	d.mutex.Unlock()
 
-	// Non-blocking write.  If the channel is full, the consumer will see the
-	// newest value.  If the channel was not full, the consumer will get another
-	// event.
+	if remaining > 0 {
+		d.signal()
+	}
+}
+
+// depth returns how many events are queued.  This is always 0 when not in
+// queue mode.
+func (d *hookData) depth() int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return len(d.queue)
+}
+
+func (d *hookData) signal() {
 	select {
 	case d.ch <- struct{}{}:
 	default:
@@ -90,7 +224,7 @@ func (d *hookData) send(newHash string) {
 
 // NewHookRunner returns a new HookRunner.
 func NewHookRunner(hook Hook, backoff time.Duration, data *hookData, log logintf, oneTime bool, async bool) *HookRunner {
-	hr := &HookRunner{hook: hook, backoff: backoff, data: data, log: log, oneTime: oneTime, async: async}
+	hr := &HookRunner{hook: hook, backoff: backoff, maxBackoff: backoff, data: data, log: log, oneTime: oneTime, async: async, depWake: make(chan struct{}, 1)}
 	if oneTime || !async {
 		hr.result = make(chan bool, 1)
 	}
@@ -101,8 +235,22 @@ func NewHookRunner(hook Hook, backoff time.Duration, data *hookData, log logintf
 type HookRunner struct {
 	// Hook to run and check
 	hook Hook
-	// Backoff for failed hooks
-	backoff time.Duration
+	// Backoff for failed hooks.  This doubles on each failure for a given
+	// hash, up to maxBackoff.
+	backoff    time.Duration
+	maxBackoff time.Duration
+	// How many times to try a hash before giving up, or 0 for no limit.
+	maxAttempts int
+	// How many consecutive failures open the circuit, or 0 for never.
+	failureThreshold int
+	// Called when this hook gives up on a hash.
+	terminalFailureFns []func(TerminalFailure)
+	// Called when this hook's circuit opens.
+	circuitOpenFns []func(name string, err error)
+	// Called when this hook succeeds after giving up or opening its circuit.
+	recoveryFns []func(name string)
+	// A file in which to persist the last hash this hook completed, or "".
+	stateFile string
 	// Holds the data as it crosses from producer to consumer.
 	data *hookData
 	// Logger
@@ -114,6 +262,29 @@ type HookRunner struct {
 	oneTime bool
 	// Bool for whether this is an async hook or not.
 	async bool
+	// Hooks which must complete successfully for a hash before this hook
+	// will run for that hash.
+	deps []*HookRunner
+	// Hooks which depend on this hook.
+	dependents []*HookRunner
+	// Signalled when any of deps changes state.
+	depWake chan struct{}
+	// Protects the fields below.
+	doneMutex sync.Mutex
+	// The last hash for which this hook completed successfully.  For
+	// events other than syncs, this is the event's key.
+	doneHash string
+	// The last hash (or key) on which this hook gave up.
+	failedHash string
+	// Whether this hook has given up (only in one-time mode).
+	gaveUp bool
+	// How many times this hook has failed in a row, across all hashes.
+	consecutiveFailures int
+	// Whether the circuit is open, i.e. the hook is considered unhealthy.
+	circuitOpen bool
+	// Whether this hook has given up or opened its circuit since it last
+	// succeeded.
+	needsRecovery bool
 }
 
 // Just the logr methods we need in this package.
@@ -123,13 +294,136 @@ type logintf interface {
 	V(level int) logr.Logger
 }
 
-// Send sends hash to hookdata.
-func (r *HookRunner) Send(hash string) error {
-	r.data.send(hash)
+// Name returns the name of the hook being run.
+func (r *HookRunner) Name() string {
+	return r.hook.Name()
+}
+
+// IsAsync returns true if Send does not wait for the hook to complete.
+func (r *HookRunner) IsAsync() bool {
+	return r.async
+}
+
+// SetRetryPolicy configures how failed hooks are retried.  The backoff
+// between attempts doubles on each failure for a given hash, up to
+// maxBackoff.  If maxAttempts is greater than 0, the hook gives up on a hash
+// after that many attempts.  If failureThreshold is greater than 0, the
+// circuit opens after that many consecutive failures: the hook is marked
+// unhealthy and is retried at maxBackoff until it succeeds.  This must be
+// called before Run.
+func (r *HookRunner) SetRetryPolicy(maxBackoff time.Duration, maxAttempts, failureThreshold int) {
+	if maxBackoff < r.backoff {
+		maxBackoff = r.backoff
+	}
+	r.maxBackoff = maxBackoff
+	r.maxAttempts = maxAttempts
+	r.failureThreshold = failureThreshold
+}
+
+// OnTerminalFailure registers a function to be called when this hook gives
+// up on a hash.  This must be called before Run.
+func (r *HookRunner) OnTerminalFailure(fn func(TerminalFailure)) {
+	r.terminalFailureFns = append(r.terminalFailureFns, fn)
+}
+
+// OnCircuitOpen registers a function to be called when this hook's circuit
+// opens.  This must be called before Run.
+func (r *HookRunner) OnCircuitOpen(fn func(name string, err error)) {
+	r.circuitOpenFns = append(r.circuitOpenFns, fn)
+}
+
+// OnRecovery registers a function to be called when this hook succeeds after
+// it gave up on a hash or its circuit opened.  This must be called before
+// Run.
+func (r *HookRunner) OnRecovery(fn func(name string)) {
+	r.recoveryFns = append(r.recoveryFns, fn)
+}
+
+// Healthy returns false if this hook's circuit is open.
+func (r *HookRunner) Healthy() bool {
+	r.doneMutex.Lock()
+	defer r.doneMutex.Unlock()
+	return !r.circuitOpen
+}
+
+// SetStateFile sets a file in which the last hash this hook completed is
+// persisted, so that the hook is not run again for the same hash after a
+// restart.  If the file exists, the hash in it is treated as already done.
+// This must be called before Run.
+func (r *HookRunner) SetStateFile(path string) error {
+	r.stateFile = path
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("can't read hook state: %w", err)
+	}
+	hash := strings.TrimSpace(string(b))
+	r.log.V(1).Info("loaded hook state", "name", r.hook.Name(), "hash", hash)
+	r.doneMutex.Lock()
+	r.doneHash = hash
+	r.doneMutex.Unlock()
+	return nil
+}
+
+// saveState persists hash to the state file, if there is one.  Failing to
+// do so is not fatal: at worst, the hook runs again after a restart.
+func (r *HookRunner) saveState(hash string) {
+	if r.stateFile == "" {
+		return
+	}
+	if err := writeFileAtomic(r.stateFile, []byte(hash+"\n")); err != nil {
+		r.log.Error(err, "can't save hook state", "name", r.hook.Name(), "path", r.stateFile)
+	}
+}
+
+// writeFileAtomic writes data to a temporary file and renames it to path,
+// so readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// SetDependencies sets the hooks which must complete successfully for a hash
+// before this hook will run for that hash.  This must be called before Run.
+func (r *HookRunner) SetDependencies(deps []*HookRunner) {
+	r.deps = deps
+	for _, dep := range deps {
+		dep.dependents = append(dep.dependents, r)
+	}
+}
+
+// Send sends an event to hookdata.
+func (r *HookRunner) Send(ev Event) error {
+	if dropped, ok := r.data.send(ev); ok {
+		r.log.Error(nil, "hook queue is full, dropped event", "name", r.hook.Name(), "hash", dropped.Hash)
+		hookQueueDropCount.WithLabelValues(r.hook.Name()).Inc()
+	}
+	r.updateQueueMetric()
 	if !r.async {
-		r.log.V(1).Info("waiting for completion", "hash", hash, "name", r.hook.Name())
+		r.log.V(1).Info("waiting for completion", "hash", ev.Hash, "name", r.hook.Name())
 		err := r.WaitForCompletion()
-		r.log.V(1).Info("hook completed", "hash", hash, "err", err, "name", r.hook.Name())
+		r.log.V(1).Info("hook completed", "hash", ev.Hash, "err", err, "name", r.hook.Name())
 		if err != nil {
 			return err
 		}
@@ -139,7 +433,12 @@ func (r *HookRunner) Send(hash string) error {
 
 // Run waits for trigger events from the channel, and run hook when triggered.
 func (r *HookRunner) Run(ctx context.Context) {
-	var lastHash string
+	lastHash, _, _ := r.doneState() // the last hash completed or given up on
+	lastFailed := false             // whether lastHash was given up on
+	var attemptHash string
+	var attempts int
+
+	hookHealthy.WithLabelValues(r.hook.Name()).Set(1)
 
 	// Wait for trigger from hookData.Send
 	for range r.data.events() {
@@ -147,21 +446,76 @@ func (r *HookRunner) Run(ctx context.Context) {
 		for {
 			// Always get the latest value, in case we fail-and-retry and the
 			// value changed in the meantime.  This means that we might not send
-			// every single hash.
-			hash := r.data.get()
-			if hash == lastHash {
+			// every single hash, unless the hook has a queue.
+			ev := r.data.get()
+			hash := ev.Hash
+			// Events other than syncs might have the same hash.
+			key := ev.key()
+			if key == lastHash {
+				// Already done, e.g. before a restart.  Anyone waiting for
+				// this hash can proceed.
+				r.log.V(2).Info("hook already ran for hash", "hash", hash, "name", r.hook.Name(), "failed", lastFailed)
+				r.finish(ev)
+				r.sendResult(!lastFailed)
 				break
 			}
+			if key != attemptHash {
+				attemptHash = key
+				attempts = 0
+			}
 
-			if err := r.hook.Do(ctx, hash); err != nil {
-				r.log.Error(err, "hook failed", "hash", hash, "retry", r.backoff)
+			// Wait for any dependencies to finish this hash.
+			ready, err := r.waitForDeps(ctx, key)
+			if errors.Is(err, errDependencyGaveUp) {
+				// There's no point in retrying until there's a new hash.
+				r.giveUp(ev, attempts, err)
+				lastHash = key
+				lastFailed = true
+				r.finish(ev)
+				r.sendResult(false)
+				break
+			} else if err != nil {
+				r.log.Error(err, "hook dependency failed", "hash", hash, "name", r.hook.Name())
+				updateHookRunCountMetric(r.hook.Name(), "error")
+				r.sendResult(false)
+				r.sleep(ctx, r.backoff)
+				continue
+			} else if !ready {
+				// The hash changed while we were waiting.
+				continue
+			}
+
+			attempts++
+			if err := r.hook.Do(ctx, ev); err != nil {
 				updateHookRunCountMetric(r.hook.Name(), "error")
+				open := r.recordFailure(err)
+				if r.maxAttempts > 0 && attempts >= r.maxAttempts {
+					r.giveUp(ev, attempts, err)
+					lastHash = key
+					lastFailed = true
+					r.finish(ev)
+					r.sendResult(false)
+					break
+				}
+				delay := r.retryDelay(attempts, open)
+				if open {
+					// Don't spam the logs for a hook which is known to be
+					// broken.
+					r.log.V(1).Info("hook failed", "hash", hash, "name", r.hook.Name(), "attempts", attempts, "retry", delay, "error", err.Error())
+				} else {
+					r.log.Error(err, "hook failed", "hash", hash, "name", r.hook.Name(), "attempts", attempts, "retry", delay)
+				}
 				// don't want to sleep unnecessarily terminating anyways
 				r.sendResult(false)
-				time.Sleep(r.backoff)
+				r.sleep(ctx, delay)
 			} else {
 				updateHookRunCountMetric(r.hook.Name(), "success")
-				lastHash = hash
+				r.recordSuccess()
+				lastHash = key
+				lastFailed = false
+				r.saveState(key)
+				r.setDone(key)
+				r.finish(ev)
 				r.sendResult(true)
 				break
 			}
@@ -169,9 +523,184 @@ func (r *HookRunner) Run(ctx context.Context) {
 	}
 }
 
+// finish marks an event as processed, so the next queued event (if any) can
+// be run.
+func (r *HookRunner) finish(ev Event) {
+	r.data.done(ev)
+	r.updateQueueMetric()
+}
+
+func (r *HookRunner) updateQueueMetric() {
+	hookQueueDepth.WithLabelValues(r.hook.Name()).Set(float64(r.data.depth()))
+}
+
+// retryDelay returns how long to wait after the specified number of failed
+// attempts for a hash.
+func (r *HookRunner) retryDelay(attempts int, circuitOpen bool) time.Duration {
+	if circuitOpen {
+		return r.maxBackoff
+	}
+	delay := r.backoff
+	for i := 1; i < attempts && delay < r.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > r.maxBackoff {
+		delay = r.maxBackoff
+	}
+	return delay
+}
+
+// sleep waits for the specified duration or until ctx is done.
+func (r *HookRunner) sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+// recordFailure counts a failed attempt, and opens the circuit if there have
+// been too many consecutive failures.  It returns true if the circuit is
+// open.
+func (r *HookRunner) recordFailure(err error) bool {
+	r.doneMutex.Lock()
+	r.consecutiveFailures++
+	opened := false
+	if r.failureThreshold > 0 && r.consecutiveFailures >= r.failureThreshold && !r.circuitOpen {
+		r.circuitOpen = true
+		r.needsRecovery = true
+		opened = true
+		hookHealthy.WithLabelValues(r.hook.Name()).Set(0)
+		r.log.Error(err, "hook is unhealthy, opening circuit", "name", r.hook.Name(), "failures", r.consecutiveFailures, "retry", r.maxBackoff)
+	}
+	open := r.circuitOpen
+	r.doneMutex.Unlock()
+
+	if opened {
+		for _, fn := range r.circuitOpenFns {
+			fn(r.hook.Name(), err)
+		}
+	}
+	return open
+}
+
+// recordSuccess resets the failure count, and closes the circuit if it was
+// open.
+func (r *HookRunner) recordSuccess() {
+	r.doneMutex.Lock()
+	r.consecutiveFailures = 0
+	if r.circuitOpen {
+		r.circuitOpen = false
+		hookHealthy.WithLabelValues(r.hook.Name()).Set(1)
+		r.log.V(0).Info("hook is healthy, closing circuit", "name", r.hook.Name())
+	}
+	recovered := r.needsRecovery
+	r.needsRecovery = false
+	r.doneMutex.Unlock()
+
+	if recovered {
+		for _, fn := range r.recoveryFns {
+			fn(r.hook.Name())
+		}
+	}
+}
+
+// giveUp records that this hook will not try this event's hash again, and
+// notifies anyone who is interested.
+func (r *HookRunner) giveUp(ev Event, attempts int, err error) {
+	r.log.Error(err, "giving up on hook", "hash", ev.Hash, "name", r.hook.Name(), "attempts", attempts)
+	hookTerminalFailureCount.WithLabelValues(r.hook.Name()).Inc()
+
+	r.doneMutex.Lock()
+	r.failedHash = ev.key()
+	r.needsRecovery = true
+	r.doneMutex.Unlock()
+	r.wakeDependents()
+
+	tf := TerminalFailure{Name: r.hook.Name(), Event: ev, Attempts: attempts, Err: err}
+	for _, fn := range r.terminalFailureFns {
+		fn(tf)
+	}
+}
+
+// setDone records that this hook completed successfully for hash, and wakes
+// any hooks which depend on it.
+func (r *HookRunner) setDone(hash string) {
+	r.doneMutex.Lock()
+	r.doneHash = hash
+	r.doneMutex.Unlock()
+	r.wakeDependents()
+}
+
+// setGaveUp records that this hook will not run again, and wakes any hooks
+// which depend on it.
+func (r *HookRunner) setGaveUp() {
+	r.doneMutex.Lock()
+	r.gaveUp = true
+	r.doneMutex.Unlock()
+	r.wakeDependents()
+}
+
+func (r *HookRunner) wakeDependents() {
+	for _, d := range r.dependents {
+		// Non-blocking write.  If the channel is full, the dependent has not
+		// yet re-checked its dependencies and will see the newest state.
+		select {
+		case d.depWake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// doneState returns the last hash this hook completed, the last hash it gave
+// up on, and whether it has given up entirely.
+func (r *HookRunner) doneState() (string, string, bool) {
+	r.doneMutex.Lock()
+	defer r.doneMutex.Unlock()
+	return r.doneHash, r.failedHash, r.gaveUp
+}
+
+// waitForDeps blocks until all of this hook's dependencies have completed
+// the specified hash.  If the hash for this hook changes while waiting, this
+// returns false.  If a dependency gives up, this returns an error, which
+// wraps errDependencyGaveUp if the dependency gave up on this hash.
+func (r *HookRunner) waitForDeps(ctx context.Context, hash string) (bool, error) {
+	for {
+		pending := 0
+		for _, dep := range r.deps {
+			doneHash, failedHash, gaveUp := dep.doneState()
+			if gaveUp {
+				return false, fmt.Errorf("dependency %q failed", dep.Name())
+			}
+			if failedHash == hash && doneHash != hash {
+				return false, fmt.Errorf("%w: %q", errDependencyGaveUp, dep.Name())
+			}
+			if doneHash != hash {
+				pending++
+			}
+		}
+		if pending == 0 {
+			return true, nil
+		}
+		r.log.V(2).Info("waiting for hook dependencies", "hash", hash, "name", r.hook.Name(), "pending", pending)
+		select {
+		case <-r.depWake:
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+		if r.data.get().key() != hash {
+			return false, nil
+		}
+	}
+}
+
 func (r *HookRunner) sendResult(completedSuccessfully bool) {
 	// if onetime is true, we send the result then exit
 	if r.oneTime {
+		if !completedSuccessfully {
+			r.setGaveUp()
+		}
 		r.result <- completedSuccessfully
 		close(r.result)
 		runtime.Goexit()
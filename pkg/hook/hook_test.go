@@ -17,8 +17,17 @@ limitations under the License.
 package hook
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"k8s.io/git-sync/pkg/logging"
 )
 
 const (
@@ -30,11 +39,11 @@ func TestHookData(t *testing.T) {
 	t.Run("hook consumes first hash value", func(t *testing.T) {
 		hd := NewHookData()
 
-		hd.send(hash1)
+		hd.send(Event{Hash: hash1})
 
 		<-hd.events()
 
-		hash := hd.get()
+		hash := hd.get().Hash
 		if hash1 != hash {
 			t.Fatalf("expected hash %s but got %s", hash1, hash)
 		}
@@ -45,13 +54,13 @@ func TestHookData(t *testing.T) {
 
 		for i := range 10 {
 			h := fmt.Sprintf("111111111111111111111111111111111111111%d", i)
-			hd.send(h)
+			hd.send(Event{Hash: h})
 		}
-		hd.send(hash2)
+		hd.send(Event{Hash: hash2})
 
 		<-hd.events()
 
-		hash := hd.get()
+		hash := hd.get().Hash
 		if hash2 != hash {
 			t.Fatalf("expected hash %s but got %s", hash2, hash)
 		}
@@ -61,20 +70,409 @@ func TestHookData(t *testing.T) {
 		hd := NewHookData()
 		events := hd.events()
 
-		hd.send(hash1)
+		hd.send(Event{Hash: hash1})
 		<-events
 
-		hash := hd.get()
+		hash := hd.get().Hash
 		if hash1 != hash {
 			t.Fatalf("expected hash %s but got %s", hash1, hash)
 		}
 
-		hd.send(hash1)
+		hd.send(Event{Hash: hash1})
 		<-events
 
-		hash = hd.get()
+		hash = hd.get().Hash
 		if hash1 != hash {
 			t.Fatalf("expected hash %s but got %s", hash1, hash)
 		}
 	})
 }
+
+func TestHookQueue(t *testing.T) {
+	hashes := func(hd *hookData) []string {
+		var ret []string
+		for hd.depth() > 0 {
+			ev := hd.get()
+			ret = append(ret, ev.Hash)
+			hd.done(ev)
+		}
+		return ret
+	}
+
+	t.Run("every hash in order", func(t *testing.T) {
+		hd := NewHookQueue(10, QueueDropOldest)
+		for _, h := range []string{"a", "b", "c"} {
+			if _, dropped := hd.send(Event{Hash: h}); dropped {
+				t.Fatalf("unexpected drop of %s", h)
+			}
+		}
+		if want, got := []string{"a", "b", "c"}, hashes(hd); !reflect.DeepEqual(want, got) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("drop oldest", func(t *testing.T) {
+		hd := NewHookQueue(2, QueueDropOldest)
+		hd.send(Event{Hash: "a"})
+		hd.send(Event{Hash: "b"})
+		dropped, ok := hd.send(Event{Hash: "c"})
+		if !ok || dropped.Hash != "a" {
+			t.Errorf("expected a to be dropped, got %q (%v)", dropped.Hash, ok)
+		}
+		if want, got := []string{"b", "c"}, hashes(hd); !reflect.DeepEqual(want, got) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("drop newest", func(t *testing.T) {
+		hd := NewHookQueue(2, QueueDropNewest)
+		hd.send(Event{Hash: "a"})
+		hd.send(Event{Hash: "b"})
+		dropped, ok := hd.send(Event{Hash: "c"})
+		if !ok || dropped.Hash != "c" {
+			t.Errorf("expected c to be dropped, got %q (%v)", dropped.Hash, ok)
+		}
+		if want, got := []string{"a", "b"}, hashes(hd); !reflect.DeepEqual(want, got) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("block", func(t *testing.T) {
+		hd := NewHookQueue(1, QueueBlock)
+		hd.send(Event{Hash: "a"})
+		sent := make(chan struct{})
+		go func() {
+			hd.send(Event{Hash: "b"})
+			close(sent)
+		}()
+		select {
+		case <-sent:
+			t.Fatalf("send did not block on a full queue")
+		case <-time.After(100 * time.Millisecond):
+		}
+		hd.done(hd.get())
+		select {
+		case <-sent:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("send did not unblock")
+		}
+		if want, got := []string{"b"}, hashes(hd); !reflect.DeepEqual(want, got) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("done signals remaining events", func(t *testing.T) {
+		hd := NewHookQueue(10, QueueDropOldest)
+		hd.send(Event{Hash: "a"})
+		hd.send(Event{Hash: "b"})
+		<-hd.events()
+		hd.done(hd.get())
+		select {
+		case <-hd.events():
+		default:
+			t.Fatalf("expected a signal for the remaining event")
+		}
+		if hash := hd.get().Hash; hash != "b" {
+			t.Errorf("expected b, got %s", hash)
+		}
+	})
+}
+
+// fakeHook records the order in which hooks run.
+type fakeHook struct {
+	name string
+	log  chan<- string
+}
+
+func (h *fakeHook) Name() string {
+	return h.name
+}
+
+func (h *fakeHook) Do(ctx context.Context, ev Event) error {
+	h.log <- h.name + ":" + ev.Hash
+	return nil
+}
+
+func TestHookRunnerDependencies(t *testing.T) {
+	l := logging.New("", "", 0)
+	ranCh := make(chan string, 10)
+
+	first := NewHookRunner(&fakeHook{name: "first", log: ranCh}, time.Second, NewHookData(), l, false, true)
+	second := NewHookRunner(&fakeHook{name: "second", log: ranCh}, time.Second, NewHookData(), l, false, true)
+	second.SetDependencies([]*HookRunner{first})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go first.Run(ctx)
+	go second.Run(ctx)
+
+	// Trigger the dependent first, to make sure it waits.
+	if err := second.Send(Event{Hash: hash1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	select {
+	case ran := <-ranCh:
+		t.Fatalf("hook ran before its dependency: %s", ran)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if err := first.Send(Event{Hash: hash1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, want := range []string{"first:" + hash1, "second:" + hash1} {
+		select {
+		case got := <-ranCh:
+			if got != want {
+				t.Errorf("expected %q, got %q", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+}
+
+// failingHook fails until it has been called a certain number of times.
+type failingHook struct {
+	name  string
+	fails int32
+	calls atomic.Int32
+}
+
+func (h *failingHook) Name() string {
+	return h.name
+}
+
+func (h *failingHook) Do(ctx context.Context, ev Event) error {
+	if n := h.calls.Add(1); n <= h.fails {
+		return fmt.Errorf("failure %d", n)
+	}
+	return nil
+}
+
+func TestHookRunnerRetryDelay(t *testing.T) {
+	r := NewHookRunner(&fakeHook{name: "test"}, time.Second, NewHookData(), logging.New("", "", 0), false, true)
+	r.SetRetryPolicy(10*time.Second, 0, 0)
+
+	testCases := []struct {
+		attempts int
+		open     bool
+		expected time.Duration
+	}{
+		{1, false, 1 * time.Second},
+		{2, false, 2 * time.Second},
+		{3, false, 4 * time.Second},
+		{4, false, 8 * time.Second},
+		{5, false, 10 * time.Second},
+		{100, false, 10 * time.Second},
+		{1, true, 10 * time.Second},
+	}
+	for _, tc := range testCases {
+		if got := r.retryDelay(tc.attempts, tc.open); got != tc.expected {
+			t.Errorf("attempts %d, open %v: expected %v, got %v", tc.attempts, tc.open, tc.expected, got)
+		}
+	}
+
+	// The default is a fixed backoff.
+	r = NewHookRunner(&fakeHook{name: "test"}, time.Second, NewHookData(), logging.New("", "", 0), false, true)
+	if got := r.retryDelay(5, false); got != time.Second {
+		t.Errorf("expected fixed backoff, got %v", got)
+	}
+}
+
+func TestHookRunnerGiveUp(t *testing.T) {
+	l := logging.New("", "", 0)
+	ranCh := make(chan string, 10)
+
+	failing := &failingHook{name: "failing", fails: 1000}
+	first := NewHookRunner(failing, time.Millisecond, NewHookData(), l, false, true)
+	first.SetRetryPolicy(time.Millisecond, 3, 0)
+	second := NewHookRunner(&fakeHook{name: "second", log: ranCh}, time.Millisecond, NewHookData(), l, false, true)
+	second.SetDependencies([]*HookRunner{first})
+
+	failures := make(chan TerminalFailure, 10)
+	first.OnTerminalFailure(func(tf TerminalFailure) { failures <- tf })
+	second.OnTerminalFailure(func(tf TerminalFailure) { failures <- tf })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go first.Run(ctx)
+	go second.Run(ctx)
+
+	_ = second.Send(Event{Hash: hash1})
+	_ = first.Send(Event{Hash: hash1})
+
+	got := map[string]TerminalFailure{}
+	for len(got) < 2 {
+		select {
+		case tf := <-failures:
+			got[tf.Name] = tf
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for terminal failures, got %v", got)
+		}
+	}
+	if tf := got["failing"]; tf.Event.Hash != hash1 || tf.Attempts != 3 || tf.Err == nil {
+		t.Errorf("unexpected terminal failure: %+v", tf)
+	}
+	if n := failing.calls.Load(); n != 3 {
+		t.Errorf("expected 3 attempts, got %d", n)
+	}
+	if tf := got["second"]; tf.Event.Hash != hash1 || !errors.Is(tf.Err, errDependencyGaveUp) {
+		t.Errorf("unexpected terminal failure for dependent: %+v", tf)
+	}
+	select {
+	case ran := <-ranCh:
+		t.Errorf("dependent hook ran: %s", ran)
+	default:
+	}
+}
+
+func TestHookRunnerCircuit(t *testing.T) {
+	l := logging.New("", "", 0)
+
+	failing := &failingHook{name: "failing", fails: 3}
+	r := NewHookRunner(failing, time.Millisecond, NewHookData(), l, false, false)
+	r.SetRetryPolicy(time.Millisecond, 0, 2)
+	var opened, recovered atomic.Int32
+	r.OnCircuitOpen(func(name string, err error) { opened.Add(1) })
+	r.OnRecovery(func(name string) { recovered.Add(1) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx)
+
+	r.data.send(Event{Hash: hash1})
+	for i := 0; i < 3; i++ {
+		if err := r.WaitForCompletion(); err == nil {
+			t.Fatalf("attempt %d: expected failure", i+1)
+		}
+		if want, got := i+1 < 2, r.Healthy(); want != got {
+			t.Errorf("attempt %d: expected healthy %v, got %v", i+1, want, got)
+		}
+	}
+	if n := opened.Load(); n != 1 {
+		t.Errorf("expected the circuit to open once, got %d", n)
+	}
+	if err := r.WaitForCompletion(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !r.Healthy() {
+		t.Errorf("expected hook to be healthy after success")
+	}
+	if n := recovered.Load(); n != 1 {
+		t.Errorf("expected one recovery, got %d", n)
+	}
+}
+
+func TestHookRunnerStateFile(t *testing.T) {
+	l := logging.New("", "", 0)
+	stateFile := filepath.Join(t.TempDir(), "state", "test")
+	ranCh := make(chan string, 10)
+
+	// newRunner simulates a restart of git-sync.
+	newRunner := func(ctx context.Context) *HookRunner {
+		r := NewHookRunner(&fakeHook{name: "test", log: ranCh}, time.Second, NewHookData(), l, false, false)
+		if err := r.SetStateFile(stateFile); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		go r.Run(ctx)
+		return r
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := newRunner(ctx)
+	if err := r.Send(Event{Hash: hash1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := <-ranCh; got != "test:"+hash1 {
+		t.Errorf("unexpected hook run: %q", got)
+	}
+	if b, err := os.ReadFile(stateFile); err != nil {
+		t.Fatalf("can't read state file: %v", err)
+	} else if string(b) != hash1+"\n" {
+		t.Errorf("unexpected state: %q", b)
+	}
+	cancel()
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	r = newRunner(ctx)
+
+	// The same hash should not be delivered again.
+	if err := r.Send(Event{Hash: hash1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ranCh) != 0 {
+		t.Errorf("hook ran again for the same hash: %q", <-ranCh)
+	}
+
+	// A new hash should be.
+	if err := r.Send(Event{Hash: hash2}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := <-ranCh; got != "test:"+hash2 {
+		t.Errorf("unexpected hook run: %q", got)
+	}
+}
+
+// slowHook blocks until it is released.
+type slowHook struct {
+	fakeHook
+	release chan struct{}
+}
+
+func (h *slowHook) Do(ctx context.Context, ev Event) error {
+	<-h.release
+	return h.fakeHook.Do(ctx, ev)
+}
+
+func TestHookRunnerQueue(t *testing.T) {
+	l := logging.New("", "", 0)
+	ranCh := make(chan string, 10)
+
+	h := &slowHook{fakeHook: fakeHook{name: "slow", log: ranCh}, release: make(chan struct{})}
+	runner := NewHookRunner(h, time.Millisecond, NewHookQueue(10, QueueDropOldest), l, false, true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go runner.Run(ctx)
+
+	want := []string{"a", "b", "c"}
+	for _, hash := range want {
+		_ = runner.Send(Event{Hash: hash})
+	}
+	close(h.release)
+
+	for _, hash := range want {
+		select {
+		case got := <-ranCh:
+			if got != "slow:"+hash {
+				t.Errorf("expected %q, got %q", "slow:"+hash, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %q", hash)
+		}
+	}
+}
+
+func TestHookRunnerStateEvents(t *testing.T) {
+	l := logging.New("", "", 0)
+	ranCh := make(chan string, 10)
+	runner := NewHookRunner(&fakeHook{name: "notify", log: ranCh}, time.Millisecond, NewHookData(), l, false, true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go runner.Run(ctx)
+
+	// Two failures with the same published hash are both delivered.
+	for _, when := range []string{"2026-01-01T00:00:00Z", "2026-01-01T00:01:00Z"} {
+		_ = runner.Send(Event{Hash: hash1, Type: EventSyncFailure, Time: when})
+		select {
+		case got := <-ranCh:
+			if got != "notify:"+hash1 {
+				t.Errorf("unexpected hook run: %q", got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for event at %s", when)
+		}
+	}
+}
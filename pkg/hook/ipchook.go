@@ -0,0 +1,161 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// How an IPChook delivers events.
+const (
+	// Each event is written to a new connection to a Unix domain socket.
+	IPCSocket = "socket"
+	// Each event is written to a named pipe, which must have a reader.
+	IPCFIFO = "fifo"
+	// Each event is written to a new file in a directory.
+	IPCSpool = "spool"
+)
+
+// spoolTimeFormat is used to name spool files, so they sort in the order
+// they were written.
+const spoolTimeFormat = "20060102T150405.000000000Z"
+
+// IPChook implements Hook in terms of local IPC.  Each event is delivered as
+// a single line of JSON, with the same fields as a webhook body.
+type IPChook struct {
+	// Name of this hook, for logs and metrics
+	name string
+	// One of the IPC* values
+	mode string
+	// The socket, FIFO, or spool directory
+	path string
+	// Timeout for delivering each event
+	timeout time.Duration
+	// Logger
+	log logintf
+}
+
+// NewIPChook returns a new IPChook.
+func NewIPChook(name, mode, path string, timeout time.Duration, log logintf) *IPChook {
+	return &IPChook{
+		name:    name,
+		mode:    mode,
+		path:    path,
+		timeout: timeout,
+		log:     log,
+	}
+}
+
+// Name describes hook, implements Hook.Name.
+func (h *IPChook) Name() string {
+	return h.name
+}
+
+// Do delivers the event, implements Hook.Do.
+func (h *IPChook) Do(ctx context.Context, ev Event) error {
+	// Without a deadline, a socket or FIFO whose reader stops reading would
+	// block forever.
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	payload := WebhookPayload{
+		Event:     ev,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+	jb, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	jb = append(jb, '\n')
+
+	h.log.V(0).Info("sending event", "name", h.name, "hash", ev.Hash, "mode", h.mode, "path", h.path)
+	switch h.mode {
+	case IPCSocket:
+		return h.sendSocket(ctx, jb)
+	case IPCFIFO:
+		return h.sendFIFO(ctx, jb)
+	case IPCSpool:
+		return h.sendSpool(ev, jb)
+	}
+	return fmt.Errorf("unknown IPC mode %q", h.mode)
+}
+
+func (h *IPChook) sendSocket(ctx context.Context, data []byte) error {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "unix", h.path)
+	if err != nil {
+		return fmt.Errorf("can't connect to socket: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetWriteDeadline(deadline)
+	}
+	if _, err := conn.Write(data); err != nil {
+		return fmt.Errorf("can't write to socket: %w", err)
+	}
+	return nil
+}
+
+func (h *IPChook) sendFIFO(ctx context.Context, data []byte) error {
+	fi, err := os.Stat(h.path)
+	if err != nil {
+		return fmt.Errorf("can't stat FIFO: %w", err)
+	}
+	if fi.Mode()&os.ModeNamedPipe == 0 {
+		return fmt.Errorf("%s is not a FIFO", h.path)
+	}
+	// Opening without O_NONBLOCK would wait forever for a reader.
+	f, err := os.OpenFile(h.path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if errors.Is(err, syscall.ENXIO) {
+		return fmt.Errorf("FIFO %s has no reader", h.path)
+	} else if err != nil {
+		return fmt.Errorf("can't open FIFO: %w", err)
+	}
+	defer f.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = f.SetWriteDeadline(deadline)
+	}
+	// Writes of up to PIPE_BUF (4KiB) bytes are atomic, so concurrent writers
+	// can not interleave short events.  Larger events (e.g. with many changed
+	// files) may be interleaved with other writers, and may be partly written
+	// if the deadline passes.
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("can't write to FIFO: %w", err)
+	}
+	return nil
+}
+
+func (h *IPChook) sendSpool(ev Event, data []byte) error {
+	id := ev.Hash
+	if ev.Type != "" {
+		id = ev.Type
+	}
+	name := fmt.Sprintf("%s-%s.json", time.Now().UTC().Format(spoolTimeFormat), id)
+	// The file is written under a temporary name (starting with '.') and
+	// renamed, so consumers never see partial events.
+	if err := writeFileAtomic(filepath.Join(h.path, name), data); err != nil {
+		return fmt.Errorf("can't write spool file: %w", err)
+	}
+	return nil
+}
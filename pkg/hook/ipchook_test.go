@@ -0,0 +1,219 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"k8s.io/git-sync/pkg/logging"
+)
+
+// decodeLine checks that a delivered event is a single JSON line for hash1.
+func decodeLine(t *testing.T, line string) {
+	t.Helper()
+	if !strings.HasSuffix(line, "\n") || strings.Count(line, "\n") != 1 {
+		t.Errorf("expected exactly one line, got %q", line)
+	}
+	payload := WebhookPayload{}
+	if err := json.Unmarshal([]byte(line), &payload); err != nil {
+		t.Fatalf("can't decode event: %v", err)
+	}
+	if payload.Hash != hash1 || payload.Timestamp == "" {
+		t.Errorf("unexpected payload: %+v", payload)
+	}
+}
+
+func TestIPChookSocket(t *testing.T) {
+	l := logging.New("", "", 0)
+	path := filepath.Join(t.TempDir(), "sock")
+
+	h := NewIPChook("test", IPCSocket, path, time.Second, l)
+	if err := h.Do(context.Background(), Event{Hash: hash1}); err == nil {
+		t.Errorf("expected an error with no listener")
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	lines := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		lines <- line
+	}()
+
+	if err := h.Do(context.Background(), Event{Hash: hash1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	select {
+	case line := <-lines:
+		decodeLine(t, line)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("event was not received")
+	}
+}
+
+func TestIPChookFIFO(t *testing.T) {
+	l := logging.New("", "", 0)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "fifo")
+	if err := syscall.Mkfifo(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewIPChook("test", IPCFIFO, path, time.Second, l)
+	if err := h.Do(context.Background(), Event{Hash: hash1}); err == nil {
+		t.Errorf("expected an error with no reader")
+	}
+
+	r, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := h.Do(context.Background(), Event{Hash: hash1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = r.SetReadDeadline(time.Now().Add(5 * time.Second))
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil {
+		t.Fatalf("can't read FIFO: %v", err)
+	}
+	decodeLine(t, line)
+
+	// A regular file is not a FIFO.
+	notFIFO := filepath.Join(dir, "file")
+	if err := os.WriteFile(notFIFO, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	h = NewIPChook("test", IPCFIFO, notFIFO, time.Second, l)
+	if err := h.Do(context.Background(), Event{Hash: hash1}); err == nil {
+		t.Errorf("expected an error for a regular file")
+	}
+}
+
+func TestIPChookSpool(t *testing.T) {
+	l := logging.New("", "", 0)
+	dir := filepath.Join(t.TempDir(), "spool")
+
+	h := NewIPChook("test", IPCSpool, dir, time.Second, l)
+	if err := h.Do(context.Background(), Event{Hash: hash1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := h.Do(context.Background(), Event{Hash: hash2}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 files, got %d", len(entries))
+	}
+	// Files are sorted by name, which is the order they were written.
+	for i, want := range []string{hash1, hash2} {
+		name := entries[i].Name()
+		if !strings.HasSuffix(name, "-"+want+".json") {
+			t.Errorf("unexpected file name %q", name)
+		}
+		if i == 0 {
+			b, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			decodeLine(t, string(b))
+		}
+	}
+}
+
+func TestIPChookTimeout(t *testing.T) {
+	l := logging.New("", "", 0)
+	dir := t.TempDir()
+
+	// Bigger than the kernel's socket and pipe buffers.
+	ev := Event{Hash: hash1}
+	for i := 0; i < 64*1024; i++ {
+		ev.ChangedFiles = append(ev.ChangedFiles, "some/changed/file")
+	}
+
+	t.Run("socket", func(t *testing.T) {
+		path := filepath.Join(dir, "sock")
+		ln, err := net.Listen("unix", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		// Accept, but never read.
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			<-done
+			conn.Close()
+		}()
+
+		h := NewIPChook("test", IPCSocket, path, 100*time.Millisecond, l)
+		start := time.Now()
+		if err := h.Do(context.Background(), ev); err == nil {
+			t.Errorf("expected a timeout")
+		}
+		if d := time.Since(start); d > 5*time.Second {
+			t.Errorf("expected the write to time out, took %v", d)
+		}
+	})
+
+	t.Run("fifo", func(t *testing.T) {
+		path := filepath.Join(dir, "fifo")
+		if err := syscall.Mkfifo(path, 0600); err != nil {
+			t.Fatal(err)
+		}
+		// Open, but never read.
+		r, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+
+		h := NewIPChook("test", IPCFIFO, path, 100*time.Millisecond, l)
+		start := time.Now()
+		if err := h.Do(context.Background(), ev); err == nil {
+			t.Errorf("expected a timeout")
+		}
+		if d := time.Since(start); d > 5*time.Second {
+			t.Errorf("expected the write to time out, took %v", d)
+		}
+	})
+}
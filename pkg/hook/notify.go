@@ -0,0 +1,61 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Notifier runs hooks which must complete before git-sync carries on (e.g.
+// before it exits).  Like Validator, hooks are run synchronously and are not
+// retried, but a failed hook does not stop the others from running.
+type Notifier struct {
+	hooks []Hook
+	log   logintf
+}
+
+// NewNotifier returns a new Notifier with no hooks.
+func NewNotifier(log logintf) *Notifier {
+	return &Notifier{log: log}
+}
+
+// Add adds a hook to be run, after any hooks which were already added.
+func (n *Notifier) Add(h Hook) {
+	n.hooks = append(n.hooks, h)
+}
+
+// Len returns the number of hooks.
+func (n *Notifier) Len() int {
+	return len(n.hooks)
+}
+
+// Notify runs each hook once, in order, and returns any errors.
+func (n *Notifier) Notify(ctx context.Context, ev Event) error {
+	var errs []error
+	for _, h := range n.hooks {
+		n.log.V(1).Info("running hook", "name", h.Name(), "type", ev.Type)
+		if err := h.Do(ctx, ev); err != nil {
+			updateHookRunCountMetric(h.Name(), "error")
+			errs = append(errs, fmt.Errorf("hook %q: %w", h.Name(), err))
+			continue
+		}
+		updateHookRunCountMetric(h.Name(), "success")
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/git-sync/pkg/logging"
+)
+
+func TestNotifier(t *testing.T) {
+	l := logging.New("", "", 0)
+	ranCh := make(chan string, 10)
+	n := NewNotifier(l)
+	n.Add(&failingHook{name: "failing", fails: 1})
+	n.Add(&fakeHook{name: "after", log: ranCh})
+
+	if err := n.Notify(context.Background(), Event{Hash: hash1, Type: EventExit}); err == nil {
+		t.Fatalf("expected error but got nil")
+	}
+	if len(ranCh) != 1 {
+		t.Fatalf("hooks after a failure should still run")
+	}
+	if got := <-ranCh; got != "after:"+hash1 {
+		t.Errorf("unexpected hook run: %q", got)
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"sync"
+	"time"
+)
+
+// MaxRunOutput is how many bytes of each output stream are kept for a single
+// run.  If a command produces more than this, only the end is kept.
+const MaxRunOutput = 64 * 1024
+
+// ExecRun describes one run of an exechook.
+type ExecRun struct {
+	Hash            string    `json:"hash"`
+	Start           time.Time `json:"start"`
+	DurationSeconds float64   `json:"durationSeconds"`
+	// The exit code of the command, or -1 if it did not exit normally (e.g.
+	// it timed out or could not be started).
+	ExitCode int    `json:"exitCode"`
+	Error    string `json:"error,omitempty"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	// Whether any output was discarded because it was too long.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// OutputHistory keeps the last few runs of an exechook.  It is safe for
+// concurrent use.
+type OutputHistory struct {
+	mutex sync.Mutex
+	size  int
+	runs  []ExecRun
+}
+
+// NewOutputHistory returns a new OutputHistory which keeps up to size runs.
+func NewOutputHistory(size int) *OutputHistory {
+	return &OutputHistory{size: size}
+}
+
+// add records a run, discarding the oldest one if the history is full.
+func (h *OutputHistory) add(run ExecRun) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.runs = append(h.runs, run)
+	if len(h.runs) > h.size {
+		h.runs = h.runs[len(h.runs)-h.size:]
+	}
+}
+
+// Runs returns a copy of the recorded runs, oldest first.
+func (h *OutputHistory) Runs() []ExecRun {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return append([]ExecRun{}, h.runs...)
+}
+
+// outputTail accumulates the end of an output stream, up to MaxRunOutput
+// bytes.
+type outputTail struct {
+	buf       []byte
+	truncated bool
+}
+
+func (t *outputTail) addLine(line string) {
+	t.buf = append(t.buf, line...)
+	t.buf = append(t.buf, '\n')
+	if len(t.buf) > MaxRunOutput {
+		t.buf = t.buf[len(t.buf)-MaxRunOutput:]
+		t.truncated = true
+	}
+}
+
+func (t *outputTail) String() string {
+	return string(t.buf)
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// ParseSignal parses a signal name (e.g. "SIGHUP" or "HUP") or number.
+func ParseSignal(s string) (syscall.Signal, error) {
+	if num, err := strconv.ParseInt(s, 0, 0); err == nil {
+		if num <= 0 {
+			return 0, fmt.Errorf("invalid signal number: %d", num)
+		}
+		return syscall.Signal(num), nil
+	}
+	sig := unix.SignalNum(s)
+	if sig == 0 {
+		// maybe they said "HUP", meaning "SIGHUP"
+		sig = unix.SignalNum("SIG" + s)
+	}
+	if sig == 0 {
+		return 0, fmt.Errorf("invalid signal name: %q", s)
+	}
+	return sig, nil
+}
+
+// ProcessSelector chooses which processes a Signalhook signals.  Exactly one
+// field should be set.
+type ProcessSelector struct {
+	// A file holding the process ID, e.g. /run/nginx.pid.
+	PIDFile string
+	// The exact command name, e.g. "nginx".  This is compared with both the
+	// kernel's name for the process (which may be truncated) and the base
+	// name of its first argument.
+	Name string
+	// A pattern which is matched against the full command line, with the
+	// arguments separated by spaces.
+	Cmdline *regexp.Regexp
+}
+
+// Signalhook implements Hook in terms of sending a signal to other
+// processes, e.g. to make them reload their config.  Processes are found
+// through /proc, so they must be in the same PID namespace as git-sync (e.g.
+// a pod with shareProcessNamespace).
+type Signalhook struct {
+	// Name of this hook, for logs and metrics
+	name string
+	// Signal to send
+	signal syscall.Signal
+	// How to find the processes
+	selector ProcessSelector
+	// Where to find processes
+	procDir string
+	// Timeout for finding and signalling the processes
+	timeout time.Duration
+	// Logger
+	log logintf
+}
+
+// NewSignalhook returns a new Signalhook.
+func NewSignalhook(name string, signal syscall.Signal, selector ProcessSelector, timeout time.Duration, log logintf) *Signalhook {
+	return &Signalhook{
+		name:     name,
+		signal:   signal,
+		selector: selector,
+		procDir:  "/proc",
+		timeout:  timeout,
+		log:      log,
+	}
+}
+
+// Name describes hook, implements Hook.Name.
+func (h *Signalhook) Name() string {
+	return h.name
+}
+
+// Do sends the signal to all matching processes, implements Hook.Do.  It is
+// an error if no processes match.
+func (h *Signalhook) Do(ctx context.Context, ev Event) error {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	pids, err := h.findProcesses(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pids) == 0 {
+		return fmt.Errorf("no processes matched %s", h.describeSelector())
+	}
+
+	h.log.V(0).Info("sending signal", "name", h.name, "hash", ev.Hash, "signal", unix.SignalName(h.signal), "pids", pids)
+	var errs []error
+	for _, pid := range pids {
+		if err := syscall.Kill(pid, h.signal); err != nil {
+			errs = append(errs, fmt.Errorf("can't signal process %d: %w", pid, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h *Signalhook) describeSelector() string {
+	switch {
+	case h.selector.PIDFile != "":
+		return fmt.Sprintf("pid-file %q", h.selector.PIDFile)
+	case h.selector.Name != "":
+		return fmt.Sprintf("process-name %q", h.selector.Name)
+	case h.selector.Cmdline != nil:
+		return fmt.Sprintf("process-regex %q", h.selector.Cmdline.String())
+	}
+	return "<nothing>"
+}
+
+// findProcesses returns the IDs of the processes which match the selector.
+// The git-sync process itself never matches.
+func (h *Signalhook) findProcesses(ctx context.Context) ([]int, error) {
+	if h.selector.PIDFile != "" {
+		b, err := os.ReadFile(h.selector.PIDFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't read pid-file: %w", err)
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+		if err != nil || pid <= 0 {
+			return nil, fmt.Errorf("invalid pid-file %q: %q", h.selector.PIDFile, string(b))
+		}
+		// Make sure it is still running, so a stale pid-file is reported
+		// as such.
+		if err := syscall.Kill(pid, 0); errors.Is(err, syscall.ESRCH) {
+			return nil, nil
+		}
+		return []int{pid}, nil
+	}
+
+	entries, err := os.ReadDir(h.procDir)
+	if err != nil {
+		return nil, fmt.Errorf("can't list processes: %w", err)
+	}
+	self := os.Getpid()
+	pids := []int{}
+	for _, ent := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		pid, err := strconv.Atoi(ent.Name())
+		if err != nil || pid == self {
+			continue
+		}
+		// Processes may exit at any time, so errors are not fatal.
+		cmdline, err := os.ReadFile(filepath.Join(h.procDir, ent.Name(), "cmdline"))
+		if err != nil || len(cmdline) == 0 {
+			// Kernel threads and zombies have no command line.
+			continue
+		}
+		args := strings.Split(string(bytes.TrimRight(cmdline, "\x00")), "\x00")
+		if h.selector.Name != "" {
+			comm, _ := os.ReadFile(filepath.Join(h.procDir, ent.Name(), "comm"))
+			if strings.TrimSpace(string(comm)) == h.selector.Name || filepath.Base(args[0]) == h.selector.Name {
+				pids = append(pids, pid)
+			}
+		} else if h.selector.Cmdline != nil {
+			if h.selector.Cmdline.MatchString(strings.Join(args, " ")) {
+				pids = append(pids, pid)
+			}
+		}
+	}
+	return pids, nil
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"k8s.io/git-sync/pkg/logging"
+)
+
+func TestParseSignal(t *testing.T) {
+	cases := []struct {
+		in   string
+		want syscall.Signal
+		fail bool
+	}{
+		{in: "SIGHUP", want: syscall.SIGHUP},
+		{in: "HUP", want: syscall.SIGHUP},
+		{in: "10", want: syscall.Signal(10)},
+		{in: "0", fail: true},
+		{in: "SIGNOPE", fail: true},
+		{in: "", fail: true},
+	}
+	for _, tc := range cases {
+		got, err := ParseSignal(tc.in)
+		if err != nil && !tc.fail {
+			t.Errorf("%q: unexpected error: %v", tc.in, err)
+		} else if err == nil && tc.fail {
+			t.Errorf("%q: unexpected success: %v", tc.in, got)
+		} else if got != tc.want {
+			t.Errorf("%q: expected %v, got %v", tc.in, tc.want, got)
+		}
+	}
+}
+
+// startSleeper starts a long-running process with a unique command line,
+// named name, and returns a channel which is closed when it exits.
+func startSleeper(t *testing.T, name string) (*exec.Cmd, chan struct{}) {
+	t.Helper()
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep not found")
+	}
+	bin := filepath.Join(t.TempDir(), name)
+	if err := os.Symlink(sleep, bin); err != nil {
+		t.Fatal(err)
+	}
+	arg := fmt.Sprintf("%d.%d", 3600+os.Getpid()%1000, time.Now().UnixNano()%1000)
+	c := exec.Command(bin, arg)
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		_ = c.Wait()
+		close(done)
+	}()
+	t.Cleanup(func() {
+		_ = c.Process.Kill()
+		<-done
+	})
+	return c, done
+}
+
+func TestSignalhook(t *testing.T) {
+	l := logging.New("", "", 0)
+
+	wait := func(t *testing.T, done chan struct{}) {
+		t.Helper()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Errorf("process was not signalled")
+		}
+	}
+
+	t.Run("pid-file", func(t *testing.T) {
+		c, done := startSleeper(t, "gitsync-test-pidfile")
+		pidFile := filepath.Join(t.TempDir(), "pid")
+		if err := os.WriteFile(pidFile, []byte(strconv.Itoa(c.Process.Pid)+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		h := NewSignalhook("test", syscall.SIGTERM, ProcessSelector{PIDFile: pidFile}, time.Second, l)
+		if err := h.Do(context.Background(), Event{Hash: hash1}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		wait(t, done)
+	})
+
+	t.Run("name", func(t *testing.T) {
+		// Longer than the kernel's 15 character limit.
+		_, done := startSleeper(t, "gitsync-test-name-sleeper")
+		h := NewSignalhook("test", syscall.SIGTERM, ProcessSelector{Name: "gitsync-test-name-sleeper"}, time.Second, l)
+		if err := h.Do(context.Background(), Event{Hash: hash1}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		wait(t, done)
+	})
+
+	t.Run("cmdline", func(t *testing.T) {
+		c, done := startSleeper(t, "gitsync-test-cmdline")
+		re := regexp.MustCompile(regexp.QuoteMeta(c.Args[1]) + "$")
+		h := NewSignalhook("test", syscall.SIGTERM, ProcessSelector{Cmdline: re}, time.Second, l)
+		if err := h.Do(context.Background(), Event{Hash: hash1}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		wait(t, done)
+	})
+
+	t.Run("no-match", func(t *testing.T) {
+		h := NewSignalhook("test", syscall.SIGTERM, ProcessSelector{Name: "gitsync-test-nothing"}, time.Second, l)
+		if err := h.Do(context.Background(), Event{Hash: hash1}); err == nil {
+			t.Errorf("unexpected success")
+		}
+	})
+
+	t.Run("stale-pid-file", func(t *testing.T) {
+		c, done := startSleeper(t, "gitsync-test-stale")
+		_ = c.Process.Kill()
+		<-done
+		pidFile := filepath.Join(t.TempDir(), "pid")
+		if err := os.WriteFile(pidFile, []byte(strconv.Itoa(c.Process.Pid)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		h := NewSignalhook("test", syscall.SIGTERM, ProcessSelector{PIDFile: pidFile}, time.Second, l)
+		if err := h.Do(context.Background(), Event{Hash: hash1}); err == nil {
+			t.Errorf("unexpected success")
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		h := NewSignalhook("test", syscall.SIGTERM, ProcessSelector{Name: "gitsync-test-nothing"}, time.Nanosecond, l)
+		if err := h.Do(context.Background(), Event{Hash: hash1}); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected deadline exceeded, got %v", err)
+		}
+	})
+
+	t.Run("never-self", func(t *testing.T) {
+		// Only the test binary's own command line matches this, and it must
+		// be skipped.
+		h := NewSignalhook("test", syscall.SIGTERM, ProcessSelector{Cmdline: regexp.MustCompile(regexp.QuoteMeta(os.Args[0]))}, time.Second, l)
+		if err := h.Do(context.Background(), Event{Hash: hash1}); err == nil {
+			t.Errorf("unexpected success")
+		}
+	})
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// RejectedError is returned by Validator.Validate when a hook rejects a
+// revision: an exec hook exits with a non-zero status, or a webhook responds
+// with a status other than its success status.
+type RejectedError struct {
+	// The name of the hook which rejected the revision.
+	Hook string
+	// The hash which was rejected.
+	Hash string
+	// The error from the hook.
+	Err error
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("hash %s rejected by validation hook %q: %v", e.Hash, e.Hook, e.Err)
+}
+
+func (e *RejectedError) Unwrap() error {
+	return e.Err
+}
+
+// Validator runs hooks which must all succeed before a revision is
+// published.  Unlike HookRunner, hooks are run synchronously and are not
+// retried.
+type Validator struct {
+	hooks []Hook
+	log   logintf
+}
+
+// NewValidator returns a new Validator with no hooks.
+func NewValidator(log logintf) *Validator {
+	return &Validator{log: log}
+}
+
+// Add adds a hook to be run, after any hooks which were already added.
+func (v *Validator) Add(h Hook) {
+	v.hooks = append(v.hooks, h)
+}
+
+// Len returns the number of hooks.
+func (v *Validator) Len() int {
+	return len(v.hooks)
+}
+
+// Validate runs each hook in order, stopping at the first failure.  If the
+// hook rejected the revision, it returns a *RejectedError.  Other failures
+// (e.g. a timeout, or a webhook which can't be reached) say nothing about the
+// revision, so they are returned as-is, to be retried.
+func (v *Validator) Validate(ctx context.Context, ev Event) error {
+	for _, h := range v.hooks {
+		v.log.V(1).Info("running validation hook", "name", h.Name(), "hash", ev.Hash)
+		if err := h.Do(ctx, ev); err != nil {
+			updateHookRunCountMetric(h.Name(), "error")
+			if !isVeto(err) {
+				return fmt.Errorf("validation hook %q failed: %w", h.Name(), err)
+			}
+			return &RejectedError{Hook: h.Name(), Hash: ev.Hash, Err: err}
+		}
+		updateHookRunCountMetric(h.Name(), "success")
+	}
+	return nil
+}
+
+// isVeto returns true if err from a hook means that it definitely rejected
+// the revision, rather than that it could not be run or reached.
+func isVeto(err error) bool {
+	var se *statusError
+	return exitCode(err) > 0 || errors.As(err, &se)
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s.io/git-sync/pkg/cmd"
+	"k8s.io/git-sync/pkg/logging"
+)
+
+func TestValidator(t *testing.T) {
+	l := logging.New("", "", 0)
+
+	t.Run("all pass", func(t *testing.T) {
+		ranCh := make(chan string, 10)
+		v := NewValidator(l)
+		v.Add(&fakeHook{name: "first", log: ranCh})
+		v.Add(&fakeHook{name: "second", log: ranCh})
+		if err := v.Validate(context.Background(), Event{Hash: hash1}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		close(ranCh)
+		got := []string{}
+		for s := range ranCh {
+			got = append(got, s)
+		}
+		if len(got) != 2 || got[0] != "first:"+hash1 || got[1] != "second:"+hash1 {
+			t.Errorf("unexpected hook runs: %v", got)
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer srv.Close()
+
+		for name, h := range map[string]Hook{
+			"exechook": NewExechook("failing", cmd.NewRunner(l), "false", nil, time.Second, l),
+			"webhook":  NewWebhook("failing", srv.URL, "POST", WebhookSuccess2xx, time.Second, l),
+		} {
+			t.Run(name, func(t *testing.T) {
+				ranCh := make(chan string, 10)
+				v := NewValidator(l)
+				v.Add(h)
+				v.Add(&fakeHook{name: "after", log: ranCh})
+				err := v.Validate(context.Background(), Event{Hash: hash1, Worktree: "/tmp"})
+				var rejected *RejectedError
+				if !errors.As(err, &rejected) {
+					t.Fatalf("expected RejectedError, got %v", err)
+				}
+				if rejected.Hook != "failing" || rejected.Hash != hash1 {
+					t.Errorf("unexpected error: %+v", rejected)
+				}
+				if len(ranCh) != 0 {
+					t.Errorf("hooks after the rejection should not run")
+				}
+			})
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		srv.Close()
+
+		for name, h := range map[string]Hook{
+			"error":    &failingHook{name: "failing", fails: 1},
+			"timeout":  NewExechook("failing", cmd.NewRunner(l), "sleep", []string{"10"}, 100*time.Millisecond, l),
+			"no-start": NewExechook("failing", cmd.NewRunner(l), "/does/not/exist", nil, time.Second, l),
+			"no-reply": NewWebhook("failing", srv.URL, "POST", WebhookSuccess2xx, time.Second, l),
+		} {
+			t.Run(name, func(t *testing.T) {
+				ranCh := make(chan string, 10)
+				v := NewValidator(l)
+				v.Add(h)
+				v.Add(&fakeHook{name: "after", log: ranCh})
+				err := v.Validate(context.Background(), Event{Hash: hash1, Worktree: "/tmp"})
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				var rejected *RejectedError
+				if errors.As(err, &rejected) {
+					t.Errorf("expected a failure which is not a rejection, got %v", err)
+				}
+				if len(ranCh) != 0 {
+					t.Errorf("hooks after the failure should not run")
+				}
+			})
+		}
+	})
+}
@@ -17,72 +17,312 @@ limitations under the License.
 package hook
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
 	"time"
 )
 
+// WebhookSuccess2xx can be passed to NewWebhook as the success code, to
+// accept any 2xx response.
+const WebhookSuccess2xx = -1
+
+// Webhook body modes.
+const (
+	WebhookBodyJSON     = "json"
+	WebhookBodyEmpty    = "empty"
+	WebhookBodyTemplate = "template"
+)
+
+// WebhookPayload is the data sent in webhook bodies.  It is also the data
+// passed to body templates.
+type WebhookPayload struct {
+	Event
+	Timestamp string `json:"timestamp"`
+}
+
 // Webhook implements Hook for HTTP requests.
 type Webhook struct {
+	// Name of this hook, for logs and metrics
+	name string
 	// Url for the http/s request
 	url string
 	// Method for the http/s request
 	method string
 	// Code to look for when determining if the request was successful.
-	// If this is not specified, request is sent and forgotten about.
+	// If this is not specified, request is sent and forgotten about.  If
+	// this is WebhookSuccess2xx, any 2xx code is accepted.
 	success int
 	// Timeout for the http/s request
 	timeout time.Duration
+	// Extra headers to send
+	headers map[string]string
+	// Extra headers to send, with values read from files
+	headerFiles map[string]string
+	// A file holding a bearer token for the Authorization header
+	bearerTokenFile string
+	// A file holding a secret for signing requests
+	hmacSecretFile string
+	// Secrets which are read from files are added to this, if it is not nil,
+	// so they can be redacted.
+	secrets secretStore
+	// A client with custom TLS settings, or nil to use client
+	tlsClient *reloadingClient
+	// The client for requests without custom TLS settings, or nil to use
+	// http.DefaultClient
+	client *http.Client
+	// How to build the request body
+	bodyMode string
+	// Template for the request body, if bodyMode is WebhookBodyTemplate
+	bodyTemplate *template.Template
 	// Logger
 	log logintf
 }
 
 // NewWebhook returns a new WebHook.
-func NewWebhook(url, method string, success int, timeout time.Duration, log logintf) *Webhook {
+func NewWebhook(name, url, method string, success int, timeout time.Duration, log logintf) *Webhook {
 	return &Webhook{
-		url:     url,
-		method:  method,
-		success: success,
-		timeout: timeout,
-		log:     log,
+		name:     name,
+		url:      url,
+		method:   method,
+		success:  success,
+		timeout:  timeout,
+		bodyMode: WebhookBodyJSON,
+		log:      log,
+	}
+}
+
+// SetHeaders sets extra headers to be sent with each request.  The values in
+// headerFiles are paths to files, which are read before each request, so they
+// can be rotated (e.g. bearer tokens).
+func (w *Webhook) SetHeaders(headers, headerFiles map[string]string) {
+	w.headers = headers
+	w.headerFiles = headerFiles
+}
+
+// SetBearerTokenFile sets a file from which a bearer token is read before
+// each request and sent in the Authorization header.
+func (w *Webhook) SetBearerTokenFile(path string) {
+	w.bearerTokenFile = path
+}
+
+// SetSigningSecretFile sets a file holding a secret, which is used to sign
+// each request with HMAC-SHA256 (see Sign).  The file is read before each
+// request, so it can be rotated.
+func (w *Webhook) SetSigningSecretFile(path string) {
+	w.hmacSecretFile = path
+}
+
+// secretStore is implemented by loggers which redact secrets (e.g.
+// logging.Logger).
+type secretStore interface {
+	SetSecrets(source string, secrets ...string)
+}
+
+// SetSecretStore sets where secrets which are read from files (the bearer
+// token, header files, and HMAC secret) are recorded, so they can be
+// redacted.  Only the latest contents of each file are kept.
+func (w *Webhook) SetSecretStore(s secretStore) {
+	w.secrets = s
+}
+
+// setSecret records the contents of a secret file, if there is a store.
+func (w *Webhook) setSecret(path string, secret []byte) {
+	if w.secrets != nil {
+		w.secrets.SetSecrets(path, string(secret))
 	}
 }
 
+// SetClient sets the HTTP client which is used unless SetTLS was called with
+// hook-specific settings.
+func (w *Webhook) SetClient(client *http.Client) {
+	w.client = client
+}
+
+// SetTLS configures a CA bundle for verifying the server and a client
+// certificate and key for mutual TLS.  Any of these may be empty.  They
+// override the corresponding settings from base (e.g. git-sync's global TLS
+// settings), if it is not nil, and the rest of base still applies.  The files
+// are re-read, and base is called, before each request, so they can be
+// rotated.
+func (w *Webhook) SetTLS(base func() *tls.Config, caFile, certFile, keyFile string) error {
+	if (certFile == "") != (keyFile == "") {
+		return fmt.Errorf("client cert and key must be specified together")
+	}
+	w.tlsClient = newReloadingClient(base, caFile, certFile, keyFile)
+	if w.tlsClient != nil {
+		// Fail early if the files are not usable.
+		if _, err := w.tlsClient.get(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetBody sets how the request body is built.  The mode must be one of
+// WebhookBodyJSON, WebhookBodyEmpty, or WebhookBodyTemplate, in which case
+// tmpl is parsed as a Go text/template and executed with a WebhookPayload.
+func (w *Webhook) SetBody(mode, tmpl string) error {
+	switch mode {
+	case WebhookBodyJSON, WebhookBodyEmpty:
+		w.bodyMode = mode
+		w.bodyTemplate = nil
+	case WebhookBodyTemplate:
+		t, err := template.New(w.name).Funcs(templateFuncs).Option("missingkey=error").Parse(tmpl)
+		if err != nil {
+			return fmt.Errorf("can't parse body template: %w", err)
+		}
+		w.bodyMode = mode
+		w.bodyTemplate = t
+	default:
+		return fmt.Errorf("unknown body mode %q", mode)
+	}
+	return nil
+}
+
+// templateFuncs are available in body templates.
+var templateFuncs = template.FuncMap{
+	// json encodes a value as JSON, e.g. to safely quote strings.
+	"json": func(v any) (string, error) {
+		jb, err := json.Marshal(v)
+		return string(jb), err
+	},
+}
+
+// payload returns the data for the request body.
+func (w *Webhook) payload(ev Event) WebhookPayload {
+	return WebhookPayload{
+		Event:     ev,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// body returns the request body and its content type.
+func (w *Webhook) body(ev Event) ([]byte, string, error) {
+	switch w.bodyMode {
+	case WebhookBodyEmpty:
+		return nil, "", nil
+	case WebhookBodyTemplate:
+		buf := bytes.Buffer{}
+		if err := w.bodyTemplate.Execute(&buf, w.payload(ev)); err != nil {
+			return nil, "", fmt.Errorf("can't execute body template: %w", err)
+		}
+		return buf.Bytes(), "", nil
+	}
+	jb, err := json.Marshal(w.payload(ev))
+	if err != nil {
+		return nil, "", err
+	}
+	return jb, "application/json", nil
+}
+
+// setHeaders adds any extra headers to the request.
+func (w *Webhook) setHeaders(req *http.Request) error {
+	for k, v := range w.headers {
+		req.Header.Set(k, v)
+	}
+	for k, path := range w.headerFiles {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("can't read header file for %q: %w", k, err)
+		}
+		w.setSecret(path, b)
+		req.Header.Set(k, strings.TrimSpace(string(b)))
+	}
+	if w.bearerTokenFile != "" {
+		b, err := os.ReadFile(w.bearerTokenFile)
+		if err != nil {
+			return fmt.Errorf("can't read bearer token file: %w", err)
+		}
+		w.setSecret(w.bearerTokenFile, b)
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(b)))
+	}
+	return nil
+}
+
 // Name describes hook, implements Hook.Name.
 func (w *Webhook) Name() string {
-	return "webhook"
+	return w.name
 }
 
 // Do calls webhook.url, implements Hook.Do.
-func (w *Webhook) Do(ctx context.Context, hash string) error {
-	req, err := http.NewRequest(w.method, w.url, nil)
+func (w *Webhook) Do(ctx context.Context, ev Event) error {
+	hash := ev.Hash
+	body, contentType, err := w.body(ev)
 	if err != nil {
 		return err
 	}
+	req, err := http.NewRequest(w.method, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if err := w.setHeaders(req); err != nil {
+		return err
+	}
 	req.Header.Set("Gitsync-Hash", hash)
+	if w.hmacSecretFile != "" {
+		if err := w.signRequest(req, hash, body, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	client := http.DefaultClient
+	if w.client != nil {
+		client = w.client
+	}
+	if w.tlsClient != nil {
+		if client, err = w.tlsClient.get(); err != nil {
+			return err
+		}
+	}
 
 	ctx, cancel := context.WithTimeout(ctx, w.timeout)
 	defer cancel()
 	req = req.WithContext(ctx)
 
-	w.log.V(0).Info("sending webhook", "hash", hash, "url", w.url, "method", w.method, "timeout", w.timeout)
-	resp, err := http.DefaultClient.Do(req)
+	w.log.V(0).Info("sending webhook", "name", w.name, "hash", hash, "url", w.url, "method", w.method, "timeout", w.timeout)
+	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return err
 	}
 
 	// If the webhook has a success statusCode, check against it
-	if w.success > 0 && resp.StatusCode != w.success {
-		return fmt.Errorf("received response code %d expected %d, body: %q", resp.StatusCode, w.success, body)
+	if w.success == WebhookSuccess2xx {
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return &statusError{code: resp.StatusCode, expected: "2xx", body: respBody}
+		}
+	} else if w.success > 0 && resp.StatusCode != w.success {
+		return &statusError{code: resp.StatusCode, expected: strconv.Itoa(w.success), body: respBody}
 	}
 
-	w.log.V(1).Info("webhook succeeded", "hash", hash, "status", resp.StatusCode, "headers", resp.Header, "body", body)
+	w.log.V(1).Info("webhook succeeded", "name", w.name, "hash", hash, "status", resp.StatusCode, "headers", resp.Header, "body", respBody)
 	return nil
 }
+
+// statusError is returned when a webhook's response does not have the
+// success status.
+type statusError struct {
+	code     int
+	expected string
+	body     []byte
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("received response code %d expected %s, body: %q", e.code, e.expected, e.body)
+}
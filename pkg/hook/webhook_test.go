@@ -18,6 +18,13 @@ package hook
 
 import (
 	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 
@@ -27,15 +34,156 @@ import (
 func TestWebhookDo(t *testing.T) {
 	t.Run("test invalid urls are handled", func(t *testing.T) {
 		wh := NewWebhook(
+			"test",
 			":http://localhost:601426/hooks/webhook",
 			"POST",
 			200,
 			time.Second,
 			logging.New("", "", 0),
 		)
-		err := wh.Do(context.Background(), "hash")
+		err := wh.Do(context.Background(), Event{Hash: "hash"})
 		if err == nil {
 			t.Fatalf("expected error for invalid url but got none")
 		}
 	})
 }
+
+func TestWebhookSuccess2xx(t *testing.T) {
+	code := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+	}))
+	defer srv.Close()
+
+	wh := NewWebhook("test", srv.URL, "POST", WebhookSuccess2xx, time.Second, logging.New("", "", 0))
+	for _, tc := range []struct {
+		code int
+		fail bool
+	}{
+		{http.StatusOK, false},
+		{http.StatusAccepted, false},
+		{http.StatusNoContent, false},
+		{http.StatusNotModified, true},
+		{http.StatusBadRequest, true},
+		{http.StatusInternalServerError, true},
+	} {
+		code = tc.code
+		err := wh.Do(context.Background(), Event{Hash: hash1})
+		if tc.fail && err == nil {
+			t.Errorf("code %d: expected error", tc.code)
+		} else if !tc.fail && err != nil {
+			t.Errorf("code %d: unexpected error: %v", tc.code, err)
+		}
+	}
+}
+
+func TestWebhookPayload(t *testing.T) {
+	type request struct {
+		header http.Header
+		body   []byte
+	}
+	reqs := make(chan request, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		reqs <- request{header: r.Header, body: body}
+	}))
+	defer srv.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("s3cr3t\n"), 0600); err != nil {
+		t.Fatalf("can't write token file: %v", err)
+	}
+
+	t.Run("default json body", func(t *testing.T) {
+		wh := NewWebhook("test", srv.URL, "POST", 200, time.Second, logging.New("", "", 0))
+		wh.SetHeaders(map[string]string{"X-Static": "static"}, map[string]string{"X-From-File": tokenFile})
+		wh.SetBearerTokenFile(tokenFile)
+
+		ev := Event{
+			Hash:             hash2,
+			PreviousHash:     hash1,
+			Ref:              "main",
+			Repo:             "https://example.com/repo",
+			Link:             "/root/link",
+			Worktree:         "/root/.worktrees/" + hash2,
+			PreviousWorktree: "/root/.worktrees/" + hash1,
+			SyncCount:        2,
+			ChangedFiles:     []string{"a", "dir/b"},
+		}
+		if err := wh.Do(context.Background(), ev); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		req := <-reqs
+		if want, got := "application/json", req.header.Get("Content-Type"); want != got {
+			t.Errorf("expected Content-Type %q, got %q", want, got)
+		}
+		if want, got := ev.Hash, req.header.Get("Gitsync-Hash"); want != got {
+			t.Errorf("expected Gitsync-Hash %q, got %q", want, got)
+		}
+		if want, got := "static", req.header.Get("X-Static"); want != got {
+			t.Errorf("expected X-Static %q, got %q", want, got)
+		}
+		if want, got := "s3cr3t", req.header.Get("X-From-File"); want != got {
+			t.Errorf("expected X-From-File %q, got %q", want, got)
+		}
+		if want, got := "Bearer s3cr3t", req.header.Get("Authorization"); want != got {
+			t.Errorf("expected Authorization %q, got %q", want, got)
+		}
+
+		payload := WebhookPayload{}
+		if err := json.Unmarshal(req.body, &payload); err != nil {
+			t.Fatalf("can't decode body %q: %v", req.body, err)
+		}
+		if !reflect.DeepEqual(payload.Event, ev) {
+			t.Errorf("expected event %+v, got %+v", ev, payload.Event)
+		}
+		if _, err := time.Parse(time.RFC3339, payload.Timestamp); err != nil {
+			t.Errorf("bad timestamp %q: %v", payload.Timestamp, err)
+		}
+	})
+
+	t.Run("empty body", func(t *testing.T) {
+		wh := NewWebhook("test", srv.URL, "POST", 200, time.Second, logging.New("", "", 0))
+		if err := wh.SetBody(WebhookBodyEmpty, ""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := wh.Do(context.Background(), Event{Hash: hash1}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if req := <-reqs; len(req.body) != 0 {
+			t.Errorf("expected empty body, got %q", req.body)
+		}
+	})
+
+	t.Run("template body", func(t *testing.T) {
+		wh := NewWebhook("test", srv.URL, "POST", 200, time.Second, logging.New("", "", 0))
+		if err := wh.SetBody(WebhookBodyTemplate, `{"text": {{ printf "synced %s at %s" .Ref .Hash | json }}}`); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := wh.Do(context.Background(), Event{Hash: hash1, Ref: "main"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		req := <-reqs
+		if want, got := `{"text": "synced main at `+hash1+`"}`, string(req.body); want != got {
+			t.Errorf("expected body %q, got %q", want, got)
+		}
+	})
+
+	t.Run("bad template", func(t *testing.T) {
+		wh := NewWebhook("test", srv.URL, "POST", 200, time.Second, logging.New("", "", 0))
+		if err := wh.SetBody(WebhookBodyTemplate, `{{ .Hash `); err == nil {
+			t.Errorf("expected error for bad template")
+		}
+		if err := wh.SetBody("xml", ""); err == nil {
+			t.Errorf("expected error for bad mode")
+		}
+	})
+
+	t.Run("missing header file", func(t *testing.T) {
+		wh := NewWebhook("test", srv.URL, "POST", 200, time.Second, logging.New("", "", 0))
+		wh.SetHeaders(nil, map[string]string{"X-From-File": "/does/not/exist"})
+		if err := wh.Do(context.Background(), Event{Hash: hash1}); err == nil {
+			t.Errorf("expected error for missing header file")
+		}
+	})
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Headers used for signed webhooks.
+const (
+	SignatureHeader = "Gitsync-Signature-256"
+	TimestampHeader = "Gitsync-Timestamp"
+)
+
+// Sign computes the signature for a webhook request.  The signed message is
+// "<timestamp>.<hash>.<body>", so that neither the hash header nor the body
+// can be altered, and the timestamp lets receivers reject replayed requests.
+// Receivers can use this to verify requests.
+func Sign(secret []byte, timestamp, hash string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write([]byte(hash))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// signRequest adds signature headers to the request.  The secret file is
+// read every time, so it can be rotated.
+func (w *Webhook) signRequest(req *http.Request, hash string, body []byte, now time.Time) error {
+	secret, err := os.ReadFile(w.hmacSecretFile)
+	if err != nil {
+		return fmt.Errorf("can't read HMAC secret file: %w", err)
+	}
+	secret = bytes.TrimSpace(secret)
+	if len(secret) == 0 {
+		return fmt.Errorf("HMAC secret file %q is empty", w.hmacSecretFile)
+	}
+	w.setSecret(w.hmacSecretFile, secret)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set(TimestampHeader, ts)
+	req.Header.Set(SignatureHeader, Sign(secret, ts, hash, body))
+	return nil
+}
+
+// reloadingClient is an HTTP client whose TLS settings come from files, on
+// top of a base config.  The files are re-read on each use, and the client is
+// rebuilt if any of them, or the base config, have changed (e.g. when a
+// mounted Secret is rotated).
+type reloadingClient struct {
+	// Returns the config which the files override, or nil.  The result must
+	// not be modified.
+	base     func() *tls.Config
+	caFile   string
+	certFile string
+	keyFile  string
+
+	mutex   sync.Mutex
+	sum     string
+	baseCfg *tls.Config
+	client  *http.Client
+}
+
+// newReloadingClient returns a reloadingClient.  If all of the files are
+// empty, this returns nil.
+func newReloadingClient(base func() *tls.Config, caFile, certFile, keyFile string) *reloadingClient {
+	if caFile == "" && certFile == "" && keyFile == "" {
+		return nil
+	}
+	return &reloadingClient{base: base, caFile: caFile, certFile: certFile, keyFile: keyFile}
+}
+
+// get returns an HTTP client built from the current contents of the files.
+func (rc *reloadingClient) get() (*http.Client, error) {
+	var ca, cert, key []byte
+	var err error
+	if rc.caFile != "" {
+		if ca, err = os.ReadFile(rc.caFile); err != nil {
+			return nil, fmt.Errorf("can't read CA file: %w", err)
+		}
+	}
+	if rc.certFile != "" {
+		if cert, err = os.ReadFile(rc.certFile); err != nil {
+			return nil, fmt.Errorf("can't read client cert file: %w", err)
+		}
+		if key, err = os.ReadFile(rc.keyFile); err != nil {
+			return nil, fmt.Errorf("can't read client key file: %w", err)
+		}
+	}
+	h := sha256.New()
+	for _, b := range [][]byte{ca, cert, key} {
+		h.Write([]byte(strconv.Itoa(len(b)) + ":"))
+		h.Write(b)
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	var baseCfg *tls.Config
+	if rc.base != nil {
+		baseCfg = rc.base()
+	}
+
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	if rc.client != nil && sum == rc.sum && baseCfg == rc.baseCfg {
+		return rc.client, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if baseCfg != nil {
+		tlsConfig = baseCfg.Clone()
+	}
+	if ca != nil {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in CA file %q", rc.caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cert != nil {
+		pair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("can't load client cert and key: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{pair}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert
+	transport.TLSClientConfig = tlsConfig
+
+	if rc.client != nil {
+		rc.client.CloseIdleConnections()
+	}
+	rc.client = &http.Client{Transport: transport}
+	rc.sum = sum
+	rc.baseCfg = baseCfg
+	return rc.client, nil
+}
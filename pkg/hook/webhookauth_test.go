@@ -0,0 +1,270 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package hook
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s.io/git-sync/pkg/logging"
+)
+
+func TestSign(t *testing.T) {
+	a := Sign([]byte("secret"), "1700000000", hash1, []byte("body"))
+	if a != Sign([]byte("secret"), "1700000000", hash1, []byte("body")) {
+		t.Errorf("signature is not deterministic")
+	}
+	for name, b := range map[string]string{
+		"secret":    Sign([]byte("other"), "1700000000", hash1, []byte("body")),
+		"timestamp": Sign([]byte("secret"), "1700000001", hash1, []byte("body")),
+		"hash":      Sign([]byte("secret"), "1700000000", hash2, []byte("body")),
+		"body":      Sign([]byte("secret"), "1700000000", hash1, []byte("other")),
+	} {
+		if a == b {
+			t.Errorf("changing the %s did not change the signature", name)
+		}
+	}
+}
+
+func TestWebhookSigned(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "secret")
+
+	var gotSecret []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts := r.Header.Get(TimestampHeader)
+		sig := r.Header.Get(SignatureHeader)
+		for _, secret := range []string{"one", "two"} {
+			if sig == Sign([]byte(secret), ts, r.Header.Get("Gitsync-Hash"), body) {
+				gotSecret = []byte(secret)
+				return
+			}
+		}
+		http.Error(w, "bad signature", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	wh := NewWebhook("test", srv.URL, "POST", 200, time.Second, logging.New("", "", 0))
+	wh.SetSigningSecretFile(secretFile)
+
+	// Missing secret file is an error.
+	if err := wh.Do(context.Background(), Event{Hash: hash1}); err == nil {
+		t.Errorf("expected error for missing secret file")
+	}
+
+	for _, secret := range []string{"one", "two"} {
+		if err := os.WriteFile(secretFile, []byte(secret+"\n"), 0600); err != nil {
+			t.Fatalf("can't write secret file: %v", err)
+		}
+		if err := wh.Do(context.Background(), Event{Hash: hash1}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(gotSecret) != secret {
+			t.Errorf("expected request signed with %q, got %q", secret, gotSecret)
+		}
+	}
+}
+
+func TestWebhookSecretsAreRecorded(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content+"\n"), 0600); err != nil {
+			t.Fatalf("can't write %s: %v", name, err)
+		}
+		return path
+	}
+	tokenFile := write("token", "bearer-1")
+	headerFile := write("header", "header-1")
+	secretFile := write("secret", "hmac-1")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	log := logging.New("", "", 0)
+	wh := NewWebhook("test", srv.URL, "POST", 200, time.Second, log.WithName("test"))
+	wh.SetHeaders(nil, map[string]string{"X-Key": headerFile})
+	wh.SetBearerTokenFile(tokenFile)
+	wh.SetSigningSecretFile(secretFile)
+	wh.SetSecretStore(log)
+
+	if err := wh.Do(context.Background(), Event{Hash: hash1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := log.Redact("bearer-1 header-1 hmac-1"); got != "REDACTED REDACTED REDACTED" {
+		t.Errorf("expected secrets redacted, got %q", got)
+	}
+
+	// Rotated secrets replace the old ones.
+	write("token", "bearer-2")
+	if err := wh.Do(context.Background(), Event{Hash: hash1}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := log.Redact("bearer-1 bearer-2"); got != "bearer-1 REDACTED" {
+		t.Errorf("expected only the current token redacted, got %q", got)
+	}
+}
+
+// writeClientCert writes a new self-signed client cert and key with the
+// specified common name.
+func writeClientCert(t *testing.T, certFile, keyFile, cn string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("can't generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("can't create cert: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("can't marshal key: %v", err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("can't write cert: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatalf("can't write key: %v", err)
+	}
+}
+
+func TestWebhookTLS(t *testing.T) {
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.crt")
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+
+	var gotCN string
+	var gotVersion uint16
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotVersion = r.TLS.Version
+		if len(r.TLS.PeerCertificates) > 0 {
+			gotCN = r.TLS.PeerCertificates[0].Subject.CommonName
+		}
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, ca, 0600); err != nil {
+		t.Fatalf("can't write CA file: %v", err)
+	}
+	writeClientCert(t, certFile, keyFile, "first")
+
+	t.Run("default client can't verify the server", func(t *testing.T) {
+		wh := NewWebhook("test", srv.URL, "POST", 200, time.Second, logging.New("", "", 0))
+		if err := wh.Do(context.Background(), Event{Hash: hash1}); err == nil {
+			t.Errorf("expected TLS error")
+		}
+	})
+
+	t.Run("mismatched cert and key", func(t *testing.T) {
+		wh := NewWebhook("test", srv.URL, "POST", 200, time.Second, logging.New("", "", 0))
+		if err := wh.SetTLS(nil, caFile, certFile, ""); err == nil {
+			t.Errorf("expected error")
+		}
+	})
+
+	t.Run("shared client", func(t *testing.T) {
+		pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		transport := srv.Client().Transport.(*http.Transport).Clone() //nolint:forcetypeassert
+		transport.TLSClientConfig.Certificates = []tls.Certificate{pair}
+		wh := NewWebhook("test", srv.URL, "POST", 200, time.Second, logging.New("", "", 0))
+		wh.SetClient(&http.Client{Transport: transport})
+		gotCN = ""
+		if err := wh.Do(context.Background(), Event{Hash: hash1}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if gotCN != "first" {
+			t.Errorf("expected client cert %q, got %q", "first", gotCN)
+		}
+	})
+
+	t.Run("mutual TLS with rotation", func(t *testing.T) {
+		wh := NewWebhook("test", srv.URL, "POST", 200, time.Second, logging.New("", "", 0))
+		if err := wh.SetTLS(nil, caFile, certFile, keyFile); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := wh.Do(context.Background(), Event{Hash: hash1}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if gotCN != "first" {
+			t.Errorf("expected client cert %q, got %q", "first", gotCN)
+		}
+
+		writeClientCert(t, certFile, keyFile, "second")
+		if err := wh.Do(context.Background(), Event{Hash: hash2}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if gotCN != "second" {
+			t.Errorf("expected rotated client cert %q, got %q", "second", gotCN)
+		}
+	})
+
+	t.Run("mutual TLS with base settings", func(t *testing.T) {
+		pool := x509.NewCertPool()
+		pool.AddCert(srv.Certificate())
+		base := &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS13}
+		wh := NewWebhook("test", srv.URL, "POST", 200, time.Second, logging.New("", "", 0))
+		// No CA file, so the server is verified with the base settings.
+		if err := wh.SetTLS(func() *tls.Config { return base }, "", certFile, keyFile); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		gotCN = ""
+		if err := wh.Do(context.Background(), Event{Hash: hash1}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if gotCN != "second" {
+			t.Errorf("expected client cert %q, got %q", "second", gotCN)
+		}
+		if gotVersion != tls.VersionTLS13 {
+			t.Errorf("expected TLS 1.3 from the base settings, got %x", gotVersion)
+		}
+
+		// New base settings are used.
+		base = &tls.Config{MinVersion: tls.VersionTLS12}
+		if err := wh.Do(context.Background(), Event{Hash: hash2}); err == nil {
+			t.Errorf("expected TLS error without the base CA")
+		}
+	})
+}
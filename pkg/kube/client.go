@@ -0,0 +1,324 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package kube is a minimal client for the parts of the Kubernetes API which
+// git-sync uses to describe itself: Events and annotations on its own pod.
+package kube
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ServiceAccountDir is where Kubernetes mounts the pod's service account
+// credentials.
+const ServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// Event types, as used by Kubernetes.
+const (
+	EventTypeNormal  = "Normal"
+	EventTypeWarning = "Warning"
+)
+
+// component is reported as the source of events.
+const component = "git-sync"
+
+// Client talks to the Kubernetes API server on behalf of a single pod.  It
+// is safe for concurrent use.
+type Client struct {
+	// The base URL of the API server, e.g. "https://10.0.0.1:443".
+	host string
+	// A file holding a bearer token.  This is read before every request, so
+	// projected tokens can be rotated.
+	tokenFile string
+	// The HTTP client, configured to trust the cluster's CA.
+	httpClient *http.Client
+	// The pod which events and annotations are about.
+	namespace string
+	pod       string
+
+	mutex sync.Mutex
+	// The pod's UID, which is looked up the first time it is needed.
+	podUID string
+}
+
+// NewInClusterClient returns a Client which uses the pod's service account
+// to talk to the API server.  If namespace is "", the service account's
+// namespace is used.
+func NewInClusterClient(namespace, pod string) (*Client, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("not running in a cluster: KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT must be set")
+	}
+	if namespace == "" {
+		b, err := os.ReadFile(filepath.Join(ServiceAccountDir, "namespace"))
+		if err != nil {
+			return nil, fmt.Errorf("can't determine namespace: %w", err)
+		}
+		namespace = strings.TrimSpace(string(b))
+	}
+	caPEM, err := os.ReadFile(filepath.Join(ServiceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("can't read cluster CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificates found in cluster CA")
+	}
+	httpClient := &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+		},
+	}
+	base := "https://" + net.JoinHostPort(host, port)
+	return NewClient(base, filepath.Join(ServiceAccountDir, "token"), httpClient, namespace, pod), nil
+}
+
+// NewClient returns a Client for the API server at host (a URL).  If
+// httpClient is nil, http.DefaultClient is used.
+func NewClient(host, tokenFile string, httpClient *http.Client, namespace, pod string) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		host:       strings.TrimSuffix(host, "/"),
+		tokenFile:  tokenFile,
+		httpClient: httpClient,
+		namespace:  namespace,
+		pod:        pod,
+	}
+}
+
+// Namespace returns the pod's namespace.
+func (c *Client) Namespace() string {
+	return c.namespace
+}
+
+// Pod returns the pod's name.
+func (c *Client) Pod() string {
+	return c.pod
+}
+
+func (c *Client) podPath() string {
+	return "/api/v1/namespaces/" + url.PathEscape(c.namespace) + "/pods/" + url.PathEscape(c.pod)
+}
+
+// do sends a request and decodes the response, if out is not nil.
+func (c *Client) do(ctx context.Context, method, path, contentType string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		jb, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(jb)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.host+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.tokenFile != "" {
+		token, err := os.ReadFile(c.tokenFile)
+		if err != nil {
+			return fmt.Errorf("can't read token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	rb, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// The API server returns a Status object with a useful message.
+		status := struct {
+			Message string `json:"message"`
+		}{}
+		if json.Unmarshal(rb, &status) == nil && status.Message != "" {
+			return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, status.Message)
+		}
+		return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	if out != nil {
+		if err := json.Unmarshal(rb, out); err != nil {
+			return fmt.Errorf("%s %s: can't decode response: %w", method, path, err)
+		}
+	}
+	return nil
+}
+
+// objectMeta is the subset of metav1.ObjectMeta which git-sync uses.
+type objectMeta struct {
+	Name        string             `json:"name,omitempty"`
+	Namespace   string             `json:"namespace,omitempty"`
+	UID         string             `json:"uid,omitempty"`
+	Annotations map[string]*string `json:"annotations,omitempty"`
+}
+
+// SetAnnotations updates annotations on the pod, leaving any others alone.
+// An empty value removes the annotation.
+func (c *Client) SetAnnotations(ctx context.Context, annotations map[string]string) error {
+	patch := struct {
+		Metadata objectMeta `json:"metadata"`
+	}{}
+	patch.Metadata.Annotations = map[string]*string{}
+	for k, v := range annotations {
+		if v == "" {
+			// JSON null removes the key in a merge patch.
+			patch.Metadata.Annotations[k] = nil
+			continue
+		}
+		patch.Metadata.Annotations[k] = &v
+	}
+	pod := struct {
+		Metadata objectMeta `json:"metadata"`
+	}{}
+	if err := c.do(ctx, http.MethodPatch, c.podPath(), "application/merge-patch+json", patch, &pod); err != nil {
+		return err
+	}
+	c.setPodUID(pod.Metadata.UID)
+	return nil
+}
+
+func (c *Client) setPodUID(uid string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if uid != "" {
+		c.podUID = uid
+	}
+}
+
+// getPodUID returns the pod's UID, fetching it if needed.
+func (c *Client) getPodUID(ctx context.Context) (string, error) {
+	c.mutex.Lock()
+	uid := c.podUID
+	c.mutex.Unlock()
+	if uid != "" {
+		return uid, nil
+	}
+	pod := struct {
+		Metadata objectMeta `json:"metadata"`
+	}{}
+	if err := c.do(ctx, http.MethodGet, c.podPath(), "", nil, &pod); err != nil {
+		return "", err
+	}
+	c.setPodUID(pod.Metadata.UID)
+	return pod.Metadata.UID, nil
+}
+
+// objectReference is the subset of corev1.ObjectReference which git-sync
+// uses.
+type objectReference struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+	UID        string `json:"uid,omitempty"`
+}
+
+// event is the subset of corev1.Event which git-sync uses.
+type event struct {
+	APIVersion         string          `json:"apiVersion"`
+	Kind               string          `json:"kind"`
+	Metadata           objectMeta      `json:"metadata"`
+	InvolvedObject     objectReference `json:"involvedObject"`
+	Reason             string          `json:"reason"`
+	Message            string          `json:"message"`
+	Type               string          `json:"type"`
+	Count              int             `json:"count"`
+	FirstTimestamp     string          `json:"firstTimestamp"`
+	LastTimestamp      string          `json:"lastTimestamp"`
+	Source             eventSource     `json:"source"`
+	ReportingComponent string          `json:"reportingComponent"`
+	ReportingInstance  string          `json:"reportingInstance"`
+}
+
+type eventSource struct {
+	Component string `json:"component"`
+}
+
+// maxMessage is the longest event message which the API server accepts.
+const maxMessage = 1024
+
+// RecordEvent creates an Event about the pod.  The eventType is one of the
+// EventType* values, and reason is a short CamelCase string (e.g.
+// "SyncFailed").
+func (c *Client) RecordEvent(ctx context.Context, eventType, reason, message string) error {
+	uid, err := c.getPodUID(ctx)
+	if err != nil {
+		return fmt.Errorf("can't get pod: %w", err)
+	}
+	if len(message) > maxMessage {
+		// Don't cut a multi-byte character in half.
+		n := maxMessage - 3
+		for n > 0 && !utf8.RuneStart(message[n]) {
+			n--
+		}
+		message = message[:n] + "..."
+	}
+	now := time.Now().UTC()
+	ref := objectReference{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Namespace:  c.namespace,
+		Name:       c.pod,
+		UID:        uid,
+	}
+	ev := event{
+		APIVersion: "v1",
+		Kind:       "Event",
+		Metadata: objectMeta{
+			// This is how client-go names events.
+			Name:      fmt.Sprintf("%s.%x", c.pod, now.UnixNano()),
+			Namespace: c.namespace,
+		},
+		InvolvedObject:     ref,
+		Reason:             reason,
+		Message:            message,
+		Type:               eventType,
+		Count:              1,
+		FirstTimestamp:     now.Format(time.RFC3339),
+		LastTimestamp:      now.Format(time.RFC3339),
+		Source:             eventSource{Component: component},
+		ReportingComponent: component,
+		ReportingInstance:  c.pod,
+	}
+	path := "/api/v1/namespaces/" + url.PathEscape(c.namespace) + "/events"
+	return c.do(ctx, http.MethodPost, path, "application/json", ev, nil)
+}
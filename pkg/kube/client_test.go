@@ -0,0 +1,198 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+)
+
+const podUID = "0b1c4c4e-2f5a-4b44-9d52-6f3c1f0a9e11"
+
+// fakeAPIServer records requests and serves a single pod.
+type fakeAPIServer struct {
+	mutex       sync.Mutex
+	annotations map[string]string
+	events      []map[string]any
+	tokens      []string
+	gets        int
+}
+
+func (f *fakeAPIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.tokens = append(f.tokens, r.Header.Get("Authorization"))
+	body, _ := io.ReadAll(r.Body)
+	podJSON := func() {
+		pod := map[string]any{
+			"metadata": map[string]any{
+				"name":        "pod",
+				"namespace":   "ns",
+				"uid":         podUID,
+				"annotations": f.annotations,
+			},
+		}
+		_ = json.NewEncoder(w).Encode(pod)
+	}
+
+	switch {
+	case r.URL.Path == "/api/v1/namespaces/ns/pods/pod" && r.Method == http.MethodGet:
+		f.gets++
+		podJSON()
+	case r.URL.Path == "/api/v1/namespaces/ns/pods/pod" && r.Method == http.MethodPatch:
+		if ct := r.Header.Get("Content-Type"); ct != "application/merge-patch+json" {
+			http.Error(w, "bad content type "+ct, http.StatusUnsupportedMediaType)
+			return
+		}
+		patch := struct {
+			Metadata struct {
+				Annotations map[string]*string `json:"annotations"`
+			} `json:"metadata"`
+		}{}
+		if err := json.Unmarshal(body, &patch); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for k, v := range patch.Metadata.Annotations {
+			if v == nil {
+				delete(f.annotations, k)
+			} else {
+				f.annotations[k] = *v
+			}
+		}
+		podJSON()
+	case r.URL.Path == "/api/v1/namespaces/ns/events" && r.Method == http.MethodPost:
+		ev := map[string]any{}
+		if err := json.Unmarshal(body, &ev); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.events = append(f.events, ev)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(body)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"kind":"Status","message":"not found"}`))
+	}
+}
+
+func newTestClient(t *testing.T, pod string) (*Client, *fakeAPIServer, string) {
+	t.Helper()
+	fake := &fakeAPIServer{annotations: map[string]string{"other": "value"}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("token-1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return NewClient(srv.URL, tokenFile, srv.Client(), "ns", pod), fake, tokenFile
+}
+
+func TestSetAnnotations(t *testing.T) {
+	c, fake, tokenFile := newTestClient(t, "pod")
+	ctx := context.Background()
+
+	if err := c.SetAnnotations(ctx, map[string]string{"git-sync/hash": "abc"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want, got := "abc", fake.annotations["git-sync/hash"]; want != got {
+		t.Errorf("expected annotation %q, got %q", want, got)
+	}
+	if want, got := "value", fake.annotations["other"]; want != got {
+		t.Errorf("other annotations should not change: expected %q, got %q", want, got)
+	}
+
+	// Tokens are re-read, so they can be rotated.
+	if err := os.WriteFile(tokenFile, []byte("token-2"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetAnnotations(ctx, map[string]string{"git-sync/hash": ""}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, found := fake.annotations["git-sync/hash"]; found {
+		t.Errorf("expected annotation to be removed")
+	}
+	if want, got := []string{"Bearer token-1", "Bearer token-2"}, fake.tokens; strings.Join(want, ",") != strings.Join(got, ",") {
+		t.Errorf("expected tokens %q, got %q", want, got)
+	}
+}
+
+func TestRecordEvent(t *testing.T) {
+	c, fake, _ := newTestClient(t, "pod")
+	ctx := context.Background()
+
+	if err := c.RecordEvent(ctx, EventTypeNormal, "Published", "published abc"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.RecordEvent(ctx, EventTypeWarning, "SyncFailed", strings.Repeat("x", 2000)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.RecordEvent(ctx, EventTypeWarning, "SyncFailed", strings.Repeat("é", 1000)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fake.events) != 3 {
+		t.Fatalf("expected 3 events, got %d", len(fake.events))
+	}
+	// The pod's UID is only fetched once.
+	if fake.gets != 1 {
+		t.Errorf("expected 1 GET, got %d", fake.gets)
+	}
+
+	ev := fake.events[0]
+	if want, got := "Published", ev["reason"]; want != got {
+		t.Errorf("expected reason %q, got %q", want, got)
+	}
+	if want, got := EventTypeNormal, ev["type"]; want != got {
+		t.Errorf("expected type %q, got %q", want, got)
+	}
+	obj := ev["involvedObject"].(map[string]any)
+	if obj["kind"] != "Pod" || obj["name"] != "pod" || obj["namespace"] != "ns" || obj["uid"] != podUID {
+		t.Errorf("unexpected involvedObject: %v", obj)
+	}
+	name := ev["metadata"].(map[string]any)["name"].(string)
+	if !strings.HasPrefix(name, "pod.") {
+		t.Errorf("unexpected event name %q", name)
+	}
+	if msg := fake.events[1]["message"].(string); len(msg) != maxMessage {
+		t.Errorf("expected message to be truncated to %d bytes, got %d", maxMessage, len(msg))
+	}
+	// Multi-byte characters are not cut in half.
+	if msg := fake.events[2]["message"].(string); len(msg) > maxMessage || !strings.HasSuffix(msg, "é...") || strings.ContainsRune(msg, utf8.RuneError) {
+		t.Errorf("expected message to be truncated between characters, got %d bytes: %q", len(msg), msg[len(msg)-10:])
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c, _, _ := newTestClient(t, "missing")
+	err := c.RecordEvent(context.Background(), EventTypeNormal, "Published", "published abc")
+	if err == nil {
+		t.Fatalf("unexpected success")
+	}
+	if !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected the API server's message, got %v", err)
+	}
+}
@@ -22,16 +22,26 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sync"
 
 	"github.com/go-logr/logr"
 	"github.com/go-logr/logr/funcr"
 )
 
-// Logger provides a logging interface.
+// Logger provides a logging interface.  Secrets which are added with
+// AddSecrets, and passwords in URLs, are redacted from everything it writes.
 type Logger struct {
 	logr.Logger
 	root      string
 	errorFile string
+	redactor  *Redactor
+
+	// Errors which stay in the error file until they are released, by key,
+	// and the order in which they were held.
+	heldMutex sync.Mutex
+	held      map[string][]byte
+	heldOrder []string
 }
 
 // New returns a logr.Logger.
@@ -41,8 +51,26 @@ func New(root string, errorFile string, verbosity int) *Logger {
 		LogTimestamp: true,
 		Verbosity:    verbosity,
 	}
-	inner := funcr.NewJSON(func(obj string) { fmt.Fprintln(os.Stderr, obj) }, opts)
-	return &Logger{Logger: inner, root: root, errorFile: errorFile}
+	redactor := NewRedactor()
+	inner := funcr.NewJSON(func(obj string) { fmt.Fprintln(os.Stderr, redactor.Redact(obj)) }, opts)
+	return &Logger{Logger: inner, root: root, errorFile: errorFile, redactor: redactor}
+}
+
+// AddSecrets adds secrets (e.g. passwords and tokens) to be redacted.
+func (l *Logger) AddSecrets(secrets ...string) {
+	l.redactor.Add(secrets...)
+}
+
+// SetSecrets replaces the secrets from source (e.g. a file, or the URL which
+// a token is for) to be redacted.  See Redactor.Set.
+func (l *Logger) SetSecrets(source string, secrets ...string) {
+	l.redactor.Set(source, secrets...)
+}
+
+// Redact returns s with all known secrets redacted.  This is for text which
+// is not logged, but is written somewhere else (e.g. hook output).
+func (l *Logger) Redact(s string) string {
+	return l.redactor.Redact(s)
 }
 
 // Error implements logr.Logger.Error.
@@ -51,6 +79,40 @@ func (l *Logger) Error(err error, msg string, kvList ...interface{}) {
 	if l.errorFile == "" {
 		return
 	}
+	l.writeContent(l.errorContent(err, msg, kvList...))
+}
+
+// HoldError logs an error like Error, and keeps it in the error file until
+// ReleaseError is called with the same key, even if DeleteErrorFile is
+// called in the meantime.  This is for errors which the next successful
+// sync does not resolve, e.g. a hook which gave up.
+func (l *Logger) HoldError(key string, err error, msg string, kvList ...interface{}) {
+	l.Logger.WithCallDepth(1).Error(err, msg, kvList...)
+	if l.errorFile == "" {
+		return
+	}
+	content := l.errorContent(err, msg, kvList...)
+	l.heldMutex.Lock()
+	if l.held == nil {
+		l.held = map[string][]byte{}
+	}
+	l.held[key] = content
+	l.heldOrder = append(slices.DeleteFunc(l.heldOrder, func(k string) bool { return k == key }), key)
+	l.heldMutex.Unlock()
+	l.writeContent(content)
+}
+
+// ReleaseError forgets an error which was held by HoldError.  The error file
+// is not changed until the next call to Error or DeleteErrorFile.
+func (l *Logger) ReleaseError(key string) {
+	l.heldMutex.Lock()
+	defer l.heldMutex.Unlock()
+	delete(l.held, key)
+	l.heldOrder = slices.DeleteFunc(l.heldOrder, func(k string) bool { return k == key })
+}
+
+// errorContent returns the error file's content for an error.
+func (l *Logger) errorContent(err error, msg string, kvList ...interface{}) []byte {
 	payload := struct {
 		Msg  string
 		Err  string
@@ -75,11 +137,9 @@ func (l *Logger) Error(err error, msg string, kvList ...interface{}) {
 	jb, err := json.Marshal(payload)
 	if err != nil {
 		l.Logger.Error(err, "can't encode error payload")
-		content := fmt.Sprintf("%v", err)
-		l.writeContent([]byte(content))
-	} else {
-		l.writeContent(jb)
+		return []byte(fmt.Sprintf("%v", err))
 	}
+	return jb
 }
 
 // ExportError exports the error to the error file if --export-error is enabled.
@@ -90,11 +150,22 @@ func (l *Logger) ExportError(content string) {
 	l.writeContent([]byte(content))
 }
 
-// DeleteErrorFile deletes the error file.
+// DeleteErrorFile deletes the error file.  If any errors are held (see
+// HoldError), the most recent one is written instead.
 func (l *Logger) DeleteErrorFile() {
 	if l.errorFile == "" {
 		return
 	}
+	l.heldMutex.Lock()
+	var held []byte
+	if n := len(l.heldOrder); n > 0 {
+		held = l.held[l.heldOrder[n-1]]
+	}
+	l.heldMutex.Unlock()
+	if held != nil {
+		l.writeContent(held)
+		return
+	}
 	errorFile := filepath.Join(l.root, l.errorFile)
 	if err := os.Remove(errorFile); err != nil {
 		if os.IsNotExist(err) {
@@ -124,7 +195,7 @@ func (l *Logger) writeContent(content []byte) {
 		}
 	}()
 
-	if _, err = tmpFile.Write(content); err != nil {
+	if _, err = tmpFile.Write([]byte(l.redactor.Redact(string(content)))); err != nil {
 		l.Logger.Error(err, "can't write to temporary error-file", "filename", tmpFile.Name())
 		return
 	}
@@ -0,0 +1,76 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHoldError(t *testing.T) {
+	root := t.TempDir()
+	errorFile := filepath.Join(root, "error.json")
+	l := New(root, "error.json", 0)
+
+	read := func() string {
+		t.Helper()
+		b, err := os.ReadFile(errorFile)
+		if os.IsNotExist(err) {
+			return ""
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	l.HoldError("hook a", errors.New("a failed"), "hook gave up")
+	if s := read(); !strings.Contains(s, "a failed") {
+		t.Errorf("expected held error, got %q", s)
+	}
+
+	// A sync error replaces it, but it comes back when the sync error is
+	// cleared.
+	l.Error(errors.New("sync failed"), "sync failed")
+	if s := read(); !strings.Contains(s, "sync failed") {
+		t.Errorf("expected sync error, got %q", s)
+	}
+	l.DeleteErrorFile()
+	if s := read(); !strings.Contains(s, "a failed") {
+		t.Errorf("expected held error, got %q", s)
+	}
+
+	// The most recently held error wins.
+	l.HoldError("hook b", errors.New("b failed"), "hook gave up")
+	l.DeleteErrorFile()
+	if s := read(); !strings.Contains(s, "b failed") {
+		t.Errorf("expected latest held error, got %q", s)
+	}
+	l.ReleaseError("hook b")
+	l.DeleteErrorFile()
+	if s := read(); !strings.Contains(s, "a failed") {
+		t.Errorf("expected remaining held error, got %q", s)
+	}
+
+	l.ReleaseError("hook a")
+	l.DeleteErrorFile()
+	if s := read(); s != "" {
+		t.Errorf("expected no error file, got %q", s)
+	}
+}
@@ -0,0 +1,374 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package vault is a minimal client for the parts of the HashiCorp Vault API
+// which git-sync uses to get credentials: logging in, renewing its token,
+// and reading KV version 2 secrets.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Auth is a way to log in to Vault.
+type Auth interface {
+	// Method describes the auth method, for logs.
+	Method() string
+	// login returns the path and body of a login request.
+	login() (string, any, error)
+}
+
+// KubernetesAuth logs in with a Kubernetes service account token.
+type KubernetesAuth struct {
+	// Where the auth method is mounted.  If empty, "kubernetes" is used.
+	Mount string
+	// The Vault role to log in as.
+	Role string
+	// A file holding the service account token.  This is read for every
+	// login, so projected tokens can be rotated.
+	TokenFile string
+}
+
+var _ Auth = KubernetesAuth{}
+
+// Method implements Auth.
+func (a KubernetesAuth) Method() string {
+	return "kubernetes"
+}
+
+func (a KubernetesAuth) login() (string, any, error) {
+	jwt, err := os.ReadFile(a.TokenFile)
+	if err != nil {
+		return "", nil, fmt.Errorf("can't read service account token: %w", err)
+	}
+	body := map[string]string{
+		"role": a.Role,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	}
+	return loginPath(a.Mount, "kubernetes"), body, nil
+}
+
+// AppRoleAuth logs in with an AppRole role ID and secret ID.
+type AppRoleAuth struct {
+	// Where the auth method is mounted.  If empty, "approle" is used.
+	Mount  string
+	RoleID string
+	// A file holding the secret ID.  This is read for every login, so it can
+	// be rotated.
+	SecretIDFile string
+}
+
+var _ Auth = AppRoleAuth{}
+
+// Method implements Auth.
+func (a AppRoleAuth) Method() string {
+	return "approle"
+}
+
+func (a AppRoleAuth) login() (string, any, error) {
+	body := map[string]string{
+		"role_id": a.RoleID,
+	}
+	if a.SecretIDFile != "" {
+		secretID, err := os.ReadFile(a.SecretIDFile)
+		if err != nil {
+			return "", nil, fmt.Errorf("can't read AppRole secret ID: %w", err)
+		}
+		body["secret_id"] = strings.TrimSpace(string(secretID))
+	}
+	return loginPath(a.Mount, "approle"), body, nil
+}
+
+func loginPath(mount, def string) string {
+	if mount == "" {
+		mount = def
+	}
+	return "/v1/auth/" + strings.Trim(mount, "/") + "/login"
+}
+
+// Client talks to Vault.  It logs in when it first needs to, and renews or
+// replaces its token as it expires.  It is safe for concurrent use.
+type Client struct {
+	// The base URL of Vault, e.g. "https://vault:8200".
+	addr string
+	// A Vault Enterprise namespace, or "".
+	namespace  string
+	httpClient *http.Client
+	auth       Auth
+
+	mutex sync.Mutex
+	// The current token, and its lease.
+	token       string
+	tokenTTL    time.Duration
+	tokenExpiry time.Time
+	renewable   bool
+}
+
+// NewClient returns a Client for the Vault server at addr (a URL), which
+// logs in with auth.  If httpClient is nil, http.DefaultClient is used.
+func NewClient(addr, namespace string, httpClient *http.Client, auth Auth) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		addr:       strings.TrimSuffix(addr, "/"),
+		namespace:  namespace,
+		httpClient: httpClient,
+		auth:       auth,
+	}
+}
+
+// statusError is returned for HTTP errors, so callers can check the code.
+type statusError struct {
+	code int
+	msg  string
+}
+
+func (e *statusError) Error() string {
+	return e.msg
+}
+
+// do sends a request and decodes the response, if out is not nil.
+func (c *Client) do(ctx context.Context, method, path, token string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		jb, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(jb)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.addr+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if c.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.namespace)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	rb, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// Vault returns a list of errors.
+		status := struct {
+			Errors []string `json:"errors"`
+		}{}
+		if json.Unmarshal(rb, &status) == nil && len(status.Errors) > 0 {
+			return &statusError{resp.StatusCode, fmt.Sprintf("%s %s: %s: %s", method, path, resp.Status, strings.Join(status.Errors, "; "))}
+		}
+		return &statusError{resp.StatusCode, fmt.Sprintf("%s %s: %s", method, path, resp.Status)}
+	}
+	if out != nil {
+		if err := json.Unmarshal(rb, out); err != nil {
+			return fmt.Errorf("%s %s: can't decode response: %w", method, path, err)
+		}
+	}
+	return nil
+}
+
+// authResponse is the part of a login or renew response which describes the
+// token.
+type authResponse struct {
+	Auth *struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int64  `json:"lease_duration"`
+		Renewable     bool   `json:"renewable"`
+	} `json:"auth"`
+}
+
+// setToken records the token from resp.  The caller must hold the mutex.
+func (c *Client) setToken(resp authResponse, now time.Time) error {
+	if resp.Auth == nil || resp.Auth.ClientToken == "" {
+		return fmt.Errorf("vault response has no token")
+	}
+	c.token = resp.Auth.ClientToken
+	c.tokenTTL = time.Duration(resp.Auth.LeaseDuration) * time.Second
+	c.tokenExpiry = time.Time{}
+	if c.tokenTTL > 0 {
+		c.tokenExpiry = now.Add(c.tokenTTL)
+	}
+	c.renewable = resp.Auth.Renewable
+	return nil
+}
+
+// login gets a new token.  The caller must hold the mutex.
+func (c *Client) login(ctx context.Context) error {
+	path, body, err := c.auth.login()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	resp := authResponse{}
+	if err := c.do(ctx, http.MethodPost, path, "", body, &resp); err != nil {
+		return fmt.Errorf("can't log in to vault with %s auth: %w", c.auth.Method(), err)
+	}
+	return c.setToken(resp, now)
+}
+
+// renew extends the current token's lease.  The caller must hold the mutex.
+func (c *Client) renew(ctx context.Context) error {
+	now := time.Now()
+	resp := authResponse{}
+	if err := c.do(ctx, http.MethodPost, "/v1/auth/token/renew-self", c.token, map[string]string{}, &resp); err != nil {
+		return err
+	}
+	return c.setToken(resp, now)
+}
+
+// The token is replaced if it has less than this left.
+const minTokenLife = 30 * time.Second
+
+// getToken returns a usable token.  A token is renewed once half of its lease
+// has passed, and a new one is requested if it can't be renewed or is about
+// to expire anyway (e.g. it reached its max TTL).
+func (c *Client) getToken(ctx context.Context) (string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	if c.token != "" && !c.tokenExpiry.IsZero() {
+		left := c.tokenExpiry.Sub(now)
+		if c.renewable && left > minTokenLife && left < c.tokenTTL/2 {
+			if err := c.renew(ctx); err != nil || c.tokenExpiry.Sub(now) < minTokenLife {
+				c.token = ""
+			}
+		} else if left < minTokenLife {
+			c.token = ""
+		}
+	}
+	if c.token == "" {
+		if err := c.login(ctx); err != nil {
+			return "", err
+		}
+	}
+	return c.token, nil
+}
+
+// Token returns the client's current Vault token, or "" if it has not
+// logged in.  This is for redacting it, not for making requests.
+func (c *Client) Token() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.token
+}
+
+// forgetToken discards token, if it is still the current one, so the next
+// request logs in again.
+func (c *Client) forgetToken(token string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.token == token {
+		c.token = ""
+	}
+}
+
+// Secret is a KV version 2 secret.
+type Secret struct {
+	// The secret's data.  Non-string values are encoded as JSON.
+	Data map[string]string
+	// The version which was read.
+	Version int
+	// How long the secret may be cached, or zero if it does not say.
+	LeaseDuration time.Duration
+}
+
+// ReadKV2 reads the latest version of the secret at path in the KV version
+// 2 engine mounted at mount.
+func (c *Client) ReadKV2(ctx context.Context, mount, path string) (*Secret, error) {
+	apiPath := "/v1/" + strings.Trim(mount, "/") + "/data/" + escapePath(strings.Trim(path, "/"))
+	resp := struct {
+		LeaseDuration int64 `json:"lease_duration"`
+		Data          *struct {
+			Data     map[string]any `json:"data"`
+			Metadata struct {
+				Version int `json:"version"`
+			} `json:"metadata"`
+		} `json:"data"`
+	}{}
+
+	// If the token was revoked early, log in again once.
+	for attempt := 0; ; attempt++ {
+		token, err := c.getToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+		err = c.do(ctx, http.MethodGet, apiPath, token, nil, &resp)
+		var serr *statusError
+		if errors.As(err, &serr) && serr.code == http.StatusForbidden && attempt == 0 {
+			c.forgetToken(token)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can't read vault secret: %w", err)
+		}
+		break
+	}
+	if resp.Data == nil || resp.Data.Data == nil {
+		// A deleted version has no data.
+		return nil, fmt.Errorf("vault secret %s/%s has no data", mount, path)
+	}
+
+	secret := &Secret{
+		Data:          map[string]string{},
+		Version:       resp.Data.Metadata.Version,
+		LeaseDuration: time.Duration(resp.LeaseDuration) * time.Second,
+	}
+	for k, v := range resp.Data.Data {
+		if s, ok := v.(string); ok {
+			secret.Data[k] = s
+			continue
+		}
+		jb, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		secret.Data[k] = string(jb)
+	}
+	return secret, nil
+}
+
+// escapePath escapes each element of a slash-separated path.
+func escapePath(path string) string {
+	parts := strings.Split(path, "/")
+	for i := range parts {
+		parts[i] = url.PathEscape(parts[i])
+	}
+	return strings.Join(parts, "/")
+}
@@ -0,0 +1,296 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeVault is a stand-in for a Vault server, with one KV v2 secret at
+// secret/git.
+type fakeVault struct {
+	mutex      sync.Mutex
+	logins     []map[string]string
+	loginPaths []string
+	renewals   int
+	reads      int
+	namespaces []string
+	// Tokens which are accepted.
+	valid map[string]bool
+	// The number of tokens issued, used to make new ones.
+	issued    int
+	renewable bool
+	// The secret data, or nil if it was deleted.
+	data map[string]any
+}
+
+func newFakeVault() *fakeVault {
+	return &fakeVault{
+		valid:     map[string]bool{},
+		renewable: true,
+		data:      map[string]any{"username": "me", "password": "pass", "n": 42},
+	}
+}
+
+func (f *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.namespaces = append(f.namespaces, r.Header.Get("X-Vault-Namespace"))
+	fail := func(code int, msg string) {
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(map[string]any{"errors": []string{msg}})
+	}
+	auth := func(token string) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"auth": map[string]any{
+				"client_token":   token,
+				"lease_duration": 3600,
+				"renewable":      f.renewable,
+			},
+		})
+	}
+
+	switch {
+	case r.Method == http.MethodPost && (r.URL.Path == "/v1/auth/kubernetes/login" || r.URL.Path == "/v1/auth/approle/login" || r.URL.Path == "/v1/auth/k8s/login"):
+		body := map[string]string{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			fail(http.StatusBadRequest, err.Error())
+			return
+		}
+		f.logins = append(f.logins, body)
+		f.loginPaths = append(f.loginPaths, r.URL.Path)
+		if body["jwt"] == "bad" {
+			fail(http.StatusForbidden, "permission denied")
+			return
+		}
+		f.issued++
+		token := fmt.Sprintf("token-%d", f.issued)
+		f.valid[token] = true
+		auth(token)
+	case r.Method == http.MethodPost && r.URL.Path == "/v1/auth/token/renew-self":
+		token := r.Header.Get("X-Vault-Token")
+		if !f.valid[token] {
+			fail(http.StatusForbidden, "permission denied")
+			return
+		}
+		f.renewals++
+		auth(token)
+	case r.Method == http.MethodGet && r.URL.Path == "/v1/secret/data/git":
+		if !f.valid[r.Header.Get("X-Vault-Token")] {
+			fail(http.StatusForbidden, "permission denied")
+			return
+		}
+		f.reads++
+		var data any
+		if f.data != nil {
+			data = f.data
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"lease_duration": 0,
+			"data": map[string]any{
+				"data":     data,
+				"metadata": map[string]any{"version": 3},
+			},
+		})
+	default:
+		fail(http.StatusNotFound, "no handler for "+r.Method+" "+r.URL.Path)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadKV2(t *testing.T) {
+	fake := newFakeVault()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	writeFile(t, tokenFile, "jwt-1\n")
+	c := NewClient(srv.URL+"/", "team", srv.Client(), KubernetesAuth{Role: "git-sync", TokenFile: tokenFile})
+
+	ctx := context.Background()
+	secret, err := c.ReadKV2(ctx, "secret", "/git/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expData := map[string]string{"username": "me", "password": "pass", "n": "42"}
+	if !reflect.DeepEqual(expData, secret.Data) {
+		t.Errorf("expected data %v, got %v", expData, secret.Data)
+	}
+	if secret.Version != 3 {
+		t.Errorf("expected version 3, got %d", secret.Version)
+	}
+	expLogin := map[string]string{"role": "git-sync", "jwt": "jwt-1"}
+	if len(fake.logins) != 1 || !reflect.DeepEqual(expLogin, fake.logins[0]) {
+		t.Errorf("expected one login with %v, got %v", expLogin, fake.logins)
+	}
+	if fake.namespaces[0] != "team" {
+		t.Errorf("expected namespace header, got %q", fake.namespaces[0])
+	}
+
+	// The token is reused.
+	if _, err := c.ReadKV2(ctx, "secret", "git"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fake.logins) != 1 || fake.reads != 2 {
+		t.Errorf("expected 1 login and 2 reads, got %d and %d", len(fake.logins), fake.reads)
+	}
+
+	// A deleted secret is an error.
+	fake.data = nil
+	if _, err := c.ReadKV2(ctx, "secret", "git"); err == nil {
+		t.Errorf("expected error for deleted secret")
+	}
+}
+
+func TestTokenLifecycle(t *testing.T) {
+	fake := newFakeVault()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	writeFile(t, tokenFile, "jwt-1")
+	c := NewClient(srv.URL, "", srv.Client(), KubernetesAuth{Mount: "k8s", Role: "r", TokenFile: tokenFile})
+	ctx := context.Background()
+	read := func() {
+		t.Helper()
+		if _, err := c.ReadKV2(ctx, "secret", "git"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	read()
+	if fake.loginPaths[0] != "/v1/auth/k8s/login" {
+		t.Errorf("expected login at mount k8s, got %q", fake.loginPaths[0])
+	}
+
+	// Past half of the TTL, the token is renewed.
+	c.tokenExpiry = time.Now().Add(10 * time.Minute)
+	read()
+	if fake.renewals != 1 || len(fake.logins) != 1 {
+		t.Errorf("expected 1 renewal and 1 login, got %d and %d", fake.renewals, len(fake.logins))
+	}
+	if left := time.Until(c.tokenExpiry); left < 59*time.Minute {
+		t.Errorf("expected renewed expiry, got %v left", left)
+	}
+
+	// Near expiry, it logs in again, with the current SA token.
+	writeFile(t, tokenFile, "jwt-2")
+	c.tokenExpiry = time.Now().Add(10 * time.Second)
+	read()
+	if len(fake.logins) != 2 || fake.logins[1]["jwt"] != "jwt-2" {
+		t.Errorf("expected a second login with the new token, got %v", fake.logins)
+	}
+
+	// If renewal fails, it logs in again.
+	fake.valid = map[string]bool{}
+	c.tokenExpiry = time.Now().Add(10 * time.Minute)
+	read()
+	if len(fake.logins) != 3 || fake.renewals != 1 {
+		t.Errorf("expected a third login and no renewal, got %d and %d", len(fake.logins), fake.renewals)
+	}
+
+	// If the token is revoked, it logs in again.
+	fake.valid = map[string]bool{}
+	read()
+	if len(fake.logins) != 4 {
+		t.Errorf("expected a fourth login, got %d", len(fake.logins))
+	}
+
+	// Tokens which can't be renewed are replaced near expiry.
+	fake.renewable = false
+	c.renewable = false
+	c.tokenExpiry = time.Now().Add(10 * time.Minute)
+	read()
+	if len(fake.logins) != 4 || fake.renewals != 1 {
+		t.Errorf("expected no new login or renewal, got %d and %d", len(fake.logins), fake.renewals)
+	}
+	c.tokenExpiry = time.Now().Add(10 * time.Second)
+	read()
+	if len(fake.logins) != 5 {
+		t.Errorf("expected a fifth login, got %d", len(fake.logins))
+	}
+}
+
+func TestAppRoleAuth(t *testing.T) {
+	fake := newFakeVault()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	secretIDFile := filepath.Join(t.TempDir(), "secret-id")
+	writeFile(t, secretIDFile, "sid\n")
+	c := NewClient(srv.URL, "", srv.Client(), AppRoleAuth{RoleID: "rid", SecretIDFile: secretIDFile})
+	if _, err := c.ReadKV2(context.Background(), "secret", "git"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expLogin := map[string]string{"role_id": "rid", "secret_id": "sid"}
+	if len(fake.logins) != 1 || !reflect.DeepEqual(expLogin, fake.logins[0]) {
+		t.Errorf("expected one login with %v, got %v", expLogin, fake.logins)
+	}
+	if fake.loginPaths[0] != "/v1/auth/approle/login" {
+		t.Errorf("expected approle login, got %q", fake.loginPaths[0])
+	}
+}
+
+func TestLoginErrors(t *testing.T) {
+	fake := newFakeVault()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	badFile := filepath.Join(dir, "bad")
+	writeFile(t, badFile, "bad")
+
+	cases := []struct {
+		name string
+		auth Auth
+	}{{
+		name: "denied",
+		auth: KubernetesAuth{Role: "r", TokenFile: badFile},
+	}, {
+		name: "no-token-file",
+		auth: KubernetesAuth{Role: "r", TokenFile: filepath.Join(dir, "missing")},
+	}, {
+		name: "no-secret-id-file",
+		auth: AppRoleAuth{RoleID: "r", SecretIDFile: filepath.Join(dir, "missing")},
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewClient(srv.URL, "", srv.Client(), tc.auth)
+			if _, err := c.ReadKV2(context.Background(), "secret", "git"); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
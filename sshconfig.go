@@ -0,0 +1,259 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// sshHost is the SSH configuration for connections to specific hosts.  These
+// are rendered into an ssh_config file.
+type sshHost struct {
+	// One or more ssh_config Host patterns, separated by spaces.
+	Host     string `json:"host"`
+	HostName string `json:"hostname,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	User     string `json:"user,omitempty"`
+	// If not specified, --ssh-key-file is used.
+	KeyFiles []string `json:"key-files,omitempty"`
+	// An SSH certificate for one of KeyFiles.
+	CertFile string `json:"cert-file,omitempty"`
+	// How to verify the host's key.  If none of these are specified,
+	// --ssh-known-hosts and --ssh-known-hosts-file are used.
+	KnownHostsFile      string   `json:"known-hosts-file,omitempty"`
+	HostKeyFingerprints []string `json:"host-key-fingerprints,omitempty"`
+	CertAuthorityFile   string   `json:"cert-authority-file,omitempty"`
+	// Only one of these may be specified.
+	ProxyJump    string `json:"proxy-jump,omitempty"`
+	ProxyCommand string `json:"proxy-command,omitempty"`
+}
+
+func (h sshHost) String() string {
+	jb, err := json.Marshal(h)
+	if err != nil {
+		return fmt.Sprintf("<encoding error: %v>", err)
+	}
+	return string(jb)
+}
+
+// patterns returns the Host patterns for h.
+func (h sshHost) patterns() []string {
+	return strings.Fields(h.Host)
+}
+
+// verifiesHostKeys returns true if h specifies how to verify host keys.
+func (h sshHost) verifiesHostKeys() bool {
+	return h.KnownHostsFile != "" || len(h.HostKeyFingerprints) > 0 || h.CertAuthorityFile != ""
+}
+
+// files returns the files which h reads.
+func (h sshHost) files() []string {
+	ret := append([]string{}, h.KeyFiles...)
+	for _, f := range []string{h.CertFile, h.KnownHostsFile, h.CertAuthorityFile} {
+		if f != "" {
+			ret = append(ret, f)
+		}
+	}
+	return ret
+}
+
+// This is the format ssh uses to print key fingerprints.
+var sshFingerprintRE = regexp.MustCompile(`^SHA256:[A-Za-z0-9+/]{43}$`)
+
+// validateSSHHosts checks that hosts can be safely rendered into an
+// ssh_config file.
+func validateSSHHosts(hosts []sshHost) error {
+	for i, h := range hosts {
+		if len(h.patterns()) == 0 {
+			return fmt.Errorf("ssh host %d: host must be specified", i)
+		}
+		// Values are written to ssh_config, one per line, and files are
+		// quoted.
+		values := append([]string{h.Host, h.HostName, h.User, h.ProxyJump, h.ProxyCommand}, h.files()...)
+		for _, v := range values {
+			if strings.ContainsAny(v, "\r\n\x00") {
+				return fmt.Errorf("ssh host %q: values may not contain newlines", h.Host)
+			}
+		}
+		for _, v := range append([]string{h.Host, h.HostName, h.User, h.ProxyJump}, h.files()...) {
+			if strings.Contains(v, `"`) {
+				return fmt.Errorf("ssh host %q: values may not contain quotes", h.Host)
+			}
+		}
+		if strings.ContainsAny(h.HostName+h.User+h.ProxyJump, " \t") {
+			return fmt.Errorf("ssh host %q: hostname, user, and proxy-jump may not contain spaces", h.Host)
+		}
+		if h.Port < 0 || h.Port > 65535 {
+			return fmt.Errorf("ssh host %q: invalid port %d", h.Host, h.Port)
+		}
+		if h.CertFile != "" && len(h.KeyFiles) == 0 {
+			return fmt.Errorf("ssh host %q: cert-file requires key-files", h.Host)
+		}
+		for _, fp := range h.HostKeyFingerprints {
+			if !sshFingerprintRE.MatchString(fp) {
+				return fmt.Errorf("ssh host %q: invalid host key fingerprint %q (expected SHA256:<base64>)", h.Host, fp)
+			}
+		}
+		if h.ProxyJump != "" && h.ProxyCommand != "" {
+			return fmt.Errorf("ssh host %q: only one of proxy-jump and proxy-command may be specified", h.Host)
+		}
+	}
+	return nil
+}
+
+// sshConfigFiles is a rendered ssh_config and the files it refers to, keyed
+// by their names in the config directory.
+type sshConfigFiles map[string]string
+
+const sshConfigFile = "ssh_config"
+
+// renderSSHConfig renders an ssh_config, to be written in dir, for hosts.
+// Connections to other hosts use keyFiles and, if useKnownHosts is true,
+// knownHostsFile.
+//
+// Host keys can be pinned by fingerprint, which ssh does not support
+// directly, so a small script is generated for KnownHostsCommand.  It is
+// given the key which the server offered, and prints it as a known_hosts
+// line if the fingerprint is one of the pinned ones.
+func renderSSHConfig(dir string, hosts []sshHost, keyFiles []string, useKnownHosts bool, knownHostsFile string) (sshConfigFiles, error) {
+	files := sshConfigFiles{}
+	path := func(name string) string {
+		return dir + "/" + name
+	}
+	quote := func(paths ...string) string {
+		q := []string{}
+		for _, p := range paths {
+			q = append(q, `"`+p+`"`)
+		}
+		return strings.Join(q, " ")
+	}
+	defaultKeys := func(b *strings.Builder) {
+		for _, f := range keyFiles {
+			fmt.Fprintf(b, "  IdentityFile %s\n", quote(f))
+		}
+	}
+	defaultHostKeys := func(b *strings.Builder) {
+		if useKnownHosts {
+			fmt.Fprintf(b, "  StrictHostKeyChecking yes\n")
+			fmt.Fprintf(b, "  UserKnownHostsFile %s\n", quote(knownHostsFile))
+		} else {
+			fmt.Fprintf(b, "  StrictHostKeyChecking no\n")
+		}
+	}
+
+	b := &strings.Builder{}
+	b.WriteString("# Generated by git-sync.\n")
+	others := []string{"*"}
+	for i, h := range hosts {
+		for _, p := range h.patterns() {
+			if !strings.HasPrefix(p, "!") {
+				others = append(others, "!"+p)
+			}
+		}
+
+		fmt.Fprintf(b, "\nHost %s\n", strings.Join(h.patterns(), " "))
+		if h.HostName != "" {
+			fmt.Fprintf(b, "  HostName %s\n", h.HostName)
+		}
+		if h.Port != 0 {
+			fmt.Fprintf(b, "  Port %d\n", h.Port)
+		}
+		if h.User != "" {
+			fmt.Fprintf(b, "  User %s\n", h.User)
+		}
+
+		if len(h.KeyFiles) > 0 {
+			b.WriteString("  IdentitiesOnly yes\n")
+			for _, f := range h.KeyFiles {
+				fmt.Fprintf(b, "  IdentityFile %s\n", quote(f))
+			}
+			if h.CertFile != "" {
+				fmt.Fprintf(b, "  CertificateFile %s\n", quote(h.CertFile))
+			}
+		} else {
+			defaultKeys(b)
+		}
+
+		if h.verifiesHostKeys() {
+			knownHosts := []string{}
+			if h.KnownHostsFile != "" {
+				knownHosts = append(knownHosts, h.KnownHostsFile)
+			}
+			if h.CertAuthorityFile != "" {
+				// This file is only used for this host, so the CA can be
+				// trusted for any name.
+				ca, err := os.ReadFile(h.CertAuthorityFile)
+				if err != nil {
+					return nil, fmt.Errorf("ssh host %q: can't read cert-authority-file: %w", h.Host, err)
+				}
+				lines := []string{}
+				for _, line := range strings.Split(string(ca), "\n") {
+					if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+						lines = append(lines, "@cert-authority * "+line)
+					}
+				}
+				if len(lines) == 0 {
+					return nil, fmt.Errorf("ssh host %q: cert-authority-file %s has no keys", h.Host, h.CertAuthorityFile)
+				}
+				name := fmt.Sprintf("known_hosts.%d", i)
+				files[name] = strings.Join(lines, "\n") + "\n"
+				knownHosts = append(knownHosts, path(name))
+			}
+			b.WriteString("  StrictHostKeyChecking yes\n")
+			if len(knownHosts) > 0 {
+				fmt.Fprintf(b, "  UserKnownHostsFile %s\n", quote(knownHosts...))
+			} else {
+				b.WriteString("  UserKnownHostsFile /dev/null\n")
+			}
+			b.WriteString("  GlobalKnownHostsFile /dev/null\n")
+			if len(h.HostKeyFingerprints) > 0 {
+				name := fmt.Sprintf("pinned_host_keys.%d.sh", i)
+				files[name] = fmt.Sprintf("#!/bin/sh\n"+
+					"# Generated by git-sync.  Prints the offered host key if it is pinned.\n"+
+					"case \"$1\" in\n"+
+					"%s) echo \"$2 $3 $4\" ;;\n"+
+					"esac\n", strings.Join(h.HostKeyFingerprints, "|"))
+				fmt.Fprintf(b, "  KnownHostsCommand /bin/sh %s %%f %%H %%t %%K\n", quote(path(name)))
+			}
+		} else {
+			defaultHostKeys(b)
+		}
+
+		if h.ProxyJump != "" {
+			fmt.Fprintf(b, "  ProxyJump %s\n", h.ProxyJump)
+		}
+		if h.ProxyCommand != "" {
+			fmt.Fprintf(b, "  ProxyCommand %s\n", h.ProxyCommand)
+		}
+	}
+
+	fmt.Fprintf(b, "\nHost %s\n", strings.Join(others, " "))
+	defaultKeys(b)
+	defaultHostKeys(b)
+
+	files[sshConfigFile] = b.String()
+	return files, nil
+}
+
+// pflagSSHHostSlice is like pflag.StringSlice().
+func pflagSSHHostSlice(name, def, usage string) *[]sshHost {
+	return pflagJSONSlice[sshHost](name, "sshHostSlice", def, usage)
+}
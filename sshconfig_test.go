@@ -0,0 +1,244 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	testFingerprint1 = "SHA256:nThbg6kXUpJWGl7E1IGOCspRomTxdCARLviKw6E5SY8"
+	testFingerprint2 = "SHA256:p2QAMXNIC1TJYWeIOttrVc98/R1BUFWu3/LiyKgUfQM"
+)
+
+func TestValidateSSHHosts(t *testing.T) {
+	cases := []struct {
+		name string
+		host sshHost
+		fail bool
+	}{{
+		name: "minimal",
+		host: sshHost{Host: "github.com"},
+	}, {
+		name: "full",
+		host: sshHost{
+			Host:                "gitlab.corp *.gitlab.corp",
+			HostName:            "10.0.0.1",
+			Port:                2222,
+			User:                "git",
+			KeyFiles:            []string{"/keys/id_ed25519"},
+			CertFile:            "/keys/id_ed25519-cert.pub",
+			KnownHostsFile:      "/keys/known_hosts",
+			HostKeyFingerprints: []string{testFingerprint1, testFingerprint2},
+			CertAuthorityFile:   "/keys/ca.pub",
+			ProxyJump:           "bastion.corp",
+		},
+	}, {
+		name: "proxy-command",
+		host: sshHost{Host: "x", ProxyCommand: `nc -X connect -x "proxy:3128" %h %p`},
+	}, {
+		name: "no-host",
+		host: sshHost{HostName: "x"},
+		fail: true,
+	}, {
+		name: "blank-host",
+		host: sshHost{Host: "  "},
+		fail: true,
+	}, {
+		name: "newline",
+		host: sshHost{Host: "x", ProxyCommand: "nc %h %p\nHost *"},
+		fail: true,
+	}, {
+		name: "quote-in-file",
+		host: sshHost{Host: "x", KeyFiles: []string{`/keys/"id"`}},
+		fail: true,
+	}, {
+		name: "space-in-user",
+		host: sshHost{Host: "x", User: "git IdentityFile /etc/passwd"},
+		fail: true,
+	}, {
+		name: "bad-port",
+		host: sshHost{Host: "x", Port: 65536},
+		fail: true,
+	}, {
+		name: "cert-without-key",
+		host: sshHost{Host: "x", CertFile: "/keys/id-cert.pub"},
+		fail: true,
+	}, {
+		name: "bad-fingerprint",
+		host: sshHost{Host: "x", HostKeyFingerprints: []string{"MD5:00:11:22"}},
+		fail: true,
+	}, {
+		name: "both-proxies",
+		host: sshHost{Host: "x", ProxyJump: "bastion", ProxyCommand: "nc %h %p"},
+		fail: true,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateSSHHosts([]sshHost{tc.host})
+			if err != nil && !tc.fail {
+				t.Errorf("unexpected error: %v", err)
+			} else if err == nil && tc.fail {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
+
+func TestRenderSSHConfig(t *testing.T) {
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pub")
+	if err := os.WriteFile(caFile, []byte("# comment\nssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIA ca\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	hosts := []sshHost{{
+		Host:     "github.com",
+		KeyFiles: []string{"/keys/github"},
+	}, {
+		Host:              "*.corp",
+		User:              "git",
+		Port:              2222,
+		KeyFiles:          []string{"/keys/corp"},
+		CertFile:          "/keys/corp-cert.pub",
+		CertAuthorityFile: caFile,
+		ProxyJump:         "bastion.example.com",
+	}, {
+		Host:                "pinned.example.com",
+		HostKeyFingerprints: []string{testFingerprint1, testFingerprint2},
+	}}
+
+	files, err := renderSSHConfig(dir, hosts, []string{"/etc/git-secret/ssh"}, true, "/etc/git-secret/known_hosts")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expConfig := `# Generated by git-sync.
+
+Host github.com
+  IdentitiesOnly yes
+  IdentityFile "/keys/github"
+  StrictHostKeyChecking yes
+  UserKnownHostsFile "/etc/git-secret/known_hosts"
+
+Host *.corp
+  Port 2222
+  User git
+  IdentitiesOnly yes
+  IdentityFile "/keys/corp"
+  CertificateFile "/keys/corp-cert.pub"
+  StrictHostKeyChecking yes
+  UserKnownHostsFile "` + dir + `/known_hosts.1"
+  GlobalKnownHostsFile /dev/null
+  ProxyJump bastion.example.com
+
+Host pinned.example.com
+  IdentityFile "/etc/git-secret/ssh"
+  StrictHostKeyChecking yes
+  UserKnownHostsFile /dev/null
+  GlobalKnownHostsFile /dev/null
+  KnownHostsCommand /bin/sh "` + dir + `/pinned_host_keys.2.sh" %f %H %t %K
+
+Host * !github.com !*.corp !pinned.example.com
+  IdentityFile "/etc/git-secret/ssh"
+  StrictHostKeyChecking yes
+  UserKnownHostsFile "/etc/git-secret/known_hosts"
+`
+	if got := files[sshConfigFile]; got != expConfig {
+		t.Errorf("wrong ssh_config:\nexpected:\n%s\ngot:\n%s", expConfig, got)
+	}
+	if got, exp := files["known_hosts.1"], "@cert-authority * ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIA ca\n"; got != exp {
+		t.Errorf("wrong known_hosts: expected %q, got %q", exp, got)
+	}
+	if len(files) != 3 {
+		t.Errorf("expected 3 files, got %d", len(files))
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The pinning script only prints keys with a pinned fingerprint.
+	pin := func(fp string) string {
+		t.Helper()
+		out, err := exec.Command("/bin/sh", filepath.Join(dir, "pinned_host_keys.2.sh"), fp, "[pinned.example.com]:22", "ssh-ed25519", "AAAA").Output()
+		if err != nil {
+			t.Fatalf("pinning script failed: %v", err)
+		}
+		return string(out)
+	}
+	if got, exp := pin(testFingerprint2), "[pinned.example.com]:22 ssh-ed25519 AAAA\n"; got != exp {
+		t.Errorf("pinned key: expected %q, got %q", exp, got)
+	}
+	if got := pin("SHA256:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"); got != "" {
+		t.Errorf("unpinned key: expected no output, got %q", got)
+	}
+	if got := pin(""); got != "" {
+		t.Errorf("no key: expected no output, got %q", got)
+	}
+
+	// If ssh is available, make sure it understands the config.
+	ssh, err := exec.LookPath("ssh")
+	if err != nil {
+		return
+	}
+	out, err := exec.Command(ssh, "-G", "-F", filepath.Join(dir, sshConfigFile), "git.corp").CombinedOutput()
+	if err != nil {
+		t.Fatalf("ssh -G failed: %v: %s", err, out)
+	}
+	for _, exp := range []string{"port 2222", "user git", "identityfile /keys/corp", "proxyjump bastion.example.com"} {
+		if !strings.Contains(string(out), exp+"\n") {
+			t.Errorf("expected ssh -G output to contain %q", exp)
+		}
+	}
+	if strings.Contains(string(out), "/etc/git-secret/ssh") {
+		t.Errorf("expected default key not to be used for git.corp")
+	}
+}
+
+func TestRenderSSHConfigNoKnownHosts(t *testing.T) {
+	dir := t.TempDir()
+	hosts := []sshHost{{Host: "example.com", User: "git"}}
+	files, err := renderSSHConfig(dir, hosts, nil, false, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expConfig := `# Generated by git-sync.
+
+Host example.com
+  User git
+  StrictHostKeyChecking no
+
+Host * !example.com
+  StrictHostKeyChecking no
+`
+	if got := files[sshConfigFile]; got != expConfig {
+		t.Errorf("wrong ssh_config:\nexpected:\n%s\ngot:\n%s", expConfig, got)
+	}
+
+	// A missing CA file is an error.
+	hosts[0].CertAuthorityFile = filepath.Join(dir, "missing")
+	if _, err := renderSSHConfig(dir, hosts, nil, false, ""); err == nil {
+		t.Errorf("expected error for missing cert-authority-file")
+	}
+}
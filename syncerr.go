@@ -0,0 +1,189 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/git-sync/pkg/cmd"
+	"k8s.io/git-sync/pkg/hook"
+)
+
+// errorClass describes how a sync failure should be handled.
+type errorClass string
+
+const (
+	// Permanent errors are not expected to go away by retrying, e.g. bad
+	// credentials or a ref which does not exist.
+	errorClassPermanent errorClass = "permanent"
+	// Transient errors are expected to go away by themselves, e.g. DNS or
+	// network failures or server errors.
+	errorClassTransient errorClass = "transient"
+	// Unknown errors could not be classified.
+	errorClassUnknown errorClass = "unknown"
+	// Rejected errors mean that a validation hook rejected the new hash.
+	// These are not failures of git-sync itself, and the previous hash
+	// remains published.
+	errorClassRejected errorClass = "rejected"
+)
+
+// Exit codes used when git-sync gives up after sync failures in --one-time
+// mode.  Note that pflag uses 2 for flag-parsing errors.
+const (
+	exitCodeUnknownFailure   = 1
+	exitCodePermanentFailure = 3
+	exitCodeTransientFailure = 4
+	exitCodeRejected         = 5
+)
+
+// exitCode returns the process exit code for this class of error.
+func (c errorClass) exitCode() int {
+	switch c {
+	case errorClassPermanent:
+		return exitCodePermanentFailure
+	case errorClassTransient:
+		return exitCodeTransientFailure
+	case errorClassRejected:
+		return exitCodeRejected
+	}
+	return exitCodeUnknownFailure
+}
+
+// syncError is an error with a classification, for use in deciding how to
+// handle sync failures.
+type syncError struct {
+	class  errorClass
+	reason string // a short, human-friendly reason, e.g. "auth"
+	err    error
+}
+
+func (e *syncError) Error() string {
+	return e.err.Error()
+}
+
+func (e *syncError) Unwrap() error {
+	return e.err
+}
+
+// errorPattern maps a pattern in git's output to a classification.
+type errorPattern struct {
+	class  errorClass
+	reason string
+	re     *regexp.Regexp
+}
+
+// errorPatterns is an ordered list of known failure signatures.  The first
+// match wins, so more specific patterns must come first.  For example, SSH
+// auth failures also print "Could not read from remote repository", which
+// would otherwise look like a network problem.
+var errorPatterns = []errorPattern{
+	// Permanent
+	{errorClassPermanent, "auth", regexp.MustCompile(`(?i)authentication failed`)},
+	{errorClassPermanent, "auth", regexp.MustCompile(`(?i)invalid username or password`)},
+	{errorClassPermanent, "auth", regexp.MustCompile(`(?i)could not read (username|password)`)},
+	{errorClassPermanent, "auth", regexp.MustCompile(`(?i)terminal prompts disabled`)},
+	{errorClassPermanent, "auth", regexp.MustCompile(`(?i)permission denied \(`)},
+	{errorClassPermanent, "auth", regexp.MustCompile(`(?i)host key verification failed`)},
+	{errorClassPermanent, "auth", regexp.MustCompile(`(?i)returned error: 40[13]`)},
+	{errorClassPermanent, "ref-not-found", regexp.MustCompile(`(?i)couldn't find remote ref`)},
+	{errorClassPermanent, "ref-not-found", regexp.MustCompile(`(?i)invalid refspec`)},
+	{errorClassPermanent, "ref-not-found", regexp.MustCompile(`(?i)not our ref`)},
+	{errorClassPermanent, "ref-not-found", regexp.MustCompile(`(?i)unknown revision`)},
+	{errorClassPermanent, "repo-not-found", regexp.MustCompile(`(?i)repository .*not found`)},
+	{errorClassPermanent, "repo-not-found", regexp.MustCompile(`(?i)does not appear to be a git repository`)},
+	{errorClassPermanent, "repo-not-found", regexp.MustCompile(`(?i)returned error: 404`)},
+	// Transient
+	{errorClassTransient, "dns", regexp.MustCompile(`(?i)could not resolve host`)},
+	{errorClassTransient, "dns", regexp.MustCompile(`(?i)temporary failure in name resolution`)},
+	{errorClassTransient, "dns", regexp.MustCompile(`(?i)name or service not known`)},
+	{errorClassTransient, "timeout", regexp.MustCompile(`(?i)timed out`)},
+	{errorClassTransient, "server", regexp.MustCompile(`(?i)returned error: 5[0-9][0-9]`)},
+	{errorClassTransient, "server", regexp.MustCompile(`(?i)auth URL returned status 5[0-9][0-9]`)},
+	{errorClassTransient, "network", regexp.MustCompile(`(?i)connection (refused|reset)`)},
+	{errorClassTransient, "network", regexp.MustCompile(`(?i)network is unreachable`)},
+	{errorClassTransient, "network", regexp.MustCompile(`(?i)no route to host`)},
+	{errorClassTransient, "network", regexp.MustCompile(`(?i)early eof`)},
+	{errorClassTransient, "network", regexp.MustCompile(`(?i)the remote end hung up unexpectedly`)},
+	{errorClassTransient, "network", regexp.MustCompile(`(?i)rpc failed`)},
+	{errorClassTransient, "network", regexp.MustCompile(`(?i)could not read from remote repository`)},
+}
+
+// classifyError examines an error (typically from SyncRepo) and returns a
+// classified syncError.  If err is already a syncError, it is returned as-is.
+func classifyError(err error) *syncError {
+	if err == nil {
+		return nil
+	}
+	var se *syncError
+	if errors.As(err, &se) {
+		return se
+	}
+	var rejected *hook.RejectedError
+	if errors.As(err, &rejected) {
+		return &syncError{class: errorClassRejected, reason: "validation", err: err}
+	}
+
+	// Prefer git's own output, when we have it, since that is where the
+	// useful information is.
+	text := err.Error()
+	var cmdErr *cmd.Error
+	if errors.As(err, &cmdErr) {
+		text = cmdErr.Stderr + "\n" + cmdErr.Stdout
+	}
+	for _, p := range errorPatterns {
+		if p.re.MatchString(text) {
+			return &syncError{class: p.class, reason: p.reason, err: err}
+		}
+	}
+
+	// Fall back on Go's own errors, e.g. from HTTP clients.
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &syncError{class: errorClassTransient, reason: "timeout", err: err}
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return &syncError{class: errorClassTransient, reason: "timeout", err: err}
+		}
+		return &syncError{class: errorClassTransient, reason: "network", err: err}
+	}
+
+	return &syncError{class: errorClassUnknown, reason: "unknown", err: err}
+}
+
+// failurePolicy describes how to handle consecutive failures of a given
+// class.
+type failurePolicy struct {
+	maxFailures int           // negative means retry forever
+	backoff     time.Duration // 0 means use the normal period
+}
+
+// parseMaxFailures parses a per-class max-failures flag value.  The empty
+// string means "use the default".
+func parseMaxFailures(val string, def int) (int, error) {
+	val = strings.TrimSpace(val)
+	if val == "" {
+		return def, nil
+	}
+	return strconv.Atoi(val)
+}
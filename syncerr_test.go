@@ -0,0 +1,144 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"k8s.io/git-sync/pkg/cmd"
+	"k8s.io/git-sync/pkg/hook"
+)
+
+func TestClassifyError(t *testing.T) {
+	gitErr := func(stderr string) error {
+		return &cmd.Error{Cmd: "git fetch", Stderr: stderr, Err: errors.New("exit status 128")}
+	}
+
+	cases := []struct {
+		name   string
+		err    error
+		class  errorClass
+		reason string
+	}{{
+		name:   "http-auth",
+		err:    gitErr("remote: Invalid username or password.\nfatal: Authentication failed for 'https://example.com/repo'"),
+		class:  errorClassPermanent,
+		reason: "auth",
+	}, {
+		name:   "ssh-auth",
+		err:    gitErr("git@example.com: Permission denied (publickey).\nfatal: Could not read from remote repository."),
+		class:  errorClassPermanent,
+		reason: "auth",
+	}, {
+		name:   "bad-ref",
+		err:    gitErr("fatal: couldn't find remote ref does-not-exist"),
+		class:  errorClassPermanent,
+		reason: "ref-not-found",
+	}, {
+		name:   "bad-repo",
+		err:    gitErr("remote: Repository not found.\nfatal: repository 'https://example.com/nope/' not found"),
+		class:  errorClassPermanent,
+		reason: "repo-not-found",
+	}, {
+		name:   "bad-local-repo",
+		err:    gitErr("fatal: '/tmp/nope' does not appear to be a git repository"),
+		class:  errorClassPermanent,
+		reason: "repo-not-found",
+	}, {
+		name:   "dns",
+		err:    gitErr("fatal: unable to access 'https://example.com/repo/': Could not resolve host: example.com"),
+		class:  errorClassTransient,
+		reason: "dns",
+	}, {
+		name:   "server-error",
+		err:    gitErr("fatal: unable to access 'https://example.com/repo/': The requested URL returned error: 503"),
+		class:  errorClassTransient,
+		reason: "server",
+	}, {
+		name:   "hung-up",
+		err:    gitErr("fatal: the remote end hung up unexpectedly"),
+		class:  errorClassTransient,
+		reason: "network",
+	}, {
+		name:   "deadline",
+		err:    fmt.Errorf("credential refresh failed: %w", context.DeadlineExceeded),
+		class:  errorClassTransient,
+		reason: "timeout",
+	}, {
+		name:   "already-classified",
+		err:    fmt.Errorf("wrapped: %w", &syncError{class: errorClassPermanent, reason: "custom", err: errors.New("custom")}),
+		class:  errorClassPermanent,
+		reason: "custom",
+	}, {
+		name:   "rejected",
+		err:    &hook.RejectedError{Hook: "validate", Hash: "abc", Err: gitErr("connection timed out")},
+		class:  errorClassRejected,
+		reason: "validation",
+	}, {
+		name:   "unknown",
+		err:    gitErr("fatal: something odd happened"),
+		class:  errorClassUnknown,
+		reason: "unknown",
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			serr := classifyError(tc.err)
+			if serr.class != tc.class {
+				t.Errorf("expected class %q, got %q", tc.class, serr.class)
+			}
+			if serr.reason != tc.reason {
+				t.Errorf("expected reason %q, got %q", tc.reason, serr.reason)
+			}
+			if !errors.Is(serr, tc.err) && !errors.Is(tc.err, serr) {
+				t.Errorf("classified error does not wrap the original")
+			}
+		})
+	}
+}
+
+func TestParseMaxFailures(t *testing.T) {
+	cases := []struct {
+		in   string
+		def  int
+		exp  int
+		fail bool
+	}{
+		{in: "", def: 3, exp: 3},
+		{in: " ", def: -1, exp: -1},
+		{in: "0", def: 3, exp: 0},
+		{in: "-1", def: 0, exp: -1},
+		{in: "5", def: 0, exp: 5},
+		{in: "five", def: 0, fail: true},
+	}
+
+	for _, tc := range cases {
+		n, err := parseMaxFailures(tc.in, tc.def)
+		if err != nil && !tc.fail {
+			t.Errorf("%q: unexpected error: %v", tc.in, err)
+		}
+		if err == nil && tc.fail {
+			t.Errorf("%q: unexpected success", tc.in)
+		}
+		if err == nil && n != tc.exp {
+			t.Errorf("%q: expected %d, got %d", tc.in, tc.exp, n)
+		}
+	}
+}
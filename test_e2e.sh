@@ -317,6 +317,7 @@ EXECHOOK_COMMAND="$TEST_TOOLS/exechook_command.sh"
 EXECHOOK_COMMAND_FAIL="$TEST_TOOLS/exechook_command_fail.sh"
 EXECHOOK_COMMAND_SLEEPY="$TEST_TOOLS/exechook_command_with_sleep.sh"
 EXECHOOK_COMMAND_FAIL_SLEEPY="$TEST_TOOLS/exechook_command_fail_with_sleep.sh"
+CREDENTIAL_EXEC_COMMAND="$TEST_TOOLS/credential_exec.sh"
 EXECHOOK_ENVKEY=ENVKEY
 EXECHOOK_ENVVAL=envval
 RUNLOG="$DIR/runlog"
@@ -2013,6 +2014,41 @@ function e2e::auth_http_password_file() {
     assert_file_eq "$ROOT/link/file" "${FUNCNAME[0]}"
 }
 
+##############################################
+# Test password-file which is rotated while running
+##############################################
+function e2e::auth_http_password_file_rotated() {
+    # Run a git-over-HTTP server.
+    local ctr
+    ctr=$(docker_run \
+        -v "$REPO":/git/repo:ro \
+        e2e/test/httpd)
+    local ip
+    ip=$(docker_ip "$ctr")
+
+    # Start with a bad password.
+    echo -n "wrong" > "$WORK/password-file"
+
+    GIT_SYNC \
+        --period=100ms \
+        --max-failures=-1 \
+        --repo="http://$ip/repo" \
+        --root="$ROOT" \
+        --link="link" \
+        --username="testuser" \
+        --password-file="$WORK/password-file" \
+        &
+    sleep 3
+    assert_file_absent "$ROOT/link/file"
+
+    # Fix the password, without restarting.
+    echo -n "testpass" > "$WORK/password-file"
+    wait_for_sync "${MAXWAIT}"
+    assert_link_exists "$ROOT/link"
+    assert_file_exists "$ROOT/link/file"
+    assert_file_eq "$ROOT/link/file" "${FUNCNAME[0]}"
+}
+
 ##############################################
 # Test SSH (user@host:path syntax)
 ##############################################
@@ -2093,6 +2129,87 @@ function e2e::auth_ssh_url() {
     assert_file_eq "$ROOT/link/file" "${FUNCNAME[0]}"
 }
 
+##############################################
+# Test SSH with per-host settings
+##############################################
+function e2e::auth_ssh_host() {
+    # Run a git-over-SSH server.
+    local ctr
+    ctr=$(docker_run \
+        -v "$DOT_SSH/server/3":/dot_ssh:ro \
+        -v "$REPO":/git/repo:ro \
+        e2e/test/sshd)
+    local ip
+    ip=$(docker_ip "$ctr")
+
+    # The default key is wrong, but the host has its own key.
+    GIT_SYNC \
+        --one-time \
+        --repo="test@$ip:/git/repo" \
+        --root="$ROOT" \
+        --link="link" \
+        --ssh-known-hosts=false \
+        --ssh-key-file="/ssh/secret.1" \
+        --ssh-host="{\"host\": \"$ip\", \"key-files\": [\"/ssh/secret.3\"]}"
+
+    assert_link_exists "$ROOT/link"
+    assert_file_exists "$ROOT/link/file"
+    assert_file_eq "$ROOT/link/file" "${FUNCNAME[0]}"
+}
+
+##############################################
+# Test SSH with pinned host keys
+##############################################
+function e2e::auth_ssh_host_pinned() {
+    # Run a git-over-SSH server.
+    local ctr
+    ctr=$(docker_run \
+        -v "$DOT_SSH/server/3":/dot_ssh:ro \
+        -v "$REPO":/git/repo:ro \
+        e2e/test/sshd)
+    local ip
+    ip=$(docker_ip "$ctr")
+
+    # Get the server's host key fingerprints, as a JSON list.
+    local fps=""
+    for _ in $(seq 1 10); do
+        fps=$(ssh-keyscan -q "$ip" 2>/dev/null \
+            | ssh-keygen -lf - \
+            | awk '{print "\"" $2 "\""}' \
+            | paste -sd, -)
+        if [[ -n "$fps" ]]; then
+            break
+        fi
+        sleep 1
+    done
+    if [[ -z "$fps" ]]; then
+        fail "can't get host key fingerprints"
+    fi
+
+    # A wrong fingerprint is rejected.
+    assert_fail \
+        GIT_SYNC \
+            --one-time \
+            --repo="test@$ip:/git/repo" \
+            --root="$ROOT" \
+            --link="link" \
+            --ssh-key-file="/ssh/secret.3" \
+            --ssh-host="{\"host\": \"$ip\", \"host-key-fingerprints\": [\"SHA256:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA\"]}"
+    assert_file_absent "$ROOT/link/file"
+
+    GIT_SYNC \
+        --one-time \
+        --repo="test@$ip:/git/repo" \
+        --root="$ROOT" \
+        --link="link" \
+        --ssh-key-file="/ssh/secret.3" \
+        --ssh-host="{\"host\": \"$ip\", \"host-key-fingerprints\": [$fps]}"
+
+    assert_link_exists "$ROOT/link"
+    assert_file_exists "$ROOT/link/file"
+    assert_file_eq "$ROOT/link/file" "${FUNCNAME[0]}"
+}
+
 ##############################################
 # Test askpass-url with bad password
 ##############################################
@@ -2157,6 +2274,92 @@ function e2e::auth_askpass_url_correct_password() {
     assert_file_eq "$ROOT/link/file" "${FUNCNAME[0]}"
 }
 
+##############################################
+# Test askpass-url with a JSON response which is cached until it expires
+##############################################
+function e2e::auth_askpass_url_json_cached() {
+    # run with askpass_url service which returns a long-lived credential
+    local hitlog="$WORK/hitlog"
+    cat /dev/null > "$hitlog"
+    local ctr
+    ctr=$(docker_run \
+        -v "$hitlog":/var/log/hits \
+        e2e/test/ncsvr \
+        80 'read X
+            echo "HTTP/1.1 200 OK"
+            echo "Content-Type: application/json"
+            echo
+            echo "{\"username\": \"my-username\", \"password\": \"my-password\", \"expires_at\": \"2099-01-01T00:00:00Z\"}"
+            ')
+    local ip
+    ip=$(docker_ip "$ctr")
+
+    # First sync
+    echo "${FUNCNAME[0]} 1" > "$REPO/file"
+    git -C "$REPO" commit -qam "${FUNCNAME[0]} 1"
+
+    GIT_SYNC \
+        --period=100ms \
+        --repo="file://$REPO" \
+        --root="$ROOT" \
+        --link="link" \
+        --git="/$ASKPASS_GIT" \
+        --askpass-url="http://$ip/git_askpass" \
+        --askpass-url-timeout=5s \
+        &
+    wait_for_sync "${MAXWAIT}"
+    assert_link_exists "$ROOT/link"
+    assert_file_exists "$ROOT/link/file"
+    assert_file_eq "$ROOT/link/file" "${FUNCNAME[0]} 1"
+
+    # Move HEAD forward
+    echo "${FUNCNAME[0]} 2" > "$REPO/file"
+    git -C "$REPO" commit -qam "${FUNCNAME[0]} 2"
+    wait_for_sync "${MAXWAIT}"
+    assert_link_exists "$ROOT/link"
+    assert_file_exists "$ROOT/link/file"
+    assert_file_eq "$ROOT/link/file" "${FUNCNAME[0]} 2"
+
+    # The credential was only fetched once.
+    assert_file_lines_eq "$hitlog" 1
+}
+
+##############################################
+# Test credential-exec with cached credentials
+##############################################
+function e2e::auth_credential_exec_cached() {
+    cat /dev/null > "$RUNLOG"
+
+    # First sync
+    echo "${FUNCNAME[0]} 1" > "$REPO/file"
+    git -C "$REPO" commit -qam "${FUNCNAME[0]} 1"
+
+    GIT_SYNC \
+        --period=100ms \
+        --repo="file://$REPO" \
+        --root="$ROOT" \
+        --link="link" \
+        --git="/$ASKPASS_GIT" \
+        --credential-exec="/$CREDENTIAL_EXEC_COMMAND" \
+        &
+    wait_for_sync "${MAXWAIT}"
+    assert_link_exists "$ROOT/link"
+    assert_file_exists "$ROOT/link/file"
+    assert_file_eq "$ROOT/link/file" "${FUNCNAME[0]} 1"
+
+    # Move HEAD forward
+    echo "${FUNCNAME[0]} 2" > "$REPO/file"
+    git -C "$REPO" commit -qam "${FUNCNAME[0]} 2"
+    wait_for_sync "${MAXWAIT}"
+    assert_link_exists "$ROOT/link"
+    assert_file_exists "$ROOT/link/file"
+    assert_file_eq "$ROOT/link/file" "${FUNCNAME[0]} 2"
+
+    # The command was only run once.
+    assert_file_lines_eq "$RUNLOG" 1
+    assert_file_eq "$RUNLOG" "initial"
+}
+
 ##############################################
 # Test askpass-url where the URL is sometimes wrong
 ##############################################
@@ -2486,6 +2689,46 @@ function e2e::exechook_startup_after_crash() {
     assert_file_lines_eq "$RUNLOG" 1
 }
 
+##############################################
+# Test exechook is not re-run after restart
+##############################################
+function e2e::exechook_not_rerun_after_restart() {
+    cat /dev/null > "$RUNLOG"
+
+    echo "${FUNCNAME[0]} 1" > "$REPO/file"
+    git -C "$REPO" commit -qam "${FUNCNAME[0]} 1"
+
+    GIT_SYNC \
+        --one-time \
+        --repo="file://$REPO" \
+        --root="$ROOT" \
+        --link="link" \
+        --exechook-command="/$EXECHOOK_COMMAND"
+    assert_file_eq "$ROOT/link/exechook" "${FUNCNAME[0]} 1"
+    assert_file_lines_eq "$RUNLOG" 1
+
+    # Restart with no changes to repo
+    GIT_SYNC \
+        --one-time \
+        --repo="file://$REPO" \
+        --root="$ROOT" \
+        --link="link" \
+        --exechook-command="/$EXECHOOK_COMMAND"
+    assert_file_lines_eq "$RUNLOG" 1
+
+    # Restart with a change
+    echo "${FUNCNAME[0]} 2" > "$REPO/file"
+    git -C "$REPO" commit -qam "${FUNCNAME[0]} 2"
+    GIT_SYNC \
+        --one-time \
+        --repo="file://$REPO" \
+        --root="$ROOT" \
+        --link="link" \
+        --exechook-command="/$EXECHOOK_COMMAND"
+    assert_file_eq "$ROOT/link/exechook" "${FUNCNAME[0]} 2"
+    assert_file_lines_eq "$RUNLOG" 2
+}
+
 ##############################################
 # Test exechook-success with --hooks-async=false
 ##############################################
@@ -2535,6 +2778,130 @@ function e2e::exechook_success_hooks_before_symlink_non_async() {
     assert_file_absent "$ROOT/link/delaycheck"
 }
 
+##############################################
+# Test multiple hooks with --hook
+##############################################
+function e2e::hook_multiple_with_deps() {
+    cat /dev/null > "$RUNLOG"
+
+    echo "${FUNCNAME[0]}" > "$REPO/file"
+    git -C "$REPO" commit -qam "${FUNCNAME[0]}"
+
+    GIT_SYNC \
+        --one-time \
+        --repo="file://$REPO" \
+        --root="$ROOT" \
+        --link="link" \
+        --hook='{"name":"second", "type":"exec", "command":"/bin/sh", "args":["-c", "echo second >> /var/log/runs"], "after":["first"]}' \
+        --hook='{"name":"first", "type":"exec", "command":"/bin/sh", "args":["-c", "echo first >> /var/log/runs"]}'
+    assert_link_exists "$ROOT/link"
+    assert_file_exists "$ROOT/link/file"
+    assert_file_eq "$ROOT/link/file" "${FUNCNAME[0]}"
+    assert_file_lines_eq "$RUNLOG" 2
+    assert_file_eq "$RUNLOG" "first
+second"
+}
+
+##############################################
+# Test exechook event info
+##############################################
+function e2e::exechook_event_info() {
+    cat /dev/null > "$RUNLOG"
+
+    # First sync
+    echo "${FUNCNAME[0]} 1" > "$REPO/file"
+    git -C "$REPO" commit -qam "${FUNCNAME[0]} 1"
+    local hash1
+    hash1=$(git -C "$REPO" rev-parse HEAD)
+
+    GIT_SYNC \
+        --period=100ms \
+        --repo="file://$REPO" \
+        --root="$ROOT" \
+        --link="link" \
+        --hook='{"name":"info", "type":"exec", "command":"/bin/sh", "args":["-c", "echo \"$GITSYNC_PREVIOUS_HASH $GITSYNC_HASH $GITSYNC_SYNC_COUNT\" >> /var/log/runs; grep -q changedFiles \"$GITSYNC_EVENT_FILE\""]}' \
+        &
+    wait_for_sync "${MAXWAIT}"
+    assert_link_exists "$ROOT/link"
+    assert_file_eq "$ROOT/link/file" "${FUNCNAME[0]} 1"
+    assert_file_lines_eq "$RUNLOG" 1
+    assert_file_eq "$RUNLOG" " $hash1 1"
+
+    # Move forward
+    echo "${FUNCNAME[0]} 2" > "$REPO/file"
+    git -C "$REPO" commit -qam "${FUNCNAME[0]} 2"
+    local hash2
+    hash2=$(git -C "$REPO" rev-parse HEAD)
+    wait_for_sync "${MAXWAIT}"
+    assert_file_eq "$ROOT/link/file" "${FUNCNAME[0]} 2"
+    assert_file_lines_eq "$RUNLOG" 2
+    assert_file_eq "$RUNLOG" " $hash1 1
+$hash1 $hash2 2"
+}
+
+##############################################
+# Test exechook output capture
+##############################################
+function e2e::exechook_output_file() {
+    # First sync
+    echo "${FUNCNAME[0]} 1" > "$REPO/file"
+    git -C "$REPO" commit -qam "${FUNCNAME[0]} 1"
+    local hash
+    hash=$(git -C "$REPO" rev-parse HEAD)
+
+    GIT_SYNC \
+        --period=100ms \
+        --repo="file://$REPO" \
+        --root="$ROOT" \
+        --link="link" \
+        --hook='{"name":"reload", "type":"exec", "command":"/bin/sh", "args":["-c", "echo reloading; echo broken config >&2; exit 3"], "output-file":true}' \
+        &
+    wait_for_sync "${MAXWAIT}"
+    assert_link_exists "$ROOT/link"
+    sleep 3 # give the hook time to run
+    assert_file_contains "$ROOT/.git-sync/hook-output/reload" "\"hash\": \"$hash\""
+    assert_file_contains "$ROOT/.git-sync/hook-output/reload" "\"exitCode\": 3"
+    assert_file_contains "$ROOT/.git-sync/hook-output/reload" "broken config"
+}
+
+##############################################
+# Test validation hooks
+##############################################
+function e2e::hook_validate_rejects() {
+    # First sync
+    echo "${FUNCNAME[0]} 1" > "$REPO/file"
+    git -C "$REPO" commit -qam "${FUNCNAME[0]} 1"
+
+    GIT_SYNC \
+        --period=100ms \
+        --max-failures=0 \
+        --repo="file://$REPO" \
+        --root="$ROOT" \
+        --link="link" \
+        --error-file="error.json" \
+        --hook='{"name":"check", "type":"exec", "command":"/bin/sh", "args":["-c", "! grep -q bad file"], "on":"validate"}' \
+        &
+    wait_for_sync "${MAXWAIT}"
+    assert_link_exists "$ROOT/link"
+    assert_file_eq "$ROOT/link/file" "${FUNCNAME[0]} 1"
+
+    # Push a bad commit, which should be rejected without exiting
+    echo "${FUNCNAME[0]} bad" > "$REPO/file"
+    git -C "$REPO" commit -qam "${FUNCNAME[0]} bad"
+    sleep 3
+    assert_link_exists "$ROOT/link"
+    assert_file_eq "$ROOT/link/file" "${FUNCNAME[0]} 1"
+    assert_file_contains "$ROOT/error.json" "rejected by validation hook"
+
+    # Fix it
+    echo "${FUNCNAME[0]} 2" > "$REPO/file"
+    git -C "$REPO" commit -qam "${FUNCNAME[0]} 2"
+    wait_for_sync "${MAXWAIT}"
+    assert_link_exists "$ROOT/link"
+    assert_file_eq "$ROOT/link/file" "${FUNCNAME[0]} 2"
+    assert_file_absent "$ROOT/error.json"
+}
+
 ##############################################
 # Test webhook success
 ##############################################
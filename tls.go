@@ -0,0 +1,174 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// tlsVersions maps --tls-min-version values to the Go constant and git's
+// http.sslVersion value.
+var tlsVersions = map[string]struct {
+	version uint16
+	git     string
+}{
+	"1.2": {tls.VersionTLS12, "tlsv1.2"},
+	"1.3": {tls.VersionTLS13, "tlsv1.3"},
+}
+
+// tlsSettings are the TLS settings for all outbound HTTPS connections, from
+// git and from git-sync itself.
+type tlsSettings struct {
+	// A PEM file of CA certificates, which replace the system's, or "".
+	caBundle string
+	// A client certificate and key, or "".
+	certFile string
+	keyFile  string
+	// One of the keys of tlsVersions, or "" to leave git's default alone
+	// and use TLS 1.2 for git-sync itself.
+	minVersion string
+}
+
+// files returns the files which the settings refer to.
+func (ts tlsSettings) files() []string {
+	var files []string
+	for _, f := range []string{ts.caBundle, ts.certFile, ts.keyFile} {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// config reads the files and returns a tls.Config.
+func (ts tlsSettings) config() (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if ts.minVersion != "" {
+		v, found := tlsVersions[ts.minVersion]
+		if !found {
+			return nil, fmt.Errorf("unsupported TLS version %q", ts.minVersion)
+		}
+		cfg.MinVersion = v.version
+	}
+	if ts.caBundle != "" {
+		ca, err := os.ReadFile(ts.caBundle)
+		if err != nil {
+			return nil, fmt.Errorf("can't read CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %q", ts.caBundle)
+		}
+		cfg.RootCAs = pool
+	}
+	if ts.certFile != "" {
+		pair, err := tls.LoadX509KeyPair(ts.certFile, ts.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't load client cert and key: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{pair}
+	}
+	return cfg, nil
+}
+
+// reloadableTransport is an http.RoundTripper whose TLS settings can be
+// replaced, e.g. when a mounted CA bundle is rotated, without replacing the
+// http.Clients which use it.
+type reloadableTransport struct {
+	mutex     sync.Mutex
+	transport *http.Transport
+	config    *tls.Config
+}
+
+var _ http.RoundTripper = &reloadableTransport{}
+
+// newReloadableTransport returns a reloadableTransport with the settings
+// from ts.
+func newReloadableTransport(ts tlsSettings) (*reloadableTransport, error) {
+	rt := &reloadableTransport{}
+	if err := rt.reload(ts); err != nil {
+		return nil, err
+	}
+	return rt, nil
+}
+
+// reload re-reads the files in ts.  Connections which are in use are not
+// interrupted.
+func (rt *reloadableTransport) reload(ts tlsSettings) error {
+	cfg, err := ts.config()
+	if err != nil {
+		return err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert
+	transport.TLSClientConfig = cfg
+
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	if rt.transport != nil {
+		rt.transport.CloseIdleConnections()
+	}
+	rt.transport = transport
+	rt.config = cfg
+	return nil
+}
+
+// TLSConfig returns the current TLS settings, for clients which need to add
+// their own (e.g. webhooks with their own CA or client cert).  The result must
+// not be modified.  It is replaced, not changed, on reload.
+func (rt *reloadableTransport) TLSConfig() *tls.Config {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	return rt.config
+}
+
+func (rt *reloadableTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.mutex.Lock()
+	transport := rt.transport
+	rt.mutex.Unlock()
+	return transport.RoundTrip(req)
+}
+
+// SetupGitTLS configures git to use the same TLS settings as git-sync.
+func (git *repoSync) SetupGitTLS(ctx context.Context, ts tlsSettings) error {
+	git.log.V(1).Info("configuring git TLS", "caBundle", ts.caBundle, "certFile", ts.certFile, "minVersion", ts.minVersion)
+
+	configs := []keyVal{}
+	// Only override git's default if asked to.
+	if ts.minVersion != "" {
+		configs = append(configs, keyVal{key: "http.sslVersion", val: tlsVersions[ts.minVersion].git})
+	}
+	if ts.caBundle != "" {
+		configs = append(configs, keyVal{key: "http.sslCAInfo", val: ts.caBundle})
+	}
+	if ts.certFile != "" {
+		configs = append(configs,
+			keyVal{key: "http.sslCert", val: ts.certFile},
+			keyVal{key: "http.sslKey", val: ts.keyFile})
+	}
+	for _, kv := range configs {
+		if _, _, err := git.Run(ctx, "", "config", "--global", kv.key, kv.val); err != nil {
+			return fmt.Errorf("error configuring git %q %q: %w", kv.key, kv.val, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/git-sync/pkg/cmd"
+	"k8s.io/git-sync/pkg/logging"
+)
+
+func TestTLSSettingsConfig(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "not-pem")
+	if err := os.WriteFile(notPEM, []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name       string
+		settings   tlsSettings
+		expVersion uint16
+		fail       bool
+	}{{
+		name:       "defaults",
+		settings:   tlsSettings{},
+		expVersion: tls.VersionTLS12,
+	}, {
+		name:       "tls12",
+		settings:   tlsSettings{minVersion: "1.2"},
+		expVersion: tls.VersionTLS12,
+	}, {
+		name:       "tls13",
+		settings:   tlsSettings{minVersion: "1.3"},
+		expVersion: tls.VersionTLS13,
+	}, {
+		name:     "bad-version",
+		settings: tlsSettings{minVersion: "1.1"},
+		fail:     true,
+	}, {
+		name:     "missing-ca-bundle",
+		settings: tlsSettings{minVersion: "1.2", caBundle: filepath.Join(dir, "missing")},
+		fail:     true,
+	}, {
+		name:     "empty-ca-bundle",
+		settings: tlsSettings{minVersion: "1.2", caBundle: notPEM},
+		fail:     true,
+	}, {
+		name:     "bad-client-cert",
+		settings: tlsSettings{minVersion: "1.2", certFile: notPEM, keyFile: notPEM},
+		fail:     true,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := tc.settings.config()
+			if err != nil && !tc.fail {
+				t.Errorf("unexpected error: %v", err)
+			} else if err == nil && tc.fail {
+				t.Errorf("expected error")
+			} else if err == nil && cfg.MinVersion != tc.expVersion {
+				t.Errorf("expected min version %x, got %x", tc.expVersion, cfg.MinVersion)
+			}
+		})
+	}
+}
+
+func TestReloadableTransport(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	// httptest servers all use the same certificate, so make another.
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "other"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	otherDER, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	caBundle := filepath.Join(t.TempDir(), "ca.crt")
+	writeCA := func(der []byte) {
+		t.Helper()
+		ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+		if err := os.WriteFile(caBundle, ca, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	get := func(client *http.Client) error {
+		resp, err := client.Get(srv.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	// The bundle replaces the system roots.
+	ts := tlsSettings{caBundle: caBundle, minVersion: "1.2"}
+	writeCA(otherDER)
+	rt, err := newReloadableTransport(ts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client := &http.Client{Transport: rt}
+	if err := get(client); err == nil {
+		t.Errorf("expected TLS error with the wrong CA")
+	}
+
+	// The same client picks up a new bundle.
+	writeCA(srv.Certificate().Raw)
+	if err := rt.reload(ts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := get(client); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// A bad bundle is an error, and the old settings are kept.
+	if err := os.WriteFile(caBundle, []byte("oops"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.reload(ts); err == nil {
+		t.Errorf("expected error for bad CA bundle")
+	}
+	if err := get(client); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSetupGitTLS(t *testing.T) {
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(t.TempDir(), "gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "true")
+	log := logging.New("", "", 0)
+	git := &repoSync{
+		cmd: "git",
+		log: log,
+		run: cmd.NewRunner(log),
+	}
+	ctx := context.Background()
+	ts := tlsSettings{caBundle: "/ca.crt", certFile: "/tls.crt", keyFile: "/tls.key", minVersion: "1.3"}
+	if err := git.SetupGitTLS(ctx, ts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out, _, err := git.Run(ctx, "", "config", "--global", "--get-regexp", "^http\\.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{
+		"http.sslversion tlsv1.3",
+		"http.sslcainfo /ca.crt",
+		"http.sslcert /tls.crt",
+		"http.sslkey /tls.key",
+	}
+	if got := strings.Split(out, "\n"); !reflect.DeepEqual(exp, got) {
+		t.Errorf("expected git config %q, got %q", exp, got)
+	}
+
+	// Without --tls-min-version, git's default is left alone.
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(t.TempDir(), "gitconfig"))
+	if err := git.SetupGitTLS(ctx, tlsSettings{caBundle: "/ca.crt"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out, _, err = git.Run(ctx, "", "config", "--global", "--get-regexp", "^http\\.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "http.sslcainfo /ca.crt"; out != exp {
+		t.Errorf("expected git config %q, got %q", exp, out)
+	}
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// The OAuth2 grants which are supported by --oauth2-grant.
+const (
+	oauth2GrantClientCredentials = "client-credentials"
+	oauth2GrantTokenExchange     = "token-exchange"
+)
+
+const (
+	// RFC 8693 values.
+	grantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	tokenTypeJWT           = "urn:ietf:params:oauth:token-type:jwt"
+	tokenTypeAccessToken   = "urn:ietf:params:oauth:token-type:access_token"
+)
+
+// tokenProvider gets short-lived tokens, which are used as git passwords.
+type tokenProvider interface {
+	// Name describes the provider, for logs and metrics.
+	Name() string
+	// Token returns a new token, and when it expires, or a zero time if
+	// that is not known.
+	Token(ctx context.Context) (string, time.Time, error)
+}
+
+// oauth2Client is the common configuration for OAuth2 token requests.
+type oauth2Client struct {
+	tokenURL string
+	clientID string
+	// Only one of these should be set.  The file is read for each request,
+	// so it can be rotated.
+	clientSecret     string
+	clientSecretFile string
+	scopes           string
+	audience         string
+	httpClient       *http.Client
+	// Secrets which are read from files are added to this, if it is not
+	// nil, so they can be redacted.
+	secrets secretStore
+}
+
+// secretStore is implemented by loggers which redact secrets (e.g.
+// logging.Logger).
+type secretStore interface {
+	SetSecrets(source string, secrets ...string)
+}
+
+// oauth2TokenResponse is a successful response from a token endpoint (RFC
+// 6749 section 5.1).
+type oauth2TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// oauth2ErrorResponse is an error response from a token endpoint (RFC 6749
+// section 5.2).
+type oauth2ErrorResponse struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// requestToken POSTs form to the token endpoint, authenticating as the
+// client, and decodes the response.
+func (c oauth2Client) requestToken(ctx context.Context, form url.Values) (string, time.Time, error) {
+	if c.scopes != "" {
+		form.Set("scope", c.scopes)
+	}
+	if c.audience != "" {
+		form.Set("audience", c.audience)
+	}
+	secret := c.clientSecret
+	if c.clientSecretFile != "" {
+		b, err := os.ReadFile(c.clientSecretFile)
+		if err != nil {
+			return "", time.Time{}, fmt.Errorf("can't read client secret file: %w", err)
+		}
+		secret = strings.TrimSpace(string(b))
+		if c.secrets != nil {
+			c.secrets.SetSecrets(c.clientSecretFile, secret)
+		}
+	}
+	if secret == "" && c.clientID != "" {
+		// A public client just identifies itself.
+		form.Set("client_id", c.clientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("can't create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if secret != "" {
+		// RFC 6749 section 2.3.1 says these must be form-encoded first.
+		req.SetBasicAuth(url.QueryEscape(c.clientID), url.QueryEscape(secret))
+	}
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("can't access token endpoint: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("can't read token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		errResp := oauth2ErrorResponse{}
+		if json.Unmarshal(body, &errResp) == nil && errResp.Error != "" {
+			return "", time.Time{}, fmt.Errorf("token endpoint returned status %d: %s: %s", resp.StatusCode, errResp.Error, errResp.Description)
+		}
+		return "", time.Time{}, fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+
+	tokenResp := oauth2TokenResponse{}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return "", time.Time{}, fmt.Errorf("can't decode token response: %w", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return "", time.Time{}, fmt.Errorf("token response has no access_token")
+	}
+	expiry := time.Time{}
+	if tokenResp.ExpiresIn > 0 {
+		expiry = start.Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	}
+	return tokenResp.AccessToken, expiry, nil
+}
+
+// clientCredentialsProvider implements tokenProvider with the OAuth2
+// client-credentials grant (RFC 6749 section 4.4).
+type clientCredentialsProvider struct {
+	oauth2Client
+}
+
+var _ tokenProvider = clientCredentialsProvider{}
+
+func (p clientCredentialsProvider) Name() string {
+	return oauth2GrantClientCredentials
+}
+
+func (p clientCredentialsProvider) Token(ctx context.Context) (string, time.Time, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	return p.requestToken(ctx, form)
+}
+
+// tokenExchangeProvider implements tokenProvider with OAuth2 token exchange
+// (RFC 8693), e.g. to exchange a Kubernetes service account token for a
+// token which git servers accept.
+type tokenExchangeProvider struct {
+	oauth2Client
+	// The subject token is read from this file for each request, since
+	// projected service account tokens are rotated.
+	subjectTokenFile string
+	subjectTokenType string
+}
+
+var _ tokenProvider = tokenExchangeProvider{}
+
+func (p tokenExchangeProvider) Name() string {
+	return oauth2GrantTokenExchange
+}
+
+func (p tokenExchangeProvider) Token(ctx context.Context) (string, time.Time, error) {
+	subject, err := os.ReadFile(p.subjectTokenFile)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("can't read subject token file: %w", err)
+	}
+	if p.secrets != nil {
+		p.secrets.SetSecrets(p.subjectTokenFile, string(subject))
+	}
+	form := url.Values{}
+	form.Set("grant_type", grantTypeTokenExchange)
+	form.Set("subject_token", strings.TrimSpace(string(subject)))
+	form.Set("subject_token_type", p.subjectTokenType)
+	form.Set("requested_token_type", tokenTypeAccessToken)
+	return p.requestToken(ctx, form)
+}
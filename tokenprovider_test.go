@@ -0,0 +1,242 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"k8s.io/git-sync/pkg/cmd"
+	"k8s.io/git-sync/pkg/logging"
+)
+
+// fakeTokenEndpoint records the last request, and replies with status and
+// body.
+type fakeTokenEndpoint struct {
+	status   int
+	body     string
+	form     url.Values
+	user     string
+	password string
+}
+
+func (f *fakeTokenEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.form = r.PostForm
+	f.user, f.password, _ = r.BasicAuth()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(f.status)
+	_, _ = w.Write([]byte(f.body))
+}
+
+func TestClientCredentialsProvider(t *testing.T) {
+	fake := &fakeTokenEndpoint{status: http.StatusOK, body: `{"access_token": "tok", "token_type": "Bearer", "expires_in": 3600}`}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secretFile, []byte("s3cret&x\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	p := clientCredentialsProvider{oauth2Client{
+		tokenURL:         srv.URL,
+		clientID:         "my client",
+		clientSecretFile: secretFile,
+		scopes:           "read_repository api",
+		httpClient:       srv.Client(),
+	}}
+
+	start := time.Now()
+	token, expiry, err := p.Token(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token != "tok" {
+		t.Errorf("expected token %q, got %q", "tok", token)
+	}
+	if expiry.Before(start.Add(time.Hour)) || expiry.After(time.Now().Add(time.Hour)) {
+		t.Errorf("expected expiry in an hour, got %v", expiry)
+	}
+	expForm := url.Values{"grant_type": {"client_credentials"}, "scope": {"read_repository api"}}
+	if !reflect.DeepEqual(expForm, fake.form) {
+		t.Errorf("expected form %v, got %v", expForm, fake.form)
+	}
+	if fake.user != "my+client" || fake.password != "s3cret%26x" {
+		t.Errorf("wrong client authentication: %q, %q", fake.user, fake.password)
+	}
+
+	// The secret file is re-read.
+	if err := os.WriteFile(secretFile, []byte("rotated"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := p.Token(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fake.password != "rotated" {
+		t.Errorf("expected rotated secret, got %q", fake.password)
+	}
+}
+
+func TestTokenExchangeProvider(t *testing.T) {
+	fake := &fakeTokenEndpoint{status: http.StatusOK, body: `{"access_token": "tok", "issued_token_type": "urn:ietf:params:oauth:token-type:access_token", "token_type": "Bearer"}`}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	subjectFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(subjectFile, []byte("my.jwt.token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	p := tokenExchangeProvider{
+		oauth2Client: oauth2Client{
+			tokenURL:   srv.URL,
+			clientID:   "public",
+			audience:   "gitea",
+			httpClient: srv.Client(),
+		},
+		subjectTokenFile: subjectFile,
+		subjectTokenType: tokenTypeJWT,
+	}
+
+	token, expiry, err := p.Token(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token != "tok" {
+		t.Errorf("expected token %q, got %q", "tok", token)
+	}
+	if !expiry.IsZero() {
+		t.Errorf("expected no expiry, got %v", expiry)
+	}
+	expForm := url.Values{
+		"grant_type":           {grantTypeTokenExchange},
+		"subject_token":        {"my.jwt.token"},
+		"subject_token_type":   {tokenTypeJWT},
+		"requested_token_type": {tokenTypeAccessToken},
+		"audience":             {"gitea"},
+		"client_id":            {"public"},
+	}
+	if !reflect.DeepEqual(expForm, fake.form) {
+		t.Errorf("expected form %v, got %v", expForm, fake.form)
+	}
+	if fake.user != "" {
+		t.Errorf("expected no client authentication, got %q", fake.user)
+	}
+
+	// A missing subject token is an error.
+	p.subjectTokenFile = filepath.Join(t.TempDir(), "missing")
+	if _, _, err := p.Token(context.Background()); err == nil {
+		t.Errorf("expected error for missing subject token")
+	}
+}
+
+func TestOAuth2TokenErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		status int
+		body   string
+	}{{
+		name:   "oauth2-error",
+		status: http.StatusBadRequest,
+		body:   `{"error": "invalid_client", "error_description": "bad secret"}`,
+	}, {
+		name:   "other-error",
+		status: http.StatusInternalServerError,
+		body:   `oops`,
+	}, {
+		name:   "no-token",
+		status: http.StatusOK,
+		body:   `{"token_type": "Bearer"}`,
+	}, {
+		name:   "not-json",
+		status: http.StatusOK,
+		body:   `access_token=tok`,
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(&fakeTokenEndpoint{status: tc.status, body: tc.body})
+			defer srv.Close()
+			p := clientCredentialsProvider{oauth2Client{
+				tokenURL:     srv.URL,
+				clientID:     "id",
+				clientSecret: "secret",
+				httpClient:   srv.Client(),
+			}}
+			if token, _, err := p.Token(context.Background()); err == nil {
+				t.Errorf("expected error, got token %q", token)
+			}
+		})
+	}
+}
+
+// fixedTokenProvider returns the same token and expiry every time.
+type fixedTokenProvider struct {
+	token  string
+	expiry time.Time
+}
+
+func (p fixedTokenProvider) Name() string {
+	return "fixed"
+}
+
+func (p fixedTokenProvider) Token(context.Context) (string, time.Time, error) {
+	return p.token, p.expiry, nil
+}
+
+func TestRefreshProviderTokenExpiry(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(dir, "gitconfig"))
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "true")
+	log := logging.New("", "", 0)
+	git := &repoSync{
+		cmd:  "git",
+		repo: "https://git.example.com/repo.git",
+		log:  log,
+		run:  cmd.NewRunner(log),
+	}
+	ctx := context.Background()
+	if _, _, err := git.Run(ctx, "", "config", "--global", "credential.helper", "store --file "+filepath.Join(dir, "git-credentials")); err != nil {
+		t.Fatal(err)
+	}
+
+	// A token without an expiry is requested before every sync.
+	if err := git.RefreshProviderToken(ctx, fixedTokenProvider{token: "tok"}, "oauth2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !git.providerExpiry.IsZero() {
+		t.Errorf("expected no expiry, got %v", git.providerExpiry)
+	}
+
+	// A token which outlasts git's credential cache is requested again
+	// before the cache forgets it.
+	if err := git.RefreshProviderToken(ctx, fixedTokenProvider{token: "tok", expiry: time.Now().Add(12 * time.Hour)}, "oauth2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if left := time.Until(git.providerExpiry); left <= 0 || left >= credentialCacheTimeout {
+		t.Errorf("expected expiry within the credential cache timeout, got %v left", left)
+	}
+}
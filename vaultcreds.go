@@ -0,0 +1,108 @@
+/*
+Copyright 2026 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"k8s.io/git-sync/pkg/vault"
+)
+
+// The Vault auth methods which are supported by --vault-auth.
+const (
+	vaultAuthKubernetes = "kubernetes"
+	vaultAuthAppRole    = "approle"
+)
+
+// vaultCredentials describes where to find git credentials in Vault.
+type vaultCredentials struct {
+	client *vault.Client
+	// The KV version 2 mount and the path of the secret under it.
+	mount string
+	path  string
+	// The fields of the secret which hold each credential.  Fields which are
+	// not in the secret are ignored.
+	usernameField string
+	passwordField string
+	sshKeyField   string
+	// Where the SSH key, if any, is written.  This is passed to ssh along
+	// with --ssh-key-file.
+	sshKeyFile string
+	// How often to re-read a secret which has no lease.
+	refresh time.Duration
+}
+
+// RefreshVaultCredentials reads the secret from Vault and stores the
+// credentials in it.
+func (git *repoSync) RefreshVaultCredentials(ctx context.Context, vc *vaultCredentials) error {
+	git.log.V(3).Info("reading vault secret", "mount", vc.mount, "path", vc.path)
+
+	start := time.Now()
+	secret, err := vc.client.ReadKV2(ctx, vc.mount, vc.path)
+	// The client's token is a secret, too, and changes as it is renewed or
+	// replaced.
+	git.log.SetSecrets("vault token", vc.client.Token())
+	if err != nil {
+		return err
+	}
+
+	username, hasUsername := secret.Data[vc.usernameField]
+	password, hasPassword := secret.Data[vc.passwordField]
+	sshKey, hasSSHKey := secret.Data[vc.sshKeyField]
+	if !hasPassword && !hasSSHKey {
+		return fmt.Errorf("vault secret %s/%s has neither %q nor %q", vc.mount, vc.path, vc.passwordField, vc.sshKeyField)
+	}
+	if hasPassword {
+		if !hasUsername {
+			return fmt.Errorf("vault secret %s/%s has %q but not %q", vc.mount, vc.path, vc.passwordField, vc.usernameField)
+		}
+		if err := git.StoreCredentials(ctx, git.repo, username, password); err != nil {
+			return err
+		}
+	}
+	if hasSSHKey {
+		// ssh rejects keys without a trailing newline.
+		if !strings.HasSuffix(sshKey, "\n") {
+			sshKey += "\n"
+		}
+		git.log.SetSecrets(vc.sshKeyFile, sshKey)
+		if err := os.WriteFile(vc.sshKeyFile, []byte(sshKey), 0600); err != nil {
+			return fmt.Errorf("can't write SSH key from vault: %w", err)
+		}
+	}
+
+	// KV version 2 secrets don't usually have leases, but honor one if it
+	// is there.
+	git.vaultExpiry = time.Time{}
+	if secret.LeaseDuration > 0 {
+		git.vaultExpiry = start.Add(secret.LeaseDuration)
+	} else if vc.refresh > 0 {
+		git.vaultExpiry = start.Add(vc.refresh)
+	}
+	// The password must be stored again before git's credential cache
+	// forgets it.
+	if hasPassword {
+		git.vaultExpiry = cacheExpiry(start, git.vaultExpiry)
+	}
+	git.log.V(3).Info("read vault secret", "version", secret.Version, "password", hasPassword, "sshKey", hasSSHKey, "expiresAt", git.vaultExpiry)
+
+	return nil
+}